@@ -0,0 +1,81 @@
+package metadb
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ErrAssertSchema is returned by AssertSchema when any of the expected entries
+// is missing or holds data of another type, listing an error for each such
+// entry in order of name. Each error is an ErrNoEntry if the entry does not
+// exist, or an ErrWrongType if it holds data of a different type.
+type ErrAssertSchema struct {
+	Errors []error
+}
+
+// Error implements the error interface for ErrAssertSchema.
+func (err *ErrAssertSchema) Error() string {
+	return fmt.Sprintf("metadb: %d entries do not match the expected schema:\n%s", len(err.Errors), joinErrors(err.Errors))
+}
+
+// AssertSchema checks that every entry named in expected exists and holds data
+// of the type it maps to, such as to validate the configuration required by an
+// application on startup. Every entry is checked by a single query, and an
+// ErrAssertSchema listing each entry which is missing or of another type is
+// returned, rather than only the first.
+func (instance *Instance) AssertSchema(expected map[string]ValueType) error {
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	types, err := instance.typesOf(names)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, name := range names {
+		if got, ok := types[name]; !ok {
+			errs = append(errs, &ErrNoEntry{name})
+		} else if got != expected[name] {
+			errs = append(errs, &ErrWrongType{name, expected[name], got})
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ErrAssertSchema{errs}
+	}
+
+	return nil
+}
+
+// MustAssertSchema does the same as AssertSchema, but panics if an error is
+// returned.
+func (instance *Instance) MustAssertSchema(expected map[string]ValueType) {
+	if err := instance.AssertSchema(expected); err != nil {
+		panic(err)
+	}
+}
+
+// typesOf returns the ValueType of each of the requested entries which exist,
+// keyed by name, reading them with as few queries as possible.
+func (instance *Instance) typesOf(names []string) (map[string]ValueType, error) {
+	types := make(map[string]ValueType, len(names))
+	err := instance.selectMany("{name}, {type}", names, func(scan func(dest ...interface{}) error) error {
+		var name string
+		var valueType ValueType
+		if err := scan(&name, &valueType); err != nil {
+			return fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+		}
+
+		types[instance.unkey(name)] = valueType
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return types, nil
+}
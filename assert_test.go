@@ -0,0 +1,48 @@
+package metadb
+
+import "testing"
+
+// TestAssertSchema ensures that every missing or mistyped entry is reported at
+// once, in order of name.
+func TestAssertSchema(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("server.host", "localhost")
+		instance.MustSet("server.port", "8080")
+		instance.MustSet("server.debug", true)
+
+		expected := map[string]ValueType{
+			"server.host":    TypeString,
+			"server.port":    TypeInt,
+			"server.debug":   TypeBool,
+			"server.timeout": TypeDuration,
+		}
+
+		err := instance.AssertSchema(expected)
+		assertErr, ok := err.(*ErrAssertSchema)
+		if !ok {
+			t.Fatalf("Instance.AssertSchema: got '%v' expected ErrAssertSchema", err)
+		} else if len(assertErr.Errors) != 2 {
+			t.Fatalf("Instance.AssertSchema: got %d errors expected 2:\n%v", len(assertErr.Errors), err)
+		}
+
+		if wrongType, ok := assertErr.Errors[0].(*ErrWrongType); !ok || wrongType.Name != "server.port" {
+			t.Errorf("Instance.AssertSchema: got '%v' expected ErrWrongType for 'server.port'", assertErr.Errors[0])
+		}
+
+		if noEntry, ok := assertErr.Errors[1].(*ErrNoEntry); !ok || noEntry.Name != "server.timeout" {
+			t.Errorf("Instance.AssertSchema: got '%v' expected ErrNoEntry for 'server.timeout'", assertErr.Errors[1])
+		}
+
+		instance.MustForceSet("server.port", 8080)
+		delete(expected, "server.timeout")
+		instance.MustAssertSchema(expected)
+
+		if err := instance.WithPrefix("server.").AssertSchema(map[string]ValueType{"port": TypeInt}); err != nil {
+			t.Error("Instance.AssertSchema: got error within scope:\n", err)
+		}
+
+		if err := instance.AssertSchema(nil); err != nil {
+			t.Error("Instance.AssertSchema: got error with no expected entries:\n", err)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package metadb
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// changed reports that the named entries have been written or deleted. If the
+// Instance is bound to a transaction, the report is deferred until the
+// transaction has been committed. Otherwise, any variables bound to the
+// entries are updated.
+func (instance *Instance) changed(names ...string) {
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = instance.key(name)
+	}
+
+	if instance.pending != nil {
+		*instance.pending = append(*instance.pending, keys...)
+		return
+	}
+
+	instance.notify(keys...)
+}
+
+// notify updates any variables bound to the entries with the given full names,
+// regardless of the scope of the Instance, and wakes any calls to WaitFor
+// waiting on them.
+func (instance *Instance) notify(keys ...string) {
+	root := *instance
+	root.prefix = ""
+
+	for _, key := range keys {
+		instance.mu.Lock()
+		for _, waiter := range instance.waiters[key] {
+			close(waiter)
+		}
+		delete(instance.waiters, key)
+		instance.mu.Unlock()
+
+		instance.mu.RLock()
+		bindings := instance.bindings[key]
+		instance.mu.RUnlock()
+
+		if len(bindings) == 0 {
+			continue
+		}
+
+		// entries which were deleted or cannot be read leave the variables as-is
+		value, err := root.get(key)
+		if err != nil {
+			continue
+		}
+
+		for _, binding := range bindings {
+			if reflect.TypeOf(value) == binding.Type() {
+				binding.Set(reflect.ValueOf(value))
+			}
+		}
+	}
+}
+
+// Bind reads the requested entry into the variable pointed to by ptr and keeps
+// it in sync with the entry thereafter, writing the new value into the
+// variable whenever the entry is written through this Instance (or any
+// Instance derived from it). Writes within a transaction are only applied once
+// the transaction has been committed, and writes which change the type of the
+// entry or delete it leave the variable as-is. Changes made by other Instances
+// or processes are not observed.
+//
+// The variable is written by whichever goroutine writes the entry, so access
+// to it from other goroutines must be synchronized by the caller.
+//
+// ptr must be a non-nil pointer to a variable of one of the allowed types,
+// matching the type of the data stored in the entry. If it is not, or if the
+// entry does not exist, an error is returned and nothing is bound.
+func (instance *Instance) Bind(name string, ptr interface{}) error {
+	return instance.wrap("Bind", name, instance.bind(name, ptr))
+}
+
+// bind implements Bind.
+func (instance *Instance) bind(name string, ptr interface{}) error {
+	target := reflect.ValueOf(ptr)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return fmt.Errorf("metadb: cannot bind '%s' to non-pointer or nil value", name)
+	}
+
+	target = target.Elem()
+	valueType, err := toValueType(reflect.Zero(target.Type()).Interface())
+	if err != nil {
+		return err
+	}
+
+	value, err := instance.Get(name)
+	if err != nil {
+		return err
+	}
+
+	if got, _ := toValueType(value); got != valueType {
+		return &ErrWrongType{name, valueType, got}
+	}
+
+	target.Set(reflect.ValueOf(value))
+
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	key := instance.key(name)
+	instance.bindings[key] = append(instance.bindings[key], target)
+	return nil
+}
+
+// MustBind does the same as Bind, but panics if an error is returned.
+func (instance *Instance) MustBind(name string, ptr interface{}) {
+	if err := instance.Bind(name, ptr); err != nil {
+		panic(err)
+	}
+}
+
+// Unbind stops updating the variable pointed to by ptr when the requested
+// entry is written. Nothing is done if the variable is not bound to the entry.
+func (instance *Instance) Unbind(name string, ptr interface{}) {
+	target := reflect.ValueOf(ptr)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return
+	}
+
+	key := instance.key(name)
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	bindings := instance.bindings[key]
+	for i, binding := range bindings {
+		if binding.Addr().Pointer() == target.Pointer() {
+			instance.bindings[key] = append(bindings[:i:i], bindings[i+1:]...)
+			break
+		}
+	}
+
+	if len(instance.bindings[key]) == 0 {
+		delete(instance.bindings, key)
+	}
+}
@@ -0,0 +1,94 @@
+package metadb
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestBind ensures that bound variables are initialized from and kept in sync
+// with their entry, and that invalid bindings are rejected.
+func TestBind(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("port", 8080)
+		instance.MustSet("host", "localhost")
+
+		var port int
+		if err := instance.Bind("port", &port); err != nil {
+			t.Fatal("Instance.Bind: got error:\n", err)
+		} else if port != 8080 {
+			t.Errorf("Instance.Bind: got '%d' expected '8080'", port)
+		}
+
+		instance.MustSet("port", 9090)
+		if port != 9090 {
+			t.Errorf("Instance.Bind: got '%d' expected '9090' after Set", port)
+		}
+
+		// changes within a transaction only apply once committed
+		if _, _, err := instance.GetSet("port", 7070); err != nil {
+			t.Error("Instance.GetSet: got error:\n", err)
+		} else if port != 7070 {
+			t.Errorf("Instance.Bind: got '%d' expected '7070' after GetSet", port)
+		}
+
+		rollback := errors.New("rollback")
+		err := instance.withTx(func(tx *Instance) error {
+			tx.MustSet("port", 6060)
+			if port != 7070 {
+				t.Errorf("Instance.Bind: got '%d' expected '7070' before commit", port)
+			}
+
+			return rollback
+		})
+		if err != rollback || port != 7070 {
+			t.Errorf("Instance.Bind: got '%d' expected '7070' after rollback", port)
+		}
+
+		instance.MustForceSet("port", "not an int")
+		if port != 7070 {
+			t.Errorf("Instance.Bind: got '%d' expected '7070' after type change", port)
+		}
+
+		instance.MustForceSet("port", 5050)
+		instance.Unbind("port", &port)
+		instance.MustSet("port", 4040)
+		if port != 5050 {
+			t.Errorf("Instance.Unbind: got '%d' expected '5050' after unbinding", port)
+		}
+
+		var wrongType int
+		if err := instance.Bind("host", &wrongType); err == nil {
+			t.Error("Instance.Bind: expected error with variable of different type than entry")
+		} else if _, ok := err.(*ErrWrongType); !ok {
+			t.Error("Instance.Bind: expected error of type *ErrWrongType")
+		}
+
+		var missing string
+		if err := instance.Bind("missing", &missing); err == nil {
+			t.Error("Instance.Bind: expected error with non-existent entry")
+		}
+
+		var disallowed int32
+		if err := instance.Bind("port", &disallowed); err == nil {
+			t.Error("Instance.Bind: expected error with variable of disallowed type")
+		}
+
+		if err := panicked(func() { instance.MustBind("port", &disallowed) }); err == nil {
+			t.Error("Instance.MustBind: expected panic with variable of disallowed type")
+		}
+
+		if err := instance.Bind("port", port); err == nil {
+			t.Error("Instance.Bind: expected error with non-pointer")
+		}
+
+		var host string
+		if err := panicked(func() { instance.MustBind("host", &host) }); err != nil {
+			t.Error("Instance.MustBind: got panic:\n", err)
+		}
+		instance.MustRename("host", "hostname")
+		instance.MustSet("hostname", "example.com")
+		if host != "localhost" {
+			t.Errorf("Instance.Bind: got '%s' expected 'localhost' after rename", host)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package metadb
+
+// Change describes the modification of the value of a single entry.
+type Change struct {
+	Name string
+	Old  interface{}
+	New  interface{}
+}
+
+// ChangeSet describes the modifications which an import would make, without
+// including entries which would be left unchanged.
+type ChangeSet struct {
+	Adds        []Entry  // entries which would be created
+	Updates     []Change // entries whose value would change, but not its type
+	TypeChanges []Change // entries whose value would change to one of another type
+}
+
+// planImport returns the ChangeSet resulting from storing each of the given
+// entries. If overwrite is false, existing entries are left untouched. Should
+// an entry occur more than once, the last occurrence takes precedence.
+func (instance *Instance) planImport(entries []Entry, overwrite bool) (*ChangeSet, error) {
+	last := make(map[string]int, len(entries))
+	for i, entry := range entries {
+		last[entry.Name] = i
+	}
+
+	changes := &ChangeSet{}
+	for i, entry := range entries {
+		if last[entry.Name] != i {
+			continue
+		}
+
+		current, err := instance.get(entry.Name)
+		if err != nil {
+			if _, ok := err.(*ErrNoEntry); !ok {
+				return nil, err
+			}
+
+			changes.Adds = append(changes.Adds, entry)
+			continue
+		}
+
+		if !overwrite || equal(current, entry.Value) {
+			continue
+		}
+
+		change := Change{Name: entry.Name, Old: current, New: entry.Value}
+		if currentType, _ := toValueType(current); currentType != entry.Type {
+			changes.TypeChanges = append(changes.TypeChanges, change)
+		} else {
+			changes.Updates = append(changes.Updates, change)
+		}
+	}
+
+	return changes, nil
+}
+
+// applyChanges stores every change within the provided ChangeSet.
+func (instance *Instance) applyChanges(changes *ChangeSet) error {
+	for _, entry := range changes.Adds {
+		if err := instance.ForceSet(entry.Name, entry.Value); err != nil {
+			return err
+		}
+	}
+
+	for _, list := range [][]Change{changes.Updates, changes.TypeChanges} {
+		for _, change := range list {
+			if err := instance.ForceSet(change.Name, change.New); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package metadb
+
+import "fmt"
+
+// valueCodec holds a pair of transforms applied to the blob string of a single
+// entry as it is written to and read from the database.
+type valueCodec struct {
+	encode func(string) (string, error)
+	decode func(string) (string, error)
+}
+
+// SetValueCodec registers a pair of transforms for the entry with the given
+// name. encode is applied to the blob string just before it is written, and
+// decode just after it is read, so that the stored form of a value may differ
+// from its plain representation (e.g. for encryption at rest). The transforms
+// operate on the output of the regular type encoding and do not alter the
+// stored type. Passing nil for both functions removes any registered codec.
+func (instance *Instance) SetValueCodec(name string, encode, decode func(string) (string, error)) {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	if encode == nil && decode == nil {
+		delete(instance.codecs, instance.key(name))
+		return
+	}
+
+	instance.codecs[instance.key(name)] = valueCodec{encode, decode}
+}
+
+// codec returns the codec registered for the named entry, if any.
+func (instance *Instance) codec(name string) (valueCodec, bool) {
+	instance.mu.RLock()
+	defer instance.mu.RUnlock()
+
+	codec, ok := instance.codecs[instance.key(name)]
+	return codec, ok
+}
+
+// opaque returns true if the stored form of the named entry may differ from
+// the encoding of its value, due to a registered codec or to encryption, in
+// which case it must be decoded before being compared or modified.
+func (instance *Instance) opaque(name string) bool {
+	_, ok := instance.codec(name)
+	return ok || instance.aead != nil
+}
+
+// encodeBlob applies the encode transform registered for the named entry to a
+// blob string, followed by encryption if Options.EncryptionKey is set. If
+// neither applies, the blob is returned unchanged.
+func (instance *Instance) encodeBlob(name string, blob string) (string, error) {
+	if codec, ok := instance.codec(name); ok && codec.encode != nil {
+		res, err := codec.encode(blob)
+		if err != nil {
+			return "", fmt.Errorf("metadb: failed to encode value for '%s':\n%s", name, err)
+		}
+
+		blob = res
+	}
+
+	if instance.aead != nil {
+		res, err := instance.encrypt(blob)
+		if err != nil {
+			return "", fmt.Errorf("metadb: failed to encrypt value for '%s':\n%s", name, err)
+		}
+
+		blob = res
+	}
+
+	return blob, nil
+}
+
+// decodeBlob reverses encodeBlob, decrypting a blob string if
+// Options.EncryptionKey is set and then applying the decode transform
+// registered for the named entry. If neither applies, the blob is returned
+// unchanged.
+func (instance *Instance) decodeBlob(name string, blob string) (string, error) {
+	if instance.aead != nil {
+		res, err := instance.decrypt(name, blob)
+		if err != nil {
+			return "", err
+		}
+
+		blob = res
+	}
+
+	if codec, ok := instance.codec(name); ok && codec.decode != nil {
+		res, err := codec.decode(blob)
+		if err != nil {
+			return "", fmt.Errorf("metadb: failed to decode value for '%s':\n%s", name, err)
+		}
+
+		return res, nil
+	}
+
+	return blob, nil
+}
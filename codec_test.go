@@ -0,0 +1,62 @@
+package metadb
+
+import (
+	"errors"
+	"testing"
+)
+
+// reverse returns a string with the order of its bytes reversed.
+func reverse(value string) (string, error) {
+	res := []byte(value)
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+
+	return string(res), nil
+}
+
+// TestSetValueCodec ensures that registered codecs are applied to the stored
+// blob string, are transparent to Get, and that their errors are propagated.
+func TestSetValueCodec(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.SetValueCodec("token", reverse, reverse)
+
+		instance.MustSet("token", "secret")
+		instance.MustSet("plain", "secret")
+
+		fixtures := GetFixtures(instance)
+		if value := fixtures["token"].Value; value != "terces" {
+			t.Errorf("Instance.SetValueCodec: got stored value '%v' expected 'terces'", value)
+		}
+
+		if value := fixtures["plain"].Value; value != "secret" {
+			t.Errorf("Instance.SetValueCodec: got stored value '%v' expected 'secret'", value)
+		}
+
+		if value := instance.MustGet("token"); value != "secret" {
+			t.Errorf("Instance.Get: got '%v' expected 'secret'", value)
+		}
+
+		instance.SetValueCodec("count", reverse, reverse)
+		instance.MustSet("count", 120)
+		if value := instance.MustGet("count"); value != 120 {
+			t.Errorf("Instance.Get: got '%v' expected '120'", value)
+		}
+
+		failing := func(string) (string, error) { return "", errors.New("codec failure") }
+
+		instance.SetValueCodec("token", failing, failing)
+		if err := instance.Set("token", "other"); err == nil {
+			t.Error("Instance.Set: expected error with failing encoder")
+		}
+
+		if _, err := instance.Get("token"); err == nil {
+			t.Error("Instance.Get: expected error with failing decoder")
+		}
+
+		instance.SetValueCodec("token", nil, nil)
+		if value := instance.MustGet("token"); value != "terces" {
+			t.Errorf("Instance.Get: got '%v' expected 'terces' after removing codec", value)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package metadb
+
+// compareBatchSize is the number of entries read from each Instance at a time
+// by Equal.
+const compareBatchSize = 256
+
+// cursor reads the entries of an Instance in order of name, a batch at a time.
+type cursor struct {
+	instance *Instance
+	entries  []Entry
+	last     *string
+	done     bool
+	err      error
+}
+
+// peek returns the next entry without advancing the cursor, or nil once every
+// entry has been read or an error has occurred.
+func (cursor *cursor) peek() *Entry {
+	if len(cursor.entries) == 0 && !cursor.done && cursor.err == nil {
+		cursor.entries, cursor.err = cursor.instance.batch(cursor.last, compareBatchSize)
+		if len(cursor.entries) < compareBatchSize {
+			cursor.done = true
+		}
+
+		if len(cursor.entries) > 0 {
+			key := cursor.instance.key(cursor.entries[len(cursor.entries)-1].Name)
+			cursor.last = &key
+		}
+	}
+
+	if len(cursor.entries) == 0 {
+		return nil
+	}
+
+	return &cursor.entries[0]
+}
+
+// advance moves the cursor past the next entry.
+func (cursor *cursor) advance() {
+	cursor.entries = cursor.entries[1:]
+}
+
+// Equal compares every entry within the scope of the Instance with those
+// within the scope of other, returning whether they match along with the
+// names of the entries which differ in order of name. An entry differs if it
+// exists in only one of the Instances, or if its values are of different types
+// or are not equal. Entries are read from either Instance in batches rather
+// than all at once, such that large tables may be compared.
+//
+// Entries are matched by reading both Instances in order of name, which
+// assumes that both tables order names identically, as is the case when they
+// are stored within the same kind of database.
+func (instance *Instance) Equal(other *Instance) (bool, []string, error) {
+	left := &cursor{instance: instance}
+	right := &cursor{instance: other}
+	differing := make([]string, 0)
+
+	for {
+		a, b := left.peek(), right.peek()
+		if left.err != nil {
+			return false, nil, left.err
+		} else if right.err != nil {
+			return false, nil, right.err
+		}
+
+		switch {
+		case a == nil && b == nil:
+			return len(differing) == 0, differing, nil
+		case b == nil || (a != nil && a.Name < b.Name):
+			differing = append(differing, a.Name)
+			left.advance()
+		case a == nil || b.Name < a.Name:
+			differing = append(differing, b.Name)
+			right.advance()
+		default:
+			if !equal(a.Value, b.Value) {
+				differing = append(differing, a.Name)
+			}
+
+			left.advance()
+			right.advance()
+		}
+	}
+}
+
+// MustEqual does the same as Equal, but panics if an error is returned.
+func (instance *Instance) MustEqual(other *Instance) (matches bool, differing []string) {
+	matches, differing, err := instance.Equal(other)
+	if err != nil {
+		panic(err)
+	}
+
+	return matches, differing
+}
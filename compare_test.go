@@ -0,0 +1,41 @@
+package metadb
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestEqual ensures that Equal reports the entries which are missing from
+// either Instance or differ in value or type.
+func TestEqual(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		source := instance.WithPrefix("source.")
+		target := instance.WithPrefix("target.")
+
+		for i := 0; i < compareBatchSize+10; i++ {
+			source.MustSet(fmt.Sprintf("entry%04d", i), i)
+			target.MustSet(fmt.Sprintf("entry%04d", i), i)
+		}
+
+		if matches, differing := source.MustEqual(target); !matches || len(differing) != 0 {
+			t.Errorf("Instance.Equal: got '%t' with '%v' expected 'true' with '[]'", matches, differing)
+		}
+
+		source.MustSet("a", "only in source")
+		target.MustSet("z", "only in target")
+		target.MustForceSet("entry0001", int64(1))
+		target.MustSet("entry0002", 3)
+		source.MustSet("list", []string{"a", "b"})
+		target.MustSet("list", []string{"a", "b"})
+
+		matches, differing, err := source.Equal(target)
+		if err != nil {
+			t.Fatal("Instance.Equal: got error:\n", err)
+		}
+
+		expected := "[a entry0001 entry0002 z]"
+		if matches || fmt.Sprint(differing) != expected {
+			t.Errorf("Instance.Equal: got '%t' with '%v' expected 'false' with '%s'", matches, differing, expected)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package metadb
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// TestNewInstanceFromConn ensures that an Instance created from an *sql.Conn
+// performs every operation on that connection alone.
+func TestNewInstanceFromConn(t *testing.T) {
+	if _, err := NewInstanceFromConn(nil); err == nil {
+		t.Error("NewInstanceFromConn: expected error with nil connection")
+	}
+
+	RunWithDB(func(db *sql.DB) {
+		// with the only connection taken, any use of the pool would block
+		db.SetMaxOpenConns(1)
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			t.Fatal("tests: failed to get connection:\n", err)
+		}
+		defer conn.Close()
+
+		instance, err := NewInstanceFromConnWithOptions(conn, Options{Timeout: time.Second})
+		if err != nil {
+			t.Fatal("NewInstanceFromConn: got error:\n", err)
+		}
+
+		if instance.DB != nil {
+			t.Error("NewInstanceFromConn: expected nil DB field")
+		}
+
+		instance.MustSet("foo", 1)
+		if old, existed := instance.MustGetSet("foo", 2); !existed || old != 1 {
+			t.Errorf("Instance.MustGetSet: got '%v', '%t' expected '1', 'true'", old, existed)
+		}
+
+		instance.MustRename("foo", "bar")
+		if value := instance.MustGet("bar"); value != 2 {
+			t.Errorf("Instance.MustGet: got '%v' expected '2'", value)
+		}
+
+		if entries, err := instance.Entries(); err != nil {
+			t.Error("Instance.Entries: got error:\n", err)
+		} else if len(entries) != 1 {
+			t.Errorf("Instance.Entries: got %d entries expected 1", len(entries))
+		}
+
+		instance.MustDelete("bar")
+		if instance.Exists("bar") {
+			t.Error("Instance.Exists: got 'true' expected 'false'")
+		}
+	})
+}
@@ -0,0 +1,223 @@
+package metadb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// WithContext returns a copy of the Instance whose operations are all bound to
+// the provided context, such that they fail once it is cancelled. This applies
+// equally to the Must variants of each method. The returned Instance shares
+// its configuration with the original.
+func (instance *Instance) WithContext(ctx context.Context) *Instance {
+	if ctx == nil {
+		panic("metadb: nil context")
+	}
+
+	bound := *instance
+	bound.ctx = ctx
+	return &bound
+}
+
+// context returns a context for a single statement or transaction, derived
+// from the context of the Instance and bounded by Options.Timeout if set. The
+// returned cancel function must be called once the context is no longer used.
+func (instance *Instance) context() (context.Context, context.CancelFunc) {
+	if instance.options.Timeout > 0 {
+		return context.WithTimeout(instance.ctx, instance.options.Timeout)
+	}
+
+	return context.WithCancel(instance.ctx)
+}
+
+// around runs fn as the operation op on the named entry, wrapped by the
+// configured Middleware if any. For a serialized Instance, the worker is held
+// while the operation is performed, and fn is passed a copy of the Instance
+// on which it must perform every statement, see exclusive. Otherwise, fn is
+// passed the Instance itself. Any error returned is wrapped as by wrap.
+func (instance *Instance) around(op, name string, fn func(held *Instance) error) error {
+	err := instance.exclusive(func(held *Instance) error {
+		if held.options.Middleware != nil {
+			return held.options.Middleware(op, held.key(name), func() error {
+				return fn(held)
+			})
+		}
+
+		return fn(held)
+	})
+
+	return instance.wrap(op, name, err)
+}
+
+// wrap wraps err in an ErrOperation for the operation op on the named entry if
+// it is not nil and Options.WrapErrors is set, returning it as-is otherwise.
+// Errors already wrapped by an operation performed within op, such as Get, are
+// also returned as-is.
+func (instance *Instance) wrap(op, name string, err error) error {
+	if _, wrapped := err.(*ErrOperation); err != nil && !wrapped && instance.options.WrapErrors {
+		return &ErrOperation{op, instance.key(name), err}
+	}
+
+	return err
+}
+
+// ErrOperation wraps an error returned by an operation on a single entry with
+// the name of the operation (e.g. "Set") and the full name of the entry, as
+// passed to Middleware, if Options.WrapErrors is set. The wrapped error may be
+// matched with errors.Is and errors.As, or retrieved through Unwrap.
+type ErrOperation struct {
+	Op   string
+	Name string
+	Err  error
+}
+
+// Error implements the error interface for ErrOperation.
+func (err *ErrOperation) Error() string {
+	return fmt.Sprintf("metadb %s %q: %s", err.Op, err.Name, err.Err)
+}
+
+// Unwrap returns the error wrapped by the ErrOperation.
+func (err *ErrOperation) Unwrap() error {
+	return err.Err
+}
+
+// row wraps an *sql.Row, releasing the context of its query once scanned.
+type row struct {
+	*sql.Row
+	cancel context.CancelFunc
+}
+
+// Scan does the same as sql.Row.Scan, releasing the context of the query
+// afterward.
+func (row *row) Scan(dest ...interface{}) error {
+	defer row.cancel()
+	return row.Row.Scan(dest...)
+}
+
+// rows wraps an *sql.Rows, releasing the context of its query once closed.
+type rows struct {
+	*sql.Rows
+	cancel context.CancelFunc
+}
+
+// Close does the same as sql.Rows.Close, releasing the context of the query
+// afterward.
+func (rows *rows) Close() error {
+	defer rows.cancel()
+	return rows.Rows.Close()
+}
+
+// exec executes a statement after replacing its table and column placeholders.
+func (instance *Instance) exec(query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := instance.context()
+	defer cancel()
+
+	return instance.db.ExecContext(ctx, instance.sql(query), args...)
+}
+
+// query executes a query after replacing its table and column placeholders.
+// The returned rows must be closed once they are no longer used.
+func (instance *Instance) query(query string, args ...interface{}) (*rows, error) {
+	ctx, cancel := instance.context()
+
+	res, err := instance.db.QueryContext(ctx, instance.sql(query), args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &rows{res, cancel}, nil
+}
+
+// preparedRow does the same as queryRow, but executes the query through a
+// prepared statement which is cached for reuse, for queries which are executed
+// frequently. Statements are only prepared for Instances operating directly on
+// an *sql.DB, while other Instances fall back to queryRow.
+func (instance *Instance) preparedRow(query string, args ...interface{}) *row {
+	db, ok := instance.db.(*sql.DB)
+	if !ok {
+		return instance.queryRow(query, args...)
+	}
+
+	// statements are looked up and queried while the lock is held, such that
+	// Close and RefreshSchema cannot close them in between. The lock is
+	// released before the row is scanned, which is safe as database/sql
+	// defers closing a statement until rows queried through it are released.
+	raw := query
+	query = instance.sql(query)
+	instance.mu.RLock()
+	if stmt, ok := instance.statements[query]; ok {
+		defer instance.mu.RUnlock()
+
+		ctx, cancel := instance.context()
+		return &row{stmt.QueryRowContext(ctx, args...), cancel}
+	}
+	instance.mu.RUnlock()
+
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	stmt, ok := instance.statements[query]
+	if !ok {
+		ctx, cancel := instance.context()
+		var err error
+		stmt, err = db.PrepareContext(ctx, query)
+		cancel()
+
+		if err != nil {
+			// the error is reported once the row is scanned, and queryRow
+			// replaces the placeholders of the original query itself
+			return instance.queryRow(raw, args...)
+		}
+
+		instance.statements[query] = stmt
+	}
+
+	ctx, cancel := instance.context()
+	return &row{stmt.QueryRowContext(ctx, args...), cancel}
+}
+
+// Close releases the prepared statements cached by the Instance and any
+// Instance derived from it. It does not close the underlying database handle,
+// and the Instance may continue to be used afterward, in which case statements
+// are prepared again as needed. For an Instance created with
+// NewSerializedInstance, Close additionally stops its worker goroutine and
+// releases its connection, after which the Instance may no longer be used.
+func (instance *Instance) Close() error {
+	var res error
+	if instance.worker != nil {
+		res = instance.worker.stop()
+	}
+
+	if err := instance.closeStatements(); err != nil && res == nil {
+		res = err
+	}
+
+	return res
+}
+
+// closeStatements closes and forgets every cached prepared statement,
+// returning the first error encountered, if any.
+func (instance *Instance) closeStatements() error {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	var res error
+	for query, stmt := range instance.statements {
+		if err := stmt.Close(); err != nil && res == nil {
+			res = fmt.Errorf("metadb: failed to close prepared statement:\n%s", err)
+		}
+
+		delete(instance.statements, query)
+	}
+
+	return res
+}
+
+// queryRow executes a query expected to return at most one row after replacing
+// its table and column placeholders. The returned row must be scanned.
+func (instance *Instance) queryRow(query string, args ...interface{}) *row {
+	ctx, cancel := instance.context()
+	return &row{instance.db.QueryRowContext(ctx, instance.sql(query), args...), cancel}
+}
@@ -0,0 +1,212 @@
+package metadb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestWithContext ensures that operations fail once the context bound to an
+// Instance is cancelled, including those performed through Must methods.
+func TestWithContext(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("foo", "bar")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		bound := instance.WithContext(ctx)
+
+		if value := bound.MustGet("foo"); value != "bar" {
+			t.Errorf("Instance.MustGet: got '%v' expected 'bar'", value)
+		}
+
+		cancel()
+
+		if _, err := bound.Get("foo"); err == nil {
+			t.Error("Instance.Get: expected error with cancelled context")
+		}
+
+		if err := panicked(func() { bound.MustSet("foo", "baz") }); err == nil {
+			t.Error("Instance.MustSet: expected panic with cancelled context")
+		}
+
+		if _, _, err := bound.GetSet("foo", "baz"); err == nil {
+			t.Error("Instance.GetSet: expected error with cancelled context")
+		}
+
+		if value := instance.MustGet("foo"); value != "bar" {
+			t.Errorf("Instance.MustGet: got '%v' expected 'bar' on original Instance", value)
+		}
+	})
+}
+
+// TestTimeout ensures that Options.Timeout bounds operations which would
+// otherwise block indefinitely, here while waiting for a database connection.
+func TestTimeout(t *testing.T) {
+	RunWithOptions(Options{Timeout: 100 * time.Millisecond}, func(instance *Instance) {
+		instance.MustSet("foo", "bar")
+
+		// hold the only connection so that the next operation has to wait for it
+		instance.DB.SetMaxOpenConns(1)
+		tx, err := instance.DB.Begin()
+		if err != nil {
+			t.Fatal("tests: failed to begin transaction:\n", err)
+		}
+
+		start := time.Now()
+		if err := panicked(func() { instance.MustGet("foo") }); err == nil {
+			t.Error("Instance.MustGet: expected panic while waiting for a connection")
+		}
+
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("Instance.MustGet: took %s with a timeout of 100ms", elapsed)
+		}
+
+		if err := tx.Rollback(); err != nil {
+			t.Fatal("tests: failed to roll back transaction:\n", err)
+		}
+
+		if value := instance.MustGet("foo"); value != "bar" {
+			t.Errorf("Instance.MustGet: got '%v' expected 'bar'", value)
+		}
+	})
+}
+
+// TestPreparedStatements ensures that prepared statements are cached and
+// reused, and that the Instance remains usable once they have been closed.
+func TestPreparedStatements(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("foo", "bar")
+		instance.MustGet("foo")
+		instance.MustGet("foo")
+		instance.GetType("foo")
+
+		if count := len(instance.statements); count != 2 {
+			t.Errorf("Instance.Get: got '%d' prepared statements expected '2'", count)
+		}
+
+		if err := instance.Close(); err != nil {
+			t.Error("Instance.Close: got error:\n", err)
+		} else if count := len(instance.statements); count != 0 {
+			t.Errorf("Instance.Close: got '%d' prepared statements expected '0'", count)
+		}
+
+		if value := instance.MustGet("foo"); value != "bar" {
+			t.Errorf("Instance.Get: got '%v' expected 'bar' after Close", value)
+		}
+	})
+}
+
+// unprepared wraps an executor, hiding the *sql.DB underneath such that no
+// statements are prepared.
+type unprepared struct {
+	executor
+}
+
+// benchmarkGet reads a single entry b.N times after applying wrap to the
+// executor of the Instance.
+func benchmarkGet(b *testing.B, wrap func(executor) executor) {
+	RunWithInstance(func(instance *Instance) {
+		instance.db = wrap(instance.db)
+		instance.MustSet("foo", "bar")
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := instance.Get("foo"); err != nil {
+				b.Fatal("Instance.Get: got error:\n", err)
+			}
+		}
+	})
+}
+
+// BenchmarkGet measures reading a single entry through a prepared statement.
+func BenchmarkGet(b *testing.B) {
+	benchmarkGet(b, func(db executor) executor { return db })
+}
+
+// BenchmarkGetUnprepared measures reading a single entry without a prepared
+// statement, for comparison with BenchmarkGet.
+func BenchmarkGetUnprepared(b *testing.B) {
+	benchmarkGet(b, func(db executor) executor { return unprepared{db} })
+}
+
+// TestWrapErrors ensures that errors returned by wrapped operations name the
+// operation and entry while still matching the original error.
+func TestWrapErrors(t *testing.T) {
+	RunWithOptions(Options{WrapErrors: true}, func(instance *Instance) {
+		_, err := instance.WithPrefix("server.").Get("port")
+		if err == nil {
+			t.Fatal("Instance.Get: expected error with non-existent entry")
+		}
+
+		expected := `metadb Get "server.port": metadb: no entry for 'port'`
+		if err.Error() != expected {
+			t.Errorf("Instance.Get: got '%s' expected '%s'", err, expected)
+		}
+
+		var noEntry *ErrNoEntry
+		if !errors.As(err, &noEntry) || noEntry.Name != "port" {
+			t.Error("Instance.Get: expected errors.As to match *ErrNoEntry")
+		}
+
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Error("Instance.Get: expected errors.Is to match sql.ErrNoRows")
+		}
+
+		var operation *ErrOperation
+		if _, err := instance.GetString("missing"); !errors.As(err, &operation) || operation.Op != "Get" {
+			t.Errorf("Instance.GetString: got '%v' expected ErrOperation for Get", err)
+		}
+
+		instance.MustSet("port", 8080)
+		if _, err := instance.GetString("port"); !errors.As(err, &operation) || operation.Op != "Get" {
+			t.Errorf("Instance.GetString: got '%v' expected ErrOperation for Get with entry of type int", err)
+		}
+
+		var wrongType *ErrWrongType
+		if _, err := instance.GetFloat("port"); err != nil {
+			t.Error("Instance.GetFloat: got error:\n", err)
+		} else if _, err := instance.GetBool("port"); !errors.As(err, &wrongType) || !errors.As(err, &operation) {
+			t.Errorf("Instance.GetBool: got '%v' expected ErrOperation wrapping *ErrWrongType", err)
+		}
+
+		instance.MustSet("name", "metadb")
+		if _, err := instance.GetFloat("name"); !errors.As(err, &operation) || operation.Op != "Get" {
+			t.Errorf("Instance.GetFloat: got '%v' expected ErrOperation for Get with entry of type string", err)
+		}
+
+		if err := instance.Set("port", "8080"); !errors.As(err, &operation) || operation.Op != "Set" || operation.Name != "port" {
+			t.Errorf("Instance.Set: got '%v' expected ErrOperation for Set on 'port'", err)
+		}
+
+		for op, fn := range map[string]func() error{
+			"Pop":              func() error { _, err := instance.Pop("missing"); return err },
+			"Toggle":           func() error { _, err := instance.Toggle("port"); return err },
+			"Append":           func() error { _, err := instance.Append("port", "0"); return err },
+			"SetMax":           func() error { _, err := instance.SetMax("name", 1); return err },
+			"CompareAndDelete": func() error { _, err := instance.CompareAndDelete("port", "8080"); return err },
+			"GetSet":           func() error { _, _, err := instance.GetSet("port", "8080"); return err },
+			"Rename":           func() error { return instance.Rename("missing", "other") },
+			"Move":             func() error { return instance.Move("missing", "other") },
+			"GetType":          func() error { _, err := instance.GetType("missing"); return err },
+			"Reset":            func() error { return instance.Reset("missing") },
+			"Bind":             func() error { var port string; return instance.Bind("port", &port) },
+			"GetAs":            func() error { _, err := instance.GetAs("name", TypeInt); return err },
+		} {
+			if err := fn(); !errors.As(err, &operation) || operation.Op != op {
+				t.Errorf("Instance.%s: got '%v' expected ErrOperation for %s", op, err, op)
+			} else if inner := operation.Err; errors.As(inner, new(*ErrOperation)) {
+				t.Errorf("Instance.%s: got '%v' expected error to be wrapped once", op, err)
+			}
+		}
+
+		if _, err := instance.Get("missing"); errors.As(err, &operation) && errors.As(operation.Err, new(*ErrOperation)) {
+			t.Errorf("Instance.Get: got '%v' expected error to be wrapped once", err)
+		}
+
+		if _, found, err := instance.Lookup("missing"); err != nil || found {
+			t.Errorf("Instance.Lookup: got '%t', '%v' expected 'false', '<nil>'", found, err)
+		}
+	})
+}
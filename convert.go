@@ -0,0 +1,269 @@
+package metadb
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+)
+
+// convert takes a value of one of the allowed types and converts it to the
+// requested ValueType following the rules documented on GetAs. If the
+// conversion is disallowed or fails, an error is returned.
+func convert(value interface{}, valueType ValueType) (interface{}, error) {
+	currentType, err := toValueType(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if currentType == valueType {
+		return value, nil
+	}
+
+	fail := func() (interface{}, error) {
+		return nil, fmt.Errorf("metadb: cannot convert '%v' from %s to %s", value, currentType, valueType)
+	}
+
+	switch valueType {
+	case TypeString:
+		return toBlobString(value)
+	case TypeBool, TypeInt, TypeFloat, TypeInt64, TypeComplex, TypeDuration, TypeBigInt, TypeBigFloat:
+		switch value := value.(type) {
+		case string:
+			res, err := fromBlobString(value, valueType)
+			if err != nil {
+				return fail()
+			}
+
+			return res, nil
+		case int:
+			switch valueType {
+			case TypeFloat:
+				return float64(value), nil
+			case TypeInt64:
+				return int64(value), nil
+			case TypeBigInt:
+				return big.NewInt(int64(value)), nil
+			case TypeBigFloat:
+				return new(big.Float).SetInt64(int64(value)), nil
+			}
+		case int64:
+			switch valueType {
+			case TypeFloat:
+				return float64(value), nil
+			case TypeDuration:
+				return time.Duration(value), nil
+			case TypeBigInt:
+				return big.NewInt(value), nil
+			case TypeBigFloat:
+				return new(big.Float).SetInt64(value), nil
+			case TypeInt:
+				if value >= math.MinInt && value <= math.MaxInt {
+					return int(value), nil
+				}
+			}
+		case time.Duration:
+			if valueType == TypeInt64 {
+				return int64(value), nil
+			}
+		case *big.Int:
+			switch {
+			case valueType == TypeBigFloat:
+				return new(big.Float).SetInt(value), nil
+			case valueType == TypeInt64 && value.IsInt64():
+				return value.Int64(), nil
+			case valueType == TypeInt && value.IsInt64() && value.Int64() >= math.MinInt && value.Int64() <= math.MaxInt:
+				return int(value.Int64()), nil
+			}
+		case float64:
+			if valueType == TypeBigFloat && !math.IsNaN(value) {
+				return big.NewFloat(value), nil
+			}
+
+			if value == math.Trunc(value) {
+				switch {
+				case valueType == TypeInt && value >= math.MinInt && value < -math.MinInt:
+					return int(value), nil
+				case valueType == TypeInt64 && value >= math.MinInt64 && value < -math.MinInt64:
+					return int64(value), nil
+				}
+			}
+		}
+	}
+
+	return fail()
+}
+
+// GetAs returns the data within the requested entry converted to the given
+// ValueType. See the documentation of Get for the errors returned when the
+// entry cannot be read. The following conversions are allowed, any other
+// resulting in an error:
+//
+//	any type      -> its own type
+//	any type      -> string, using the same encoding as is used for storage
+//	string        -> bool, int, float64, int64, or complex128, parsed as by the strconv package
+//	string        -> time.Duration, parsed as by time.ParseDuration
+//	string        -> *big.Int or *big.Float, parsed as by their SetString methods
+//	int           -> float64, int64, *big.Int, or *big.Float
+//	int64         -> float64, time.Duration as nanoseconds, *big.Int, *big.Float, or int if within the range of int
+//	float64       -> *big.Float, or int or int64 if the value is integral and within range
+//	time.Duration -> int64 as nanoseconds
+//	*big.Int      -> *big.Float, or int or int64 if within range
+//
+// Note that converting large integers to float64 may lose precision.
+func (instance *Instance) GetAs(name string, valueType ValueType) (interface{}, error) {
+	value, err := instance.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := convert(value, valueType)
+	return res, instance.wrap("GetAs", name, err)
+}
+
+// MustGetAs does the same as GetAs, but panics if an error is returned.
+func (instance *Instance) MustGetAs(name string, valueType ValueType) interface{} {
+	if res, err := instance.GetAs(name, valueType); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// MigrateType converts every listed entry holding data of type from to the
+// type to within a single transaction, following the rules documented on
+// GetAs, and returns the number of entries which were converted. Entries which
+// do not exist or hold data of another type are skipped. If any entry cannot
+// be converted, an error is returned and no entry is modified.
+func (instance *Instance) MigrateType(names []string, from, to ValueType) (int, error) {
+	migrated := 0
+	err := instance.withTx(func(tx *Instance) error {
+		for _, name := range names {
+			value, err := tx.get(name)
+			if err != nil {
+				if _, ok := err.(*ErrNoEntry); ok {
+					continue
+				}
+
+				return err
+			}
+
+			if valueType, _ := toValueType(value); valueType != from {
+				continue
+			}
+
+			converted, err := convert(value, to)
+			if err != nil {
+				return fmt.Errorf("metadb: failed to migrate entry for '%s':\n%s", name, err)
+			}
+
+			if err := tx.set(name, converted, true); err != nil {
+				return err
+			}
+
+			migrated++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return migrated, nil
+}
+
+// MustMigrateType does the same as MigrateType, but panics if an error is
+// returned.
+func (instance *Instance) MustMigrateType(names []string, from, to ValueType) int {
+	if res, err := instance.MigrateType(names, from, to); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// MapValues replaces the value of every entry holding data of the given type
+// with the value returned by fn when passed its current value, within a single
+// transaction, and returns the number of entries whose value was changed. The
+// value returned by fn must be of the same type. If fn returns an error or a
+// value of another type, or an entry cannot be written, an error is returned
+// and no entry is modified.
+func (instance *Instance) MapValues(valueType ValueType, fn func(value interface{}) (interface{}, error)) (int, error) {
+	changed := 0
+	err := instance.withTx(func(tx *Instance) error {
+		names, err := tx.namesOfType(valueType)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			current, err := tx.get(name)
+			if err != nil {
+				return err
+			}
+
+			value, err := fn(current)
+			if err != nil {
+				return fmt.Errorf("metadb: failed to map entry for '%s':\n%s", name, err)
+			}
+
+			if got, err := toValueType(value); err != nil {
+				return fmt.Errorf("metadb: failed to map entry for '%s':\n%s", name, err)
+			} else if got != valueType {
+				return fmt.Errorf("metadb: failed to map entry for '%s':\n%s", name, &ErrWrongType{name, valueType, got})
+			}
+
+			if ok, err := tx.write(name, value, false); err != nil {
+				return err
+			} else if ok {
+				changed++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
+
+// MustMapValues does the same as MapValues, but panics if an error is
+// returned.
+func (instance *Instance) MustMapValues(valueType ValueType, fn func(value interface{}) (interface{}, error)) int {
+	if res, err := instance.MapValues(valueType, fn); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// namesOfType returns the names of every entry holding data of the given type
+// in order of name.
+func (instance *Instance) namesOfType(valueType ValueType) ([]string, error) {
+	scope, args := instance.scope()
+	rows, err := instance.query("SELECT {name} FROM {table} WHERE {type} = ? AND "+scope+" ORDER BY {name};",
+		append([]interface{}{valueType}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entry names:\n%s", err)
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan entry name:\n%s", err)
+		}
+
+		names = append(names, instance.unkey(name))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entry names:\n%s", err)
+	}
+
+	return names, nil
+}
@@ -0,0 +1,186 @@
+package metadb
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// TestConvert ensures that each of the allowed conversions is performed and
+// that disallowed or failing conversions result in an error.
+func TestConvert(t *testing.T) {
+	testValid := func(value interface{}, valueType ValueType, expected interface{}) {
+		if res, err := convert(value, valueType); err != nil {
+			t.Errorf("convert: got error converting '%v' to %s:\n%s", value, valueType, err)
+		} else if !equal(res, expected) {
+			t.Errorf("convert: got '%v' expected '%v'", res, expected)
+		}
+	}
+
+	expectError := func(value interface{}, valueType ValueType) {
+		if _, err := convert(value, valueType); err == nil {
+			t.Errorf("convert: expected error converting '%v' to %s", value, valueType)
+		}
+	}
+
+	testValid(12, TypeInt, 12)
+	testValid(true, TypeString, "true")
+	testValid(12, TypeString, "12")
+	testValid(1.5, TypeString, "1.5")
+	testValid("true", TypeBool, true)
+	testValid("42", TypeInt, 42)
+	testValid("4.2", TypeFloat, 4.2)
+	testValid(42, TypeFloat, 42.0)
+	testValid(42.0, TypeInt, 42)
+	testValid("42", TypeInt64, int64(42))
+	testValid(42, TypeInt64, int64(42))
+	testValid(int64(42), TypeInt, 42)
+	testValid(int64(42), TypeFloat, 42.0)
+	testValid(42.0, TypeInt64, int64(42))
+	testValid(int64(42), TypeString, "42")
+	testValid("1m30s", TypeDuration, 90*time.Second)
+	testValid(time.Second, TypeString, "1s")
+	testValid(int64(time.Second), TypeDuration, time.Second)
+	testValid(time.Second, TypeInt64, int64(time.Second))
+	testValid("123456789012345678901234567890", TypeBigInt, bigInt("123456789012345678901234567890"))
+	testValid(int64(42), TypeBigInt, big.NewInt(42))
+	testValid(big.NewInt(42), TypeInt, 42)
+	testValid(1.5, TypeBigFloat, big.NewFloat(1.5))
+	testValid(big.NewInt(42), TypeString, "42")
+
+	expectError("maybe", TypeBool)
+	expectError("4.2", TypeInt)
+	expectError(4.2, TypeInt)
+	expectError(math.Inf(1), TypeInt)
+	expectError(1e300, TypeInt)
+	expectError(1e300, TypeInt64)
+	expectError(int64(1), TypeBool)
+	expectError(true, TypeInt)
+	expectError(1, TypeBool)
+	expectError(1.0, TypeBool)
+	expectError("30", TypeDuration)
+	expectError(time.Second, TypeInt)
+	expectError(bigInt("123456789012345678901234567890"), TypeInt64)
+	expectError("1.5", TypeBigInt)
+	expectError(math.NaN(), TypeBigFloat)
+	expectError("foo", ValueType(100))
+	expectError(map[string]string{"disallowed": "type"}, TypeString)
+}
+
+// TestGetAs ensures that GetAs returns converted values and propagates errors.
+func TestGetAs(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("port", "8080")
+
+		if res, err := instance.GetAs("port", TypeInt); err != nil {
+			t.Error("Instance.GetAs: got error:\n", err)
+		} else if res != 8080 {
+			t.Errorf("Instance.GetAs: got '%v' expected '8080'", res)
+		}
+
+		if _, err := instance.GetAs("port", TypeBool); err == nil {
+			t.Error("Instance.GetAs: expected error with failing conversion")
+		}
+
+		if _, err := instance.GetAs("foo", TypeInt); err == nil {
+			t.Error("Instance.GetAs: expected error with non-existent entry")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Error("Instance.GetAs: expected error of type *ErrNoEntry")
+		}
+
+		if res := instance.MustGetAs("port", TypeFloat); res != 8080.0 {
+			t.Errorf("Instance.MustGetAs: got '%v' expected '8080'", res)
+		}
+
+		if err := panicked(func() { instance.MustGetAs("port", TypeBool) }); err == nil {
+			t.Error("Instance.MustGetAs: expected panic with failing conversion")
+		}
+	})
+}
+
+// TestMigrateType ensures that matching entries are converted, that others are
+// skipped, and that a failing conversion leaves every entry as-is.
+func TestMigrateType(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("a", "1")
+		instance.MustSet("b", "2")
+		instance.MustSet("c", 3)
+
+		if count, err := instance.MigrateType([]string{"a", "b", "c", "missing"}, TypeString, TypeInt); err != nil {
+			t.Error("Instance.MigrateType: got error:\n", err)
+		} else if count != 2 {
+			t.Errorf("Instance.MigrateType: got '%d' expected '2'", count)
+		}
+
+		for name, expected := range map[string]int{"a": 1, "b": 2, "c": 3} {
+			if res := instance.MustGet(name); res != expected {
+				t.Errorf("Instance.MigrateType: got '%v' expected '%d' for '%s'", res, expected, name)
+			}
+		}
+
+		instance.MustSet("d", "4")
+		instance.MustSet("e", "five")
+		if _, err := instance.MigrateType([]string{"d", "e"}, TypeString, TypeInt); err == nil {
+			t.Error("Instance.MigrateType: expected error with failing conversion")
+		}
+
+		if res := instance.MustGet("d"); res != "4" {
+			t.Errorf("Instance.MigrateType: got '%v' expected '4' after failed migration", res)
+		}
+
+		if err := panicked(func() { instance.MustMigrateType([]string{"e"}, TypeString, TypeBool) }); err == nil {
+			t.Error("Instance.MustMigrateType: expected panic with failing conversion")
+		}
+	})
+}
+
+// TestMapValues ensures that MapValues transforms every entry of the given type
+// and leaves every entry untouched if any transformation fails.
+func TestMapValues(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("a", 1)
+		instance.MustSet("b", 2)
+		instance.MustSet("zero", 0)
+		instance.MustSet("c", int64(3))
+		instance.MustSet("d", "4")
+
+		times := func(value interface{}) (interface{}, error) {
+			return value.(int) * 10, nil
+		}
+
+		if count, err := instance.MapValues(TypeInt, times); err != nil {
+			t.Fatal("Instance.MapValues: got error:\n", err)
+		} else if count != 2 {
+			t.Errorf("Instance.MapValues: got '%d' expected '2'", count)
+		}
+
+		expected := map[string]interface{}{"a": 10, "b": 20, "zero": 0, "c": int64(3), "d": "4"}
+		for name, value := range expected {
+			if got := instance.MustGet(name); got != value {
+				t.Errorf("Instance.Get: got '%v' expected '%v' for '%s'", got, value, name)
+			}
+		}
+
+		if _, err := instance.MapValues(TypeInt, func(value interface{}) (interface{}, error) {
+			if value.(int) == 20 {
+				return "twenty", nil
+			}
+
+			return value.(int) + 1, nil
+		}); err == nil {
+			t.Error("Instance.MapValues: expected error with value of another type")
+		}
+
+		if _, err := instance.MapValues(TypeInt, func(value interface{}) (interface{}, error) {
+			return nil, errors.New("tests: failed")
+		}); err == nil {
+			t.Error("Instance.MapValues: expected error from transformation")
+		}
+
+		if value := instance.MustGet("a"); value != 10 {
+			t.Errorf("Instance.Get: got '%v' expected '10' after failed transformation", value)
+		}
+	})
+}
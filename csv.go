@@ -0,0 +1,146 @@
+package metadb
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvHeader is the header row written by ExportCSV and expected by ImportCSV.
+var csvHeader = []string{"name", "value", "type"}
+
+// csvQuoted prefixes the type of rows whose name and value are written as Go
+// string literals, see ExportCSV.
+const csvQuoted = "quoted "
+
+// ExportCSV writes every metadata entry to w as CSV, preceded by a header row.
+// Each row contains the name of the entry, its value encoded as a string, and
+// the name of its type as returned by ValueType.String. Values are written as
+// decoded by any registered codecs.
+//
+// As any CSV reader drops a carriage return preceding a newline within a
+// field, the name and value of an entry containing a carriage return are
+// instead written as Go string literals, as by strconv.Quote, and its type is
+// prefixed by "quoted " (e.g. "quoted string"), such that ImportCSV restores
+// them as they were.
+func (instance *Instance) ExportCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("metadb: failed to write CSV:\n%s", err)
+	}
+
+	err := instance.each(func(name string, value interface{}) error {
+		valueType, err := toValueType(value)
+		if err != nil {
+			return err
+		}
+
+		blob, err := toBlobString(value)
+		if err != nil {
+			return err
+		}
+
+		if strings.ContainsRune(name, '\r') || strings.ContainsRune(blob, '\r') {
+			return writer.Write([]string{strconv.Quote(name), strconv.Quote(blob), csvQuoted + valueType.String()})
+		}
+
+		return writer.Write([]string{name, blob, valueType.String()})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("metadb: failed to write CSV:\n%s", err)
+	}
+
+	return nil
+}
+
+// readCSV reads the rows written by ExportCSV from r, returning an Entry for
+// each in the order in which they were read. If the header row is missing or
+// any row is malformed, an error is returned.
+func readCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvHeader)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read CSV header:\n%s", err)
+	}
+
+	for i, field := range csvHeader {
+		if header[i] != field {
+			return nil, fmt.Errorf("metadb: invalid CSV header, expected '%s' got '%s'", field, header[i])
+		}
+	}
+
+	var entries []Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("metadb: failed to read CSV:\n%s", err)
+		}
+
+		if strings.HasPrefix(record[2], csvQuoted) {
+			for i, field := range record[:2] {
+				if record[i], err = strconv.Unquote(field); err != nil {
+					return nil, fmt.Errorf("metadb: invalid quoted CSV field '%s':\n%s", field, err)
+				}
+			}
+
+			record[2] = strings.TrimPrefix(record[2], csvQuoted)
+		}
+
+		valueType, err := ParseValueType(record[2])
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := fromBlobString(record[1], valueType)
+		if err != nil {
+			return nil, fmt.Errorf("metadb: invalid CSV value for '%s':\n%s", record[0], err)
+		}
+
+		entries = append(entries, Entry{Name: record[0], Value: value, Type: valueType})
+	}
+
+	return entries, nil
+}
+
+// ImportCSV reads entries from r in the format written by ExportCSV and stores
+// each of them within a single transaction. If overwrite is true, existing
+// entries are replaced regardless of their current type, otherwise they are
+// left untouched. The input is validated in its entirety before any entry is
+// stored.
+func (instance *Instance) ImportCSV(r io.Reader, overwrite bool) error {
+	entries, err := readCSV(r)
+	if err != nil {
+		return err
+	}
+
+	return instance.withTx(func(tx *Instance) error {
+		changes, err := tx.planImport(entries, overwrite)
+		if err != nil {
+			return err
+		}
+
+		return tx.applyChanges(changes)
+	})
+}
+
+// PreviewCSV does the same as ImportCSV, but rather than storing anything
+// returns a ChangeSet describing the modifications which would be made.
+func (instance *Instance) PreviewCSV(r io.Reader, overwrite bool) (*ChangeSet, error) {
+	entries, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return instance.planImport(entries, overwrite)
+}
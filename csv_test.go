@@ -0,0 +1,142 @@
+package metadb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestExportAndImportCSV ensures that entries exported as CSV are imported
+// losslessly, that existing entries are only replaced when requested, and that
+// malformed input is rejected without storing anything.
+func TestExportAndImportCSV(t *testing.T) {
+	values := map[string]interface{}{
+		"bool":   true,
+		"int":    -42,
+		"float":  3.25,
+		"string": "commas, \"quotes\",\nand newlines",
+		"cr":     "lone\rcarriage returns",
+		"crlf":   "a\r\nb",
+		"a\r\nb": "carriage returns in names",
+	}
+
+	var buf bytes.Buffer
+	RunWithInstance(func(instance *Instance) {
+		for name, value := range values {
+			instance.MustSet(name, value)
+		}
+
+		if err := instance.ExportCSV(&buf); err != nil {
+			t.Fatal("Instance.ExportCSV: got error:\n", err)
+		}
+	})
+
+	if !strings.HasPrefix(buf.String(), "name,value,type\n") {
+		t.Errorf("Instance.ExportCSV: expected header row, got:\n%s", buf.String())
+	}
+
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("int", "existing")
+
+		if err := instance.ImportCSV(bytes.NewReader(buf.Bytes()), false); err != nil {
+			t.Fatal("Instance.ImportCSV: got error:\n", err)
+		}
+
+		for name, value := range values {
+			if name == "int" {
+				continue
+			}
+
+			if res := instance.MustGet(name); res != value {
+				t.Errorf("Instance.ImportCSV: got '%v' expected '%v' for '%s'", res, value, name)
+			}
+		}
+
+		if res := instance.MustGet("int"); res != "existing" {
+			t.Errorf("Instance.ImportCSV: got '%v' expected existing entry to be kept", res)
+		}
+
+		if err := instance.ImportCSV(bytes.NewReader(buf.Bytes()), true); err != nil {
+			t.Fatal("Instance.ImportCSV: got error:\n", err)
+		}
+
+		if res := instance.MustGet("int"); res != -42 {
+			t.Errorf("Instance.ImportCSV: got '%v' expected '-42' when overwriting", res)
+		}
+
+		expectError := func(input string, msg string) {
+			if err := instance.ImportCSV(strings.NewReader(input), true); err == nil {
+				t.Errorf("Instance.ImportCSV: expected error with %s", msg)
+			}
+		}
+
+		expectError("", "missing header")
+		expectError("key,value,type\n", "invalid header")
+		expectError("name,value,type\nfoo,bar\n", "missing field")
+		expectError("name,value,type\nfoo,bar,unknown\n", "unknown type")
+		expectError("name,value,type\nnew,1,bool\nfoo,bar,int\n", "invalid value")
+		expectError("name,value,type\nfoo,bar,quoted string\n", "invalid quoted field")
+
+		if instance.Exists("new") {
+			t.Error("Instance.ImportCSV: expected nothing to be stored with invalid input")
+		}
+	})
+}
+
+// TestPreviewCSV ensures that PreviewCSV reports the changes which ImportCSV
+// would make without storing anything.
+func TestPreviewCSV(t *testing.T) {
+	input := "name,value,type\n" +
+		"new,1,int\n" +
+		"same,hello,string\n" +
+		"updated,2.5,float64\n" +
+		"retyped,true,bool\n"
+
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("same", "hello")
+		instance.MustSet("updated", 1.5)
+		instance.MustSet("retyped", "yes")
+
+		changes, err := instance.PreviewCSV(strings.NewReader(input), true)
+		if err != nil {
+			t.Fatal("Instance.PreviewCSV: got error:\n", err)
+		}
+
+		if len(changes.Adds) != 1 || changes.Adds[0] != (Entry{Name: "new", Value: 1, Type: TypeInt}) {
+			t.Errorf("Instance.PreviewCSV: got adds '%v'", changes.Adds)
+		}
+
+		if len(changes.Updates) != 1 || changes.Updates[0] != (Change{Name: "updated", Old: 1.5, New: 2.5}) {
+			t.Errorf("Instance.PreviewCSV: got updates '%v'", changes.Updates)
+		}
+
+		if len(changes.TypeChanges) != 1 || changes.TypeChanges[0] != (Change{Name: "retyped", Old: "yes", New: true}) {
+			t.Errorf("Instance.PreviewCSV: got type changes '%v'", changes.TypeChanges)
+		}
+
+		if instance.Exists("new") || instance.MustGet("updated") != 1.5 {
+			t.Error("Instance.PreviewCSV: expected nothing to be stored")
+		}
+
+		if changes, err := instance.PreviewCSV(strings.NewReader(input), false); err != nil {
+			t.Error("Instance.PreviewCSV: got error:\n", err)
+		} else if len(changes.Adds) != 1 || len(changes.Updates) != 0 || len(changes.TypeChanges) != 0 {
+			t.Errorf("Instance.PreviewCSV: got '%v' expected only adds without overwrite", changes)
+		}
+
+		if _, err := instance.PreviewCSV(strings.NewReader("name,value,type\nfoo,bar,int\n"), true); err == nil {
+			t.Error("Instance.PreviewCSV: expected error with invalid value")
+		}
+
+		instance.MustSet("unrelated", 1)
+		if err := instance.ImportCSV(strings.NewReader(input), true); err != nil {
+			t.Fatal("Instance.ImportCSV: got error:\n", err)
+		}
+
+		if changes, err := instance.PreviewCSV(strings.NewReader(input), true); err != nil {
+			t.Error("Instance.PreviewCSV: got error:\n", err)
+		} else if len(changes.Adds)+len(changes.Updates)+len(changes.TypeChanges) != 0 {
+			t.Errorf("Instance.PreviewCSV: got '%v' expected no changes after import", changes)
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package metadb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrNoDefault is returned by Reset when no default value has been registered
+// for the requested entry.
+type ErrNoDefault struct {
+	Name string
+}
+
+// Error implements the error interface for ErrNoDefault.
+func (err *ErrNoDefault) Error() string {
+	return fmt.Sprintf("metadb: no default registered for '%s'", err.Name)
+}
+
+// RegisterDefault registers the default value of an entry, to which it may
+// later be restored with Reset. Registering a default does not write anything
+// to the database. If the value is of a disallowed type, an error is returned.
+func (instance *Instance) RegisterDefault(name string, value interface{}) error {
+	if _, err := toValueType(value); err != nil {
+		return instance.wrap("RegisterDefault", name, err)
+	}
+
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	instance.defaults[instance.key(name)] = value
+	return nil
+}
+
+// Reset restores an entry to its registered default value, creating the entry
+// if it does not exist. As the registered default is authoritative, the entry
+// is overwritten even if its current value is of a different type. If no
+// default has been registered, an ErrNoDefault is returned.
+func (instance *Instance) Reset(name string) error {
+	instance.mu.RLock()
+	value, ok := instance.defaults[instance.key(name)]
+	instance.mu.RUnlock()
+
+	if !ok {
+		return instance.wrap("Reset", name, &ErrNoDefault{name})
+	}
+
+	return instance.ForceSet(name, value)
+}
+
+// MustReset does the same as Reset, but panics if an error is returned.
+func (instance *Instance) MustReset(name string) {
+	if err := instance.Reset(name); err != nil {
+		panic(err)
+	}
+}
+
+// SeedDefaults creates each of the given entries which does not yet exist,
+// leaving existing entries untouched regardless of their value or type, and
+// returns the names of the entries which were created in order of name. All
+// entries are seeded within a single transaction, such that if any value is of
+// a disallowed type or is otherwise rejected, the error is returned and no
+// entry is created. Unlike RegisterDefault, the values are not registered for
+// use by Reset.
+func (instance *Instance) SeedDefaults(defaults map[string]interface{}) (inserted []string, err error) {
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	err = instance.withTx(func(tx *Instance) error {
+		inserted = make([]string, 0)
+		for _, name := range names {
+			// every value is checked, even those of entries which already exist
+			value := defaults[name]
+			if tx.options.CoerceNumbers {
+				var err error
+				if value, err = coerceNumber(value); err != nil {
+					return fmt.Errorf("metadb: failed to seed default for '%s':\n%s", name, err)
+				}
+			}
+
+			if _, err := toValueType(value); err != nil {
+				return fmt.Errorf("metadb: failed to seed default for '%s':\n%s", name, err)
+			}
+
+			exists, err := tx.exists(name)
+			if err != nil {
+				return fmt.Errorf("metadb: failed to seed default for '%s':\n%s", name, err)
+			} else if exists {
+				continue
+			}
+
+			changed, err := tx.write(name, value, false)
+			if err != nil {
+				return fmt.Errorf("metadb: failed to seed default for '%s':\n%s", name, err)
+			} else if changed {
+				inserted = append(inserted, name)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+// MustSeedDefaults does the same as SeedDefaults, but panics if an error is
+// returned.
+func (instance *Instance) MustSeedDefaults(defaults map[string]interface{}) []string {
+	if res, err := instance.SeedDefaults(defaults); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// UnsetDefaults returns the names of the entries for which a default has been
+// registered with RegisterDefault, but which do not exist, in order of name.
+// Such entries may be created through SeedDefaults or Reset.
+func (instance *Instance) UnsetDefaults() ([]string, error) {
+	keys, err := instance.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	exists := make(map[string]bool, len(keys))
+	for _, name := range keys {
+		exists[name] = true
+	}
+
+	instance.mu.RLock()
+	names := make([]string, 0)
+	for key := range instance.defaults {
+		if !strings.HasPrefix(key, instance.prefix) {
+			continue
+		} else if name := instance.unkey(key); !exists[name] {
+			names = append(names, name)
+		}
+	}
+	instance.mu.RUnlock()
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// MustUnsetDefaults does the same as UnsetDefaults, but panics if an error is
+// returned.
+func (instance *Instance) MustUnsetDefaults() []string {
+	if res, err := instance.UnsetDefaults(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
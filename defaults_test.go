@@ -0,0 +1,117 @@
+package metadb
+
+import "testing"
+
+// TestRegisterDefaultAndReset ensures that registered defaults are restored by
+// Reset and that resetting an entry without a default results in an
+// ErrNoDefault.
+func TestRegisterDefaultAndReset(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		if err := instance.RegisterDefault("foo", map[string]string{"disallowed": "type"}); err == nil {
+			t.Error("Instance.RegisterDefault: expected error with disallowed type")
+		}
+
+		if err := instance.RegisterDefault("foo", 8080); err != nil {
+			t.Fatal("Instance.RegisterDefault: got error:\n", err)
+		}
+
+		if instance.Exists("foo") {
+			t.Error("Instance.RegisterDefault: expected entry not to be created")
+		}
+
+		if err := instance.Reset("foo"); err != nil {
+			t.Error("Instance.Reset: got error:\n", err)
+		} else if value := instance.MustGet("foo"); value != 8080 {
+			t.Errorf("Instance.Reset: got '%v' expected '8080'", value)
+		}
+
+		instance.MustForceSet("foo", "changed")
+		if err := panicked(func() { instance.MustReset("foo") }); err != nil {
+			t.Error("Instance.MustReset: got panic:\n", err)
+		} else if value := instance.MustGet("foo"); value != 8080 {
+			t.Errorf("Instance.MustReset: got '%v' expected '8080'", value)
+		}
+
+		if err := instance.Reset("bar"); err == nil {
+			t.Error("Instance.Reset: expected error with no registered default")
+		} else if _, ok := err.(*ErrNoDefault); !ok {
+			t.Error("Instance.Reset: expected error of type *ErrNoDefault")
+		}
+
+		if err := panicked(func() { instance.MustReset("bar") }); err == nil {
+			t.Error("Instance.MustReset: expected panic with no registered default")
+		}
+	})
+}
+
+// TestSeedDefaults ensures that SeedDefaults only creates missing entries,
+// reporting which were created, and that no entry is created if any default is
+// of a disallowed type.
+func TestSeedDefaults(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("theme", "dark")
+		instance.MustSet("volume", "loud")
+
+		inserted, err := instance.SeedDefaults(map[string]interface{}{
+			"theme":   "light",
+			"volume":  10,
+			"width":   80,
+			"enabled": true,
+		})
+		if err != nil {
+			t.Fatal("Instance.SeedDefaults: got error:\n", err)
+		}
+
+		if len(inserted) != 2 || inserted[0] != "enabled" || inserted[1] != "width" {
+			t.Errorf("Instance.SeedDefaults: got '%v' expected '[enabled width]'", inserted)
+		}
+
+		expected := map[string]interface{}{"theme": "dark", "volume": "loud", "width": 80, "enabled": true}
+		for name, value := range expected {
+			if got := instance.MustGet(name); got != value {
+				t.Errorf("Instance.Get: got '%v' expected '%v'", got, value)
+			}
+		}
+
+		if _, err := instance.SeedDefaults(map[string]interface{}{
+			"height": 24,
+			"theme":  struct{}{},
+		}); err == nil {
+			t.Error("Instance.SeedDefaults: expected error with disallowed type")
+		}
+
+		if instance.Exists("height") {
+			t.Error("Instance.SeedDefaults: expected no entries to be created after error")
+		}
+
+		if inserted := instance.MustSeedDefaults(expected); len(inserted) != 0 {
+			t.Errorf("Instance.MustSeedDefaults: got '%v' expected '[]'", inserted)
+		}
+	})
+}
+
+// TestUnsetDefaults ensures that UnsetDefaults lists only the registered
+// defaults within the scope of the Instance whose entries do not exist.
+func TestUnsetDefaults(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("app.theme", "dark")
+		for _, name := range []string{"app.theme", "app.width", "app.height", "other"} {
+			if err := instance.RegisterDefault(name, 1); err != nil {
+				t.Fatal("Instance.RegisterDefault: got error:\n", err)
+			}
+		}
+
+		if names := instance.MustUnsetDefaults(); len(names) != 3 ||
+			names[0] != "app.height" || names[1] != "app.width" || names[2] != "other" {
+			t.Errorf("Instance.UnsetDefaults: got '%v' expected '[app.height app.width other]'", names)
+		}
+
+		scoped := instance.WithPrefix("app.")
+		scoped.MustReset("width")
+		if names, err := scoped.UnsetDefaults(); err != nil {
+			t.Error("Instance.UnsetDefaults: got error:\n", err)
+		} else if len(names) != 1 || names[0] != "height" {
+			t.Errorf("Instance.UnsetDefaults: got '%v' expected '[height]'", names)
+		}
+	})
+}
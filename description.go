@@ -0,0 +1,115 @@
+package metadb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SetDescription sets the human-readable description of an entry, replacing
+// any previous one. Passing the empty string removes the description. If the
+// entry does not exist, an ErrNoEntry is returned, and if the Instance was not
+// created with Options.Descriptions, an error is returned.
+func (instance *Instance) SetDescription(name, description string) error {
+	return instance.wrap("SetDescription", name, instance.setDescription(name, description))
+}
+
+// setDescription implements SetDescription.
+func (instance *Instance) setDescription(name, description string) error {
+	if !instance.options.Descriptions {
+		return errors.New("metadb: descriptions are not enabled")
+	}
+
+	res, err := instance.exec("UPDATE {table} SET {description} = ? WHERE {name} = ? AND {visible};",
+		description, instance.key(name))
+	if err != nil {
+		return fmt.Errorf("metadb: failed to set description for '%s':\n%s", name, err)
+	}
+
+	// if RowsAffected is unsupported, check whether the entry exists
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return &ErrNoEntry{name}
+	} else if err != nil {
+		if exists, err := instance.exists(name); err != nil {
+			return fmt.Errorf("metadb: failed to set description for '%s':\n%s", name, err)
+		} else if !exists {
+			return &ErrNoEntry{name}
+		}
+	}
+
+	return nil
+}
+
+// MustSetDescription does the same as SetDescription, but panics if an error
+// is returned.
+func (instance *Instance) MustSetDescription(name, description string) {
+	if err := instance.SetDescription(name, description); err != nil {
+		panic(err)
+	}
+}
+
+// GetDescription returns the description of an entry, or the empty string if
+// it has none. If the entry does not exist, an ErrNoEntry is returned, and if
+// the Instance was not created with Options.Descriptions, an error is
+// returned.
+func (instance *Instance) GetDescription(name string) (string, error) {
+	description, err := instance.getDescription(name)
+	return description, instance.wrap("GetDescription", name, err)
+}
+
+// getDescription implements GetDescription.
+func (instance *Instance) getDescription(name string) (string, error) {
+	if !instance.options.Descriptions {
+		return "", errors.New("metadb: descriptions are not enabled")
+	}
+
+	row := instance.queryRow("SELECT {description} FROM {table} WHERE {name} = ? AND {visible};", instance.key(name))
+	var description sql.NullString
+	if err := row.Scan(&description); err != nil {
+		if err == sql.ErrNoRows {
+			return "", &ErrNoEntry{name}
+		}
+
+		return "", fmt.Errorf("metadb: failed to get description for '%s':\n%s", name, err)
+	}
+
+	return description.String, nil
+}
+
+// MustGetDescription does the same as GetDescription, but panics if an error
+// is returned.
+func (instance *Instance) MustGetDescription(name string) string {
+	if res, err := instance.GetDescription(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// descriptions returns the descriptions of every entry which has one, keyed by
+// name.
+func (instance *Instance) descriptions() (map[string]string, error) {
+	scope, args := instance.scope()
+	rows, err := instance.query("SELECT {name}, {description} FROM {table} WHERE "+scope+
+		" AND {description} IS NOT NULL;", args...)
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read descriptions:\n%s", err)
+	}
+	defer rows.Close()
+
+	descriptions := make(map[string]string)
+	for rows.Next() {
+		var name, description string
+		if err := rows.Scan(&name, &description); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan description:\n%s", err)
+		}
+
+		descriptions[instance.unkey(name)] = description
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read descriptions:\n%s", err)
+	}
+
+	return descriptions, nil
+}
@@ -0,0 +1,60 @@
+package metadb
+
+import (
+	"database/sql"
+	"testing"
+)
+
+// TestDescriptions ensures that an existing table is migrated, and that
+// descriptions are stored per entry and included by Entries.
+func TestDescriptions(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		legacy, err := NewInstance(db)
+		if err != nil {
+			t.Fatal("NewInstance: got error:\n", err)
+		}
+
+		legacy.MustSet("port", 8080)
+		legacy.MustSet("host", "localhost")
+		if err := legacy.SetDescription("port", "Port to listen on"); err == nil {
+			t.Error("Instance.SetDescription: expected error with descriptions disabled")
+		}
+
+		instance, err := NewInstanceWithOptions(db, Options{Descriptions: true})
+		if err != nil {
+			t.Fatal("NewInstanceWithOptions: got error while adding descriptions:\n", err)
+		}
+
+		if res := instance.MustGetDescription("port"); res != "" {
+			t.Errorf("Instance.GetDescription: got '%s' expected ''", res)
+		}
+
+		instance.MustSetDescription("port", "Port to listen on")
+		if res, err := instance.GetDescription("port"); err != nil {
+			t.Error("Instance.GetDescription: got error:\n", err)
+		} else if res != "Port to listen on" {
+			t.Errorf("Instance.GetDescription: got '%s' expected 'Port to listen on'", res)
+		}
+
+		if err := instance.SetDescription("missing", "?"); err == nil {
+			t.Error("Instance.SetDescription: expected error with missing entry")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Errorf("Instance.SetDescription: got '%v' expected ErrNoEntry", err)
+		}
+
+		if _, err := instance.GetDescription("missing"); err == nil {
+			t.Error("Instance.GetDescription: expected error with missing entry")
+		}
+
+		// descriptions are kept when the value changes
+		instance.MustSet("port", 9090)
+		entries, err := instance.Entries()
+		if err != nil {
+			t.Fatal("Instance.Entries: got error:\n", err)
+		}
+
+		if len(entries) != 2 || entries[0].Description != "" || entries[1].Description != "Port to listen on" {
+			t.Errorf("Instance.Entries: got '%+v' expected description for 'port' only", entries)
+		}
+	})
+}
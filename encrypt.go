@@ -0,0 +1,208 @@
+package metadb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// encryptedPrefix marks the blob strings encrypted with Options.EncryptionKey,
+// distinguishing them from those written before encryption was enabled.
+const encryptedPrefix = "aes-gcm:"
+
+// ErrNotEncrypted is returned when reading an entry whose value is stored in
+// plaintext while Options.EncryptionKey is set, such as one written before
+// encryption was enabled. Such entries may be found with PlaintextEntries and
+// encrypted with EncryptPlaintext.
+type ErrNotEncrypted struct {
+	Name string
+}
+
+// Error implements the error interface for ErrNotEncrypted.
+func (err *ErrNotEncrypted) Error() string {
+	return fmt.Sprintf("metadb: value of '%s' is not encrypted", err.Name)
+}
+
+// newAEAD returns the AES-GCM cipher for the given key, which must be 16, 24,
+// or 32 bytes long.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// encrypt encrypts a blob string with a random nonce, returning the nonce and
+// ciphertext encoded as base64 behind encryptedPrefix.
+func (instance *Instance) encrypt(blob string) (string, error) {
+	nonce := make([]byte, instance.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := instance.aead.Seal(nonce, nonce, []byte(blob), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt reverses encrypt for the blob string of the named entry. If the blob
+// lacks encryptedPrefix, an ErrNotEncrypted is returned, while if it cannot be
+// decrypted, such as with the wrong key, an ErrFailedToParse is returned.
+func (instance *Instance) decrypt(name string, blob string) (string, error) {
+	if !strings.HasPrefix(blob, encryptedPrefix) {
+		return "", &ErrNotEncrypted{name}
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(blob[len(encryptedPrefix):])
+	if err != nil {
+		return "", &ErrFailedToParse{err}
+	}
+
+	size := instance.aead.NonceSize()
+	if len(sealed) < size {
+		return "", &ErrFailedToParse{errors.New("encrypted value is too short")}
+	}
+
+	res, err := instance.aead.Open(nil, sealed[:size], sealed[size:], nil)
+	if err != nil {
+		return "", &ErrFailedToParse{err}
+	}
+
+	return string(res), nil
+}
+
+// PlaintextEntries returns the names of the entries whose values are stored in
+// plaintext, in order of name, as is the case for those written before
+// Options.EncryptionKey was set. If it is not set, an error is returned.
+func (instance *Instance) PlaintextEntries() ([]string, error) {
+	if instance.aead == nil {
+		return nil, errors.New("metadb: encryption is not enabled")
+	}
+
+	scope, args := instance.scope()
+	rows, err := instance.query("SELECT {name}, {value} FROM {table} WHERE "+scope+" ORDER BY {name};", args...)
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name, blob string
+		if err := rows.Scan(&name, &blob); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+		}
+
+		if !strings.HasPrefix(blob, encryptedPrefix) {
+			names = append(names, instance.unkey(name))
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+
+	return names, nil
+}
+
+// MustPlaintextEntries does the same as PlaintextEntries, but panics if an
+// error is returned.
+func (instance *Instance) MustPlaintextEntries() []string {
+	if res, err := instance.PlaintextEntries(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// EncryptPlaintext encrypts the stored value of every entry returned by
+// PlaintextEntries within a single transaction, returning their number. Values
+// are encrypted as stored, such that any registered codec continues to apply.
+// If Options.History is set, the previous values of those entries are
+// encrypted as well. If Options.EncryptionKey is not set, an error is
+// returned.
+func (instance *Instance) EncryptPlaintext() (int, error) {
+	names, err := instance.PlaintextEntries()
+	if err != nil {
+		return 0, err
+	}
+
+	err = instance.withTx(func(tx *Instance) error {
+		for _, name := range names {
+			if err := tx.encryptStored("{table}", name); err != nil {
+				return err
+			}
+
+			if tx.options.History {
+				if err := tx.encryptStored("{history}", name); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(names), nil
+}
+
+// MustEncryptPlaintext does the same as EncryptPlaintext, but panics if an
+// error is returned.
+func (instance *Instance) MustEncryptPlaintext() int {
+	if res, err := instance.EncryptPlaintext(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// encryptStored encrypts every plaintext value stored for the named entry in
+// the given table, either {table} or {history}.
+func (instance *Instance) encryptStored(table string, name string) error {
+	rows, err := instance.query("SELECT DISTINCT {value} FROM "+table+" WHERE {name} = ?;", instance.key(name))
+	if err != nil {
+		return fmt.Errorf("metadb: failed to encrypt entry for '%s':\n%s", name, err)
+	}
+
+	blobs := make([]string, 0)
+	for rows.Next() {
+		var blob string
+		if err := rows.Scan(&blob); err != nil {
+			rows.Close()
+			return fmt.Errorf("metadb: failed to encrypt entry for '%s':\n%s", name, err)
+		}
+
+		if !strings.HasPrefix(blob, encryptedPrefix) {
+			blobs = append(blobs, blob)
+		}
+	}
+
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("metadb: failed to encrypt entry for '%s':\n%s", name, err)
+	}
+
+	for _, blob := range blobs {
+		encrypted, err := instance.encrypt(blob)
+		if err != nil {
+			return fmt.Errorf("metadb: failed to encrypt entry for '%s':\n%s", name, err)
+		}
+
+		if _, err := instance.exec("UPDATE "+table+" SET {value} = ? WHERE {name} = ? AND {value} = ?;",
+			encrypted, instance.key(name), blob); err != nil {
+			return fmt.Errorf("metadb: failed to encrypt entry for '%s':\n%s", name, err)
+		}
+	}
+
+	return nil
+}
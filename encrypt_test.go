@@ -0,0 +1,99 @@
+package metadb
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestEncryptionKey ensures that values are stored encrypted and read back
+// transparently, that plaintext values are detected and may be encrypted, and
+// that invalid keys are rejected.
+func TestEncryptionKey(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	RunWithDB(func(db *sql.DB) {
+		if _, err := NewInstanceWithOptions(db, Options{EncryptionKey: []byte("short")}); err == nil {
+			t.Error("NewInstanceWithOptions: expected error with invalid encryption key")
+		}
+
+		plain, err := NewInstance(db)
+		if err != nil {
+			t.Fatal("NewInstance: got error:\n", err)
+		}
+
+		plain.MustSet("legacy", "plaintext")
+		if _, err := plain.PlaintextEntries(); err == nil {
+			t.Error("Instance.PlaintextEntries: expected error with encryption disabled")
+		}
+
+		instance, err := NewInstanceWithOptions(db, Options{EncryptionKey: key, History: true})
+		if err != nil {
+			t.Fatal("NewInstanceWithOptions: got error:\n", err)
+		}
+
+		instance.MustSet("secret", "hunter2")
+		instance.MustSet("port", 8080)
+		instance.MustSet("toggle", false)
+
+		fixtures := GetFixtures(instance)
+		if blob := fixtures["secret"].Value.(string); !strings.HasPrefix(blob, encryptedPrefix) || strings.Contains(blob, "hunter2") {
+			t.Errorf("Instance.Set: got stored value '%s' expected it to be encrypted", blob)
+		} else if fixtures["secret"].ValueType != TypeString {
+			t.Errorf("Instance.Set: got stored type '%d' expected '%d'", fixtures["secret"].ValueType, TypeString)
+		}
+
+		if res := instance.MustGet("secret"); res != "hunter2" {
+			t.Errorf("Instance.Get: got '%v' expected 'hunter2'", res)
+		}
+
+		if res := instance.MustToggle("toggle"); res != true {
+			t.Errorf("Instance.Toggle: got '%v' expected 'true'", res)
+		}
+
+		if _, err := instance.Get("legacy"); err == nil {
+			t.Error("Instance.Get: expected error with plaintext entry")
+		} else if _, ok := err.(*ErrNotEncrypted); !ok {
+			t.Errorf("Instance.Get: got '%v' expected ErrNotEncrypted", err)
+		}
+
+		if res := instance.MustPlaintextEntries(); !reflect.DeepEqual(res, []string{"legacy"}) {
+			t.Errorf("Instance.PlaintextEntries: got '%v' expected '[legacy]'", res)
+		}
+
+		if res, err := instance.EncryptPlaintext(); err != nil {
+			t.Error("Instance.EncryptPlaintext: got error:\n", err)
+		} else if res != 1 {
+			t.Errorf("Instance.EncryptPlaintext: got %d expected 1", res)
+		}
+
+		if res := instance.MustGet("legacy"); res != "plaintext" {
+			t.Errorf("Instance.Get: got '%v' expected 'plaintext' after encrypting", res)
+		}
+
+		if res, err := instance.FindByValue(8080); err != nil {
+			t.Error("Instance.FindByValue: got error:\n", err)
+		} else if !reflect.DeepEqual(res, []string{"port"}) {
+			t.Errorf("Instance.FindByValue: got '%v' expected '[port]'", res)
+		}
+
+		if _, err := instance.EntriesSortedByValue(TypeInt, false, 0); err == nil {
+			t.Error("Instance.EntriesSortedByValue: expected error with encrypted entries")
+		}
+
+		instance.MustSet("legacy", "updated")
+		if history := instance.MustHistory("legacy"); len(history) != 1 || history[0].Value != "plaintext" {
+			t.Errorf("Instance.History: got '%v' expected a single previous value 'plaintext'", history)
+		}
+
+		other, err := NewInstanceWithOptions(db, Options{EncryptionKey: []byte("fedcba9876543210")})
+		if err != nil {
+			t.Fatal("NewInstanceWithOptions: got error:\n", err)
+		}
+
+		if _, err := other.Get("secret"); err == nil {
+			t.Error("Instance.Get: expected error with the wrong encryption key")
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package metadb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidEnumValue is returned when writing a string to an entry registered
+// with RegisterEnum which is not one of its allowed values, or when reading
+// such a string with GetEnum.
+type ErrInvalidEnumValue struct {
+	Name    string
+	Value   string
+	Allowed []string
+}
+
+// Error implements the error interface for ErrInvalidEnumValue.
+func (err *ErrInvalidEnumValue) Error() string {
+	return fmt.Sprintf("metadb: '%s' is not a valid value for '%s' (allowed: %s)",
+		err.Value, err.Name, strings.Join(err.Allowed, ", "))
+}
+
+// RegisterEnum restricts the entry with the given name to the strings within
+// allowed, such as the levels of a logging setting. Once registered, Set,
+// ForceSet, and the methods built upon them return an ErrInvalidEnumValue
+// when writing any other string to the entry, and an ErrWrongType when writing
+// a value of any other type. Enums are checked before any validator registered
+// with SetValidator. Passing an empty list removes the restriction. Existing
+// entries are not checked, though GetEnum reports those which are invalid.
+func (instance *Instance) RegisterEnum(name string, allowed []string) {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	if len(allowed) == 0 {
+		delete(instance.enums, instance.key(name))
+		return
+	}
+
+	instance.enums[instance.key(name)] = append([]string{}, allowed...)
+}
+
+// checkEnum returns an error if an enum has been registered for the named
+// entry and value is not one of its allowed strings.
+func (instance *Instance) checkEnum(name string, value interface{}) error {
+	instance.mu.RLock()
+	allowed, ok := instance.enums[instance.key(name)]
+	instance.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		got, _ := toValueType(value)
+		return &ErrWrongType{name, TypeString, got}
+	}
+
+	for _, candidate := range allowed {
+		if str == candidate {
+			return nil
+		}
+	}
+
+	return &ErrInvalidEnumValue{name, str, allowed}
+}
+
+// GetEnum does the same as GetString, but additionally returns an
+// ErrInvalidEnumValue if an enum has been registered for the entry with
+// RegisterEnum and the stored string is not one of its allowed values, as may
+// be the case for entries written before the enum was registered.
+func (instance *Instance) GetEnum(name string) (string, error) {
+	value, err := instance.GetString(name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := instance.checkEnum(name, value); err != nil {
+		return "", instance.wrap("GetEnum", name, err)
+	}
+
+	return value, nil
+}
+
+// MustGetEnum does the same as GetEnum, but panics if an error is returned.
+func (instance *Instance) MustGetEnum(name string) string {
+	if res, err := instance.GetEnum(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
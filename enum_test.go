@@ -0,0 +1,54 @@
+package metadb
+
+import "testing"
+
+// TestRegisterEnum ensures that only the allowed strings may be written to an
+// enum entry, and that GetEnum reports stored strings which are not allowed.
+func TestRegisterEnum(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("log.level", "verbose")
+		instance.RegisterEnum("log.level", []string{"debug", "info", "warn"})
+
+		if _, err := instance.GetEnum("log.level"); err == nil {
+			t.Error("Instance.GetEnum: expected error with value written before registration")
+		} else if _, ok := err.(*ErrInvalidEnumValue); !ok {
+			t.Errorf("Instance.GetEnum: got '%v' expected ErrInvalidEnumValue", err)
+		}
+
+		if err := instance.Set("log.level", "trace"); err == nil {
+			t.Error("Instance.Set: expected error with value not allowed")
+		} else if enumErr, ok := err.(*ErrInvalidEnumValue); !ok {
+			t.Errorf("Instance.Set: got '%v' expected ErrInvalidEnumValue", err)
+		} else if enumErr.Value != "trace" || len(enumErr.Allowed) != 3 {
+			t.Errorf("Instance.Set: got '%+v' expected value and allowed set", enumErr)
+		}
+
+		if err := instance.ForceSet("log.level", 1); err == nil {
+			t.Error("Instance.ForceSet: expected error with non-string value")
+		} else if _, ok := err.(*ErrWrongType); !ok {
+			t.Errorf("Instance.ForceSet: got '%v' expected ErrWrongType", err)
+		}
+
+		if _, err := instance.Append("log.level", "x"); err == nil {
+			t.Error("Instance.Append: expected error with value not allowed")
+		}
+
+		if err := instance.Validate("log.level", "trace"); err == nil {
+			t.Error("Instance.Validate: expected error with value not allowed")
+		}
+
+		if err := instance.Set("log.level", "warn"); err != nil {
+			t.Error("Instance.Set: got error:\n", err)
+		}
+
+		if value := instance.MustGetEnum("log.level"); value != "warn" {
+			t.Errorf("Instance.GetEnum: got '%v' expected 'warn'", value)
+		}
+
+		instance.RegisterEnum("log.level", nil)
+		instance.MustSet("log.level", "trace")
+		if value := instance.MustGetEnum("log.level"); value != "trace" {
+			t.Errorf("Instance.GetEnum: got '%v' expected 'trace' after removal", value)
+		}
+	})
+}
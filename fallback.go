@@ -0,0 +1,21 @@
+package metadb
+
+// WithFallback returns a copy of the Instance which reads from fallback any
+// entry which does not exist within the Instance itself, such as to layer
+// per-user overrides stored in one database over shared defaults stored in
+// another. Get, Exists, Lookup, and the typed getters built upon Get consult
+// the Instance first and then fallback, which may itself have a fallback.
+// Every write, along with methods which operate on all entries such as Keys
+// and Entries, only ever concerns the returned Instance, never its fallback.
+// Scoping the returned Instance with WithPrefix scopes its fallback alike.
+// Passing nil removes the fallback. The returned Instance shares its
+// configuration with the original.
+func (instance *Instance) WithFallback(fallback *Instance) *Instance {
+	if fallback == instance {
+		panic("metadb: an Instance cannot be its own fallback")
+	}
+
+	chained := *instance
+	chained.fallback = fallback
+	return &chained
+}
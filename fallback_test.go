@@ -0,0 +1,77 @@
+package metadb
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+// TestWithFallback ensures that reads cascade to the fallback Instance while
+// writes only touch the primary.
+func TestWithFallback(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		// the stores are kept in separate tables, as they would be in separate
+		// databases
+		defaults, err := NewInstance(db)
+		if err != nil {
+			t.Fatal("NewInstance: got error:\n", err)
+		}
+
+		overrides, err := NewInstanceWithOptions(db, Options{Table: "overrides"})
+		if err != nil {
+			t.Fatal("NewInstance: got error:\n", err)
+		}
+
+		defaults.MustSet("server.port", 80)
+		defaults.MustSet("server.host", "localhost")
+		overrides.MustSet("server.port", 8080)
+
+		chained := overrides.WithFallback(defaults)
+		if value := chained.MustGetInt("server.port"); value != 8080 {
+			t.Errorf("Instance.GetInt: got '%d' expected override '8080'", value)
+		}
+
+		if value := chained.MustGetString("server.host"); value != "localhost" {
+			t.Errorf("Instance.GetString: got '%s' expected default 'localhost'", value)
+		}
+
+		if !chained.Exists("server.host") {
+			t.Error("Instance.Exists: got 'false' expected 'true' for default")
+		}
+
+		if _, found := chained.MustLookup("server.missing"); found {
+			t.Error("Instance.Lookup: got 'true' expected 'false' for missing entry")
+		}
+
+		if _, err := chained.Get("server.missing"); err == nil {
+			t.Error("Instance.Get: expected error with entry missing from both")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Errorf("Instance.Get: got '%v' expected ErrNoEntry", err)
+		}
+
+		if value := chained.WithPrefix("server.").MustGetString("host"); value != "localhost" {
+			t.Errorf("Instance.GetString: got '%s' expected scoped default 'localhost'", value)
+		}
+
+		chained.MustSet("server.host", "example.com")
+		if value := defaults.MustGetString("server.host"); value != "localhost" {
+			t.Errorf("Instance.Set: got default '%s' expected it to be left as-is", value)
+		}
+
+		chained.MustDelete("server.host")
+		if value := chained.MustGetString("server.host"); value != "localhost" {
+			t.Errorf("Instance.GetString: got '%s' expected default 'localhost' after delete", value)
+		}
+
+		chained.MustSet("server.renamed", true)
+		chained.MustRename("server.renamed", "server.host")
+
+		if keys := chained.MustKeys(); !reflect.DeepEqual(keys, []string{"server.host", "server.port"}) {
+			t.Errorf("Instance.Keys: got '%v' expected only entries of the primary", keys)
+		}
+
+		if chained.WithFallback(nil).Exists("server.missing") {
+			t.Error("Instance.Exists: got 'true' expected 'false' without fallback")
+		}
+	})
+}
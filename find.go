@@ -0,0 +1,275 @@
+package metadb
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FindByValue returns the names of all entries holding the given value, in
+// order of name. The value is encoded in the same way as it would be by Set,
+// and entries only match if both their stored value and type are equal to
+// those of the given value. Entries with a registered codec are compared by
+// their decoded value, as is every entry if Options.EncryptionKey is set. If
+// the value is of a disallowed type, an error is returned.
+func (instance *Instance) FindByValue(value interface{}) ([]string, error) {
+	valueType, err := toValueType(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// encrypted values can only be compared once decrypted
+	if instance.aead != nil {
+		entries, err := instance.Filter(func(name string, current interface{}) bool {
+			return equal(current, value)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, len(entries))
+		for i, entry := range entries {
+			names[i] = entry.Name
+		}
+
+		return names, nil
+	}
+
+	blob, err := toBlobString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// natively stored values are matched in either form, as they may have been
+	// written with or without Options.NativeTypes set
+	condition, args := "{value} = ?", []interface{}{valueType, blob}
+	if native, ok := toNative(value); ok {
+		condition = "({value} = ? OR {value} = ?)"
+		args = append(args, native)
+	}
+
+	scope, scopeArgs := instance.scope()
+	rows, err := instance.query("SELECT {name} FROM {table} WHERE {type} = ? AND "+condition+" AND "+scope+";",
+		append(args, scopeArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to find entries by value:\n%s", err)
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+		}
+
+		// entries with a codec are compared separately below
+		name = instance.unkey(name)
+		if !instance.opaque(name) {
+			names = append(names, name)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to find entries by value:\n%s", err)
+	}
+
+	for _, name := range instance.codecNames() {
+		res, err := instance.get(name)
+		if err != nil {
+			if _, ok := err.(*ErrNoEntry); ok {
+				continue
+			}
+
+			return nil, err
+		}
+
+		if equal(res, value) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// codecNames returns the names of the entries within the scope of the Instance
+// for which a codec is registered, whether or not they exist.
+func (instance *Instance) codecNames() []string {
+	instance.mu.RLock()
+	defer instance.mu.RUnlock()
+
+	names := make([]string, 0, len(instance.codecs))
+	for name := range instance.codecs {
+		if strings.HasPrefix(name, instance.prefix) {
+			names = append(names, instance.unkey(name))
+		}
+	}
+
+	return names
+}
+
+// EntriesSortedByValue returns the entries holding data of the given numeric
+// type, which must be one of TypeInt, TypeInt64, or TypeFloat, ordered by their
+// value in ascending order, or descending order if desc is true. At most limit
+// entries are returned, or all of them if limit is not positive. Values are
+// ordered by the database, which must be able to convert the stored values to
+// numbers, as SQLite and MySQL do. Entries with a registered codec are
+// skipped, as their stored values cannot be ordered.
+func (instance *Instance) EntriesSortedByValue(t ValueType, desc bool, limit int) ([]Entry, error) {
+	if t != TypeInt && t != TypeInt64 && t != TypeFloat {
+		return nil, fmt.Errorf("metadb: cannot sort entries of non-numeric type %s", t)
+	} else if instance.aead != nil {
+		return nil, fmt.Errorf("metadb: cannot sort encrypted entries")
+	}
+
+	scope, args := instance.scope()
+	query := "SELECT {name}, {value}, {type} FROM {table} WHERE {type} = ? AND " + scope
+	args = append([]interface{}{t}, args...)
+
+	if names := instance.codecNames(); len(names) > 0 {
+		query += " AND {name} NOT IN (?" + strings.Repeat(", ?", len(names)-1) + ")"
+		for _, name := range names {
+			args = append(args, instance.key(name))
+		}
+	}
+
+	// adding zero converts values stored as blob strings to numbers
+	query += " ORDER BY {value} + 0"
+	if desc {
+		query += " DESC"
+	}
+
+	query += ", {name}"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := instance.query(query+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+	defer rows.Close()
+
+	entries := make([]Entry, 0)
+	for rows.Next() {
+		var name, blob string
+		var valueType ValueType
+		if err := rows.Scan(&name, &blob, &valueType); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+		}
+
+		name = instance.unkey(name)
+		value, err := instance.decode(name, blob, valueType)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, Entry{Name: name, Value: value, Type: valueType})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+
+	return entries, nil
+}
+
+// MustEntriesSortedByValue does the same as EntriesSortedByValue, but panics if
+// an error is returned.
+func (instance *Instance) MustEntriesSortedByValue(t ValueType, desc bool, limit int) []Entry {
+	if res, err := instance.EntriesSortedByValue(t, desc, limit); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// Filter returns every entry for which fn returns true when passed its name
+// and decoded value, in order of name. Every entry is read and decoded, but
+// only one at a time, such that only the matching entries are held in memory.
+// This suits conditions which cannot be expressed in SQL, such as on the
+// length of strings or the contents of slices.
+func (instance *Instance) Filter(fn func(name string, value interface{}) bool) ([]Entry, error) {
+	entries := make([]Entry, 0)
+	err := instance.iterate(true, func(name string, value interface{}) error {
+		if !fn(name, value) {
+			return nil
+		}
+
+		valueType, err := toValueType(value)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, Entry{Name: name, Value: value, Type: valueType})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// MustFilter does the same as Filter, but panics if an error is returned.
+func (instance *Instance) MustFilter(fn func(name string, value interface{}) bool) []Entry {
+	if res, err := instance.Filter(fn); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// EmptyValues returns every entry holding the zero value of its type, such as
+// an empty string or slice, zero, or false, in order of name, such as to find
+// settings which were created but never configured. Each Entry includes the
+// type of its value, explaining why it was deemed empty. Values are decoded
+// one at a time, as by Filter.
+func (instance *Instance) EmptyValues() ([]Entry, error) {
+	return instance.Filter(func(name string, value interface{}) bool {
+		return isZero(value)
+	})
+}
+
+// MustEmptyValues does the same as EmptyValues, but panics if an error is
+// returned.
+func (instance *Instance) MustEmptyValues() []Entry {
+	if res, err := instance.EmptyValues(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// isZero returns whether value is the zero value of its type, treating empty
+// slices alike and comparing big numbers by value.
+func isZero(value interface{}) bool {
+	switch value := value.(type) {
+	case bool:
+		return !value
+	case int:
+		return value == 0
+	case float64:
+		return value == 0
+	case string:
+		return value == ""
+	case int64:
+		return value == 0
+	case []string:
+		return len(value) == 0
+	case complex128:
+		return value == 0
+	case time.Duration:
+		return value == 0
+	case *big.Int:
+		return value.Sign() == 0
+	case *big.Float:
+		return value.Sign() == 0
+	}
+
+	return false
+}
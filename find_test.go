@@ -0,0 +1,149 @@
+package metadb
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestFindByValue ensures that entries are matched on both their value and
+// type, including those with a registered codec.
+func TestFindByValue(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.SetValueCodec("secret", reverse, reverse)
+
+		instance.MustSet("port", 8080)
+		instance.MustSet("altPort", 8080)
+		instance.MustSet("portString", "8080")
+		instance.MustSet("enabled", true)
+		instance.MustSet("ratio", 0.5)
+		instance.MustSet("secret", "8080")
+
+		testFind := func(value interface{}, expected []string) {
+			if res, err := instance.FindByValue(value); err != nil {
+				t.Errorf("Instance.FindByValue: got error finding '%v':\n%s", value, err)
+			} else if !reflect.DeepEqual(res, expected) {
+				t.Errorf("Instance.FindByValue: got '%v' expected '%v'", res, expected)
+			}
+		}
+
+		testFind(8080, []string{"altPort", "port"})
+		testFind("8080", []string{"portString", "secret"})
+		testFind(true, []string{"enabled"})
+		testFind(0.5, []string{"ratio"})
+		testFind(false, []string{})
+
+		// values stored natively are matched even without Options.NativeTypes
+		InsertFixtures(instance, []EntryFixture{{Name: "i", Value: 5, ValueType: TypeInt}, {Name: "b", Value: 1, ValueType: TypeBool}})
+		testFind(5, []string{"i"})
+		testFind(true, []string{"b", "enabled"})
+
+		if _, err := instance.FindByValue(map[string]string{"disallowed": "type"}); err == nil {
+			t.Error("Instance.FindByValue: expected error with disallowed type")
+		}
+	})
+}
+
+// TestEntriesSortedByValue ensures that entries of a numeric type are ordered
+// numerically rather than lexically, whether or not stored natively.
+func TestEntriesSortedByValue(t *testing.T) {
+	for _, options := range []Options{{}, {NativeTypes: true}} {
+		RunWithOptions(options, func(instance *Instance) {
+			instance.SetValueCodec("secret", reverse, reverse)
+			for name, value := range map[string]interface{}{
+				"a": 9, "b": 10, "c": -5, "d": 100, "e": 10, "secret": 50, "float": 1000.5, "string": "7",
+			} {
+				instance.MustSet(name, value)
+			}
+
+			entries, err := instance.EntriesSortedByValue(TypeInt, true, 3)
+			if err != nil {
+				t.Fatal("Instance.EntriesSortedByValue: got error:\n", err)
+			}
+
+			if names := entryNames(entries); !reflect.DeepEqual(names, []string{"d", "b", "e"}) {
+				t.Errorf("Instance.EntriesSortedByValue: got '%v' expected '[d b e]'", names)
+			} else if entries[0].Value != 100 {
+				t.Errorf("Instance.EntriesSortedByValue: got '%v' expected '100'", entries[0].Value)
+			}
+
+			entries = instance.MustEntriesSortedByValue(TypeInt, false, 0)
+			if names := entryNames(entries); !reflect.DeepEqual(names, []string{"c", "a", "b", "e", "d"}) {
+				t.Errorf("Instance.MustEntriesSortedByValue: got '%v' expected '[c a b e d]'", names)
+			}
+
+			if _, err := instance.EntriesSortedByValue(TypeString, false, 0); err == nil {
+				t.Error("Instance.EntriesSortedByValue: expected error with non-numeric type")
+			}
+		})
+	}
+}
+
+// TestFilter ensures that Filter returns only the entries matching the given
+// predicate, in order of name.
+func TestFilter(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("short", "abc")
+		instance.MustSet("long", "a string longer than ten")
+		instance.MustSet("longer", "another string longer than ten")
+		instance.MustSet("number", 1234567890123)
+
+		entries, err := instance.Filter(func(name string, value interface{}) bool {
+			str, ok := value.(string)
+			return ok && len(str) > 10
+		})
+		if err != nil {
+			t.Fatal("Instance.Filter: got error:\n", err)
+		}
+
+		if names := entryNames(entries); !reflect.DeepEqual(names, []string{"long", "longer"}) {
+			t.Errorf("Instance.Filter: got '%v' expected '[long longer]'", names)
+		} else if entries[0].Type != TypeString || entries[0].Value != "a string longer than ten" {
+			t.Errorf("Instance.Filter: got '%v' (%s) for 'long'", entries[0].Value, entries[0].Type)
+		}
+
+		if entries := instance.MustFilter(func(string, interface{}) bool { return false }); len(entries) != 0 {
+			t.Errorf("Instance.MustFilter: got '%v' expected no entries", entries)
+		}
+	})
+}
+
+// TestEmptyValues ensures that entries holding the zero value of their type
+// are listed in order of name, and that all others are not.
+func TestEmptyValues(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		for name, value := range map[string]interface{}{
+			"bool":     false,
+			"int":      0,
+			"float":    0.0,
+			"string":   "",
+			"int64":    int64(0),
+			"slice":    []string{},
+			"complex":  complex(0, 0),
+			"duration": time.Duration(0),
+			"bigInt":   new(big.Int),
+			"bigFloat": new(big.Float),
+			"set.bool": true,
+			"set.int":  1,
+			"set.str":  " ",
+			"set.nan":  math.NaN(),
+			"set.big":  big.NewInt(-1),
+		} {
+			instance.MustSet(name, value)
+		}
+
+		expected := []string{"bigFloat", "bigInt", "bool", "complex", "duration", "float", "int", "int64", "slice", "string"}
+		entries := instance.MustEmptyValues()
+		if names := entryNames(entries); !reflect.DeepEqual(names, expected) {
+			t.Errorf("Instance.EmptyValues: got '%v' expected '%v'", names, expected)
+		} else if entries[1].Type != TypeBigInt {
+			t.Errorf("Instance.EmptyValues: got type '%s' expected '%s' for 'bigInt'", entries[1].Type, TypeBigInt)
+		}
+
+		if entries := instance.WithPrefix("set.").MustEmptyValues(); len(entries) != 0 {
+			t.Errorf("Instance.EmptyValues: got '%v' expected no entries", entries)
+		}
+	})
+}
@@ -0,0 +1,483 @@
+package metadb
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// getSet implements the code shared between GetSet and ForceGetSet, using an
+// additional parameter to differentiate between the two.
+func (instance *Instance) getSet(name string, value interface{}, force bool) (old interface{}, existed bool, err error) {
+	err = instance.withTx(func(tx *Instance) error {
+		old, err = tx.get(name)
+		if err != nil {
+			if _, ok := err.(*ErrNoEntry); !ok {
+				return err
+			}
+		} else {
+			existed = true
+		}
+
+		return tx.set(name, value, force)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return old, existed, nil
+}
+
+// GetSet does the same as Set, but also returns the value which the entry held
+// previously and whether it existed at all. Reading the previous value and
+// writing the new one are performed within a single transaction.
+func (instance *Instance) GetSet(name string, value interface{}) (old interface{}, existed bool, err error) {
+	old, existed, err = instance.getSet(name, value, false)
+	return old, existed, instance.wrap("GetSet", name, err)
+}
+
+// MustGetSet does the same as GetSet, but panics if an error is returned.
+func (instance *Instance) MustGetSet(name string, value interface{}) (old interface{}, existed bool) {
+	old, existed, err := instance.GetSet(name, value)
+	if err != nil {
+		panic(err)
+	}
+
+	return old, existed
+}
+
+// ForceGetSet does the same as GetSet, but does not return an error if the
+// entry already exists and the data type of the new value is different than
+// that of the current.
+func (instance *Instance) ForceGetSet(name string, value interface{}) (old interface{}, existed bool, err error) {
+	old, existed, err = instance.getSet(name, value, true)
+	return old, existed, instance.wrap("ForceGetSet", name, err)
+}
+
+// MustForceGetSet does the same as ForceGetSet, but panics if an error is
+// returned.
+func (instance *Instance) MustForceGetSet(name string, value interface{}) (old interface{}, existed bool) {
+	old, existed, err := instance.ForceGetSet(name, value)
+	if err != nil {
+		panic(err)
+	}
+
+	return old, existed
+}
+
+// Pop deletes an entry, returning the value which it held. Reading the value
+// and deleting the entry are performed within a single transaction, such that
+// the value is returned by at most one of any concurrent calls. If the entry
+// does not exist, an ErrNoEntry is returned.
+func (instance *Instance) Pop(name string) (value interface{}, err error) {
+	err = instance.withTx(func(tx *Instance) error {
+		if value, err = tx.get(name); err != nil {
+			return err
+		}
+
+		return tx.remove(name)
+	})
+	if err != nil {
+		return nil, instance.wrap("Pop", name, err)
+	}
+
+	return value, nil
+}
+
+// MustPop does the same as Pop, but panics if an error is returned.
+func (instance *Instance) MustPop(name string) interface{} {
+	if res, err := instance.Pop(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// SetAllIfNotExists creates every one of the given entries only if none of
+// them exist, within a single transaction, and returns whether they were
+// created. If any of the entries already exists, false is returned and no
+// entry is created, which suits claiming a group of related locks at once. If
+// any value is of a disallowed type or is otherwise rejected, an error is
+// returned and no entry is created.
+func (instance *Instance) SetAllIfNotExists(values map[string]interface{}) (bool, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	created := false
+	err := instance.withTx(func(tx *Instance) error {
+		for _, name := range names {
+			if exists, err := tx.exists(name); err != nil {
+				return fmt.Errorf("metadb: failed to check entry for '%s':\n%s", name, err)
+			} else if exists {
+				return errExists
+			}
+		}
+
+		for _, name := range names {
+			if err := tx.set(name, values[name], false); err != nil {
+				return err
+			}
+		}
+
+		created = true
+		return nil
+	})
+	if err != nil && err != errExists {
+		return false, err
+	}
+
+	return created, nil
+}
+
+// MustSetAllIfNotExists does the same as SetAllIfNotExists, but panics if an
+// error is returned.
+func (instance *Instance) MustSetAllIfNotExists(values map[string]interface{}) bool {
+	if res, err := instance.SetAllIfNotExists(values); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// errExists is returned within a transaction to roll it back once an entry is
+// found to exist, and is never returned to callers.
+var errExists = errors.New("metadb: entry exists")
+
+// direct returns whether the named entry may be modified by a statement
+// operating on its stored value directly, rather than through set. This is not
+// the case if the stored form of its value is opaque due to a codec, or if set
+// would validate the value, limit its size, verify its encoding, record its
+// history, or reject it as immutable.
+func (instance *Instance) direct(name string) bool {
+	if instance.opaque(name) {
+		return false
+	}
+
+	instance.mu.RLock()
+	_, validated := instance.validators[instance.key(name)]
+	_, enum := instance.enums[instance.key(name)]
+	immutable := instance.immutable[instance.key(name)]
+	instance.mu.RUnlock()
+
+	return !validated && !enum && !immutable && instance.options.MaxValueBytes == 0 &&
+		!instance.options.VerifyEncoding && !instance.options.History
+}
+
+// modify reads the named entry, which must hold data of the given type, and
+// writes back the value returned by fn when passed its current value,
+// returning the new value. It must be called within a transaction for the
+// modification to be atomic.
+func (instance *Instance) modify(name string, valueType ValueType, fn func(current interface{}) interface{}) (interface{}, error) {
+	current, err := instance.get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if got, _ := toValueType(current); got != valueType {
+		return nil, &ErrWrongType{name, valueType, got}
+	}
+
+	value := fn(current)
+	if err := instance.set(name, value, false); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// Toggle atomically inverts the bool within the requested entry, returning its
+// new value. If the entry does not exist, an ErrNoEntry is returned, and if it
+// holds data of another type, an ErrWrongType is returned.
+func (instance *Instance) Toggle(name string) (bool, error) {
+	var value bool
+	err := instance.withTx(func(tx *Instance) error {
+		if !tx.direct(name) {
+			res, err := tx.modify(name, TypeBool, func(current interface{}) interface{} {
+				return !current.(bool)
+			})
+			if err != nil {
+				return err
+			}
+
+			value = res.(bool)
+			return nil
+		}
+
+		// natively stored bools are matched in either form, as they may have
+		// been written with or without Options.NativeTypes set
+		assignments := "{value} = CASE WHEN {value} IN ('true', '1', 1) THEN ? ELSE ? END"
+		args := []interface{}{tx.storedValue(name, false, "false"), tx.storedValue(name, true, "true")}
+		if tx.options.Timestamps {
+			assignments += ", {updated} = ?"
+			args = append(args, time.Now().UnixNano())
+		}
+
+		if _, err := tx.exec(`UPDATE {table} SET `+assignments+` WHERE {name} = ? AND {type} = ?;`,
+			append(args, tx.key(name), TypeBool)...); err != nil {
+			return fmt.Errorf("metadb: failed to toggle entry for '%s':\n%s", name, err)
+		}
+
+		// reading the entry back reports whether it was missing or of another type
+		current, err := tx.get(name)
+		if err != nil {
+			return err
+		}
+
+		if got, _ := toValueType(current); got != TypeBool {
+			return &ErrWrongType{name, TypeBool, got}
+		}
+
+		value = current.(bool)
+		tx.changed(name)
+		return nil
+	})
+	if err != nil {
+		return false, instance.wrap("Toggle", name, err)
+	}
+
+	return value, nil
+}
+
+// MustToggle does the same as Toggle, but panics if an error is returned.
+func (instance *Instance) MustToggle(name string) bool {
+	if res, err := instance.Toggle(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// CompareAndDelete deletes the requested entry only if it holds the expected
+// value, returning whether it was deleted. This suits lease and lock patterns,
+// where an entry must not be deleted once another process has changed it. If
+// the entry does not exist, false is returned. If it holds data of a different
+// type than the expected value, an ErrWrongType is returned and nothing is
+// deleted.
+func (instance *Instance) CompareAndDelete(name string, expected interface{}) (bool, error) {
+	deleted, err := instance.compareAndDelete(name, expected)
+	return deleted, instance.wrap("CompareAndDelete", name, err)
+}
+
+// compareAndDelete implements CompareAndDelete.
+func (instance *Instance) compareAndDelete(name string, expected interface{}) (bool, error) {
+	expectedType, err := toValueType(expected)
+	if err != nil {
+		return false, err
+	}
+
+	blob, err := toBlobString(expected)
+	if err != nil {
+		return false, err
+	}
+
+	deleted := false
+	err = instance.withTx(func(tx *Instance) error {
+		valueType, err := tx.getValueType(name)
+		if err != nil {
+			if _, ok := err.(*ErrNoEntry); ok {
+				return nil
+			}
+
+			return err
+		} else if valueType != expectedType {
+			return &ErrWrongType{name, expectedType, valueType}
+		} else if tx.isImmutable(name) {
+			return &ErrImmutable{name}
+		}
+
+		// the stored form of entries with a codec is opaque, so they are
+		// instead compared once read
+		if tx.opaque(name) {
+			current, err := tx.get(name)
+			if err != nil {
+				return err
+			} else if !equal(current, expected) {
+				return nil
+			}
+
+			deleted = true
+			return tx.remove(name)
+		}
+
+		// natively stored values are matched in either form, as in FindByValue
+		condition, args := "{value} = ?", []interface{}{tx.key(name), expectedType, blob}
+		if native, ok := toNative(expected); ok {
+			condition = "({value} = ? OR {value} = ?)"
+			args = append(args, native)
+		}
+
+		res, err := tx.exec(tx.deletion("{name} = ? AND {type} = ? AND "+condition), args...)
+		if err != nil {
+			return fmt.Errorf("metadb: failed to delete entry for '%s':\n%s", name, err)
+		}
+
+		// if RowsAffected is unsupported, check whether the entry still exists
+		if affected, err := res.RowsAffected(); err == nil {
+			deleted = affected > 0
+		} else if exists, err := tx.exists(name); err != nil {
+			return fmt.Errorf("metadb: failed to delete entry for '%s':\n%s", name, err)
+		} else {
+			deleted = !exists
+		}
+
+		if deleted {
+			tx.changed(name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
+
+// MustCompareAndDelete does the same as CompareAndDelete, but panics if an
+// error is returned.
+func (instance *Instance) MustCompareAndDelete(name string, expected interface{}) bool {
+	if res, err := instance.CompareAndDelete(name, expected); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// Append atomically appends suffix to the string within the requested entry,
+// returning its new value. The entry is read and written back within a single
+// transaction rather than concatenated by the database, as the concatenation
+// operator differs between databases (e.g. || is a logical OR with MySQL). If
+// the entry does not exist, an ErrNoEntry is returned, and if it holds data of
+// another type, an ErrWrongType is returned.
+func (instance *Instance) Append(name string, suffix string) (string, error) {
+	var value string
+	err := instance.withTx(func(tx *Instance) error {
+		res, err := tx.modify(name, TypeString, func(current interface{}) interface{} {
+			return current.(string) + suffix
+		})
+		if err != nil {
+			return err
+		}
+
+		value = res.(string)
+		return nil
+	})
+	if err != nil {
+		return "", instance.wrap("Append", name, err)
+	}
+
+	return value, nil
+}
+
+// MustAppend does the same as Append, but panics if an error is returned.
+func (instance *Instance) MustAppend(name string, suffix string) string {
+	if res, err := instance.Append(name, suffix); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// SetMax atomically sets the int within the requested entry to the greater of
+// its current value and value, returning the resulting value, such as for
+// tracking a watermark. If the entry does not exist, it is created with value,
+// and if it holds data of another type, an ErrWrongType is returned.
+func (instance *Instance) SetMax(name string, value int) (int, error) {
+	res, err := instance.setBound(name, value, "<")
+	return res, instance.wrap("SetMax", name, err)
+}
+
+// MustSetMax does the same as SetMax, but panics if an error is returned.
+func (instance *Instance) MustSetMax(name string, value int) int {
+	if res, err := instance.SetMax(name, value); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// SetMin does the same as SetMax, but keeps the lesser of the two values.
+func (instance *Instance) SetMin(name string, value int) (int, error) {
+	res, err := instance.setBound(name, value, ">")
+	return res, instance.wrap("SetMin", name, err)
+}
+
+// MustSetMin does the same as SetMin, but panics if an error is returned.
+func (instance *Instance) MustSetMin(name string, value int) int {
+	if res, err := instance.SetMin(name, value); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// setBound implements SetMax and SetMin, replacing the current value of the
+// named entry with value if the comparison current <op> value holds.
+func (instance *Instance) setBound(name string, value int, op string) (int, error) {
+	replaces := func(current int) bool {
+		if op == "<" {
+			return current < value
+		}
+
+		return current > value
+	}
+
+	var res int
+	err := instance.withTx(func(tx *Instance) error {
+		direct, affected := tx.direct(name), int64(0)
+		if direct {
+			// adding zero converts values stored as blob strings to numbers
+			assignments, args := "{value} = ?", []interface{}{tx.storedValue(name, value, strconv.Itoa(value))}
+			if tx.options.Timestamps {
+				assignments += ", {updated} = ?"
+				args = append(args, time.Now().UnixNano())
+			}
+
+			result, err := tx.exec(`UPDATE {table} SET `+assignments+` WHERE {name} = ? AND {type} = ? AND {visible}
+				AND {value} + 0 `+op+` ?;`, append(args, tx.key(name), TypeInt, value)...)
+			if err != nil {
+				return fmt.Errorf("metadb: failed to update entry for '%s':\n%s", name, err)
+			}
+
+			// if RowsAffected is unsupported, the entry is assumed to have changed
+			if affected, err = result.RowsAffected(); err != nil {
+				affected = 1
+			}
+		}
+
+		// reading the entry back reports whether it was missing or of another type
+		current, err := tx.get(name)
+		if err != nil {
+			if _, ok := err.(*ErrNoEntry); !ok {
+				return err
+			}
+
+			res = value
+			return tx.set(name, value, false)
+		}
+
+		if got, _ := toValueType(current); got != TypeInt {
+			return &ErrWrongType{name, TypeInt, got}
+		}
+
+		res = current.(int)
+		if !direct && replaces(res) {
+			res = value
+			return tx.set(name, value, false)
+		} else if affected > 0 {
+			tx.changed(name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,307 @@
+package metadb
+
+import "testing"
+
+// TestGetSet ensures that GetSet and ForceGetSet return the previous value of
+// an entry and that a failed write leaves the entry untouched.
+func TestGetSet(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		if old, existed, err := instance.GetSet("foo", 1); err != nil {
+			t.Fatal("Instance.GetSet: got error:\n", err)
+		} else if existed || old != nil {
+			t.Errorf("Instance.GetSet: got '%v', '%t' expected '<nil>', 'false'", old, existed)
+		}
+
+		if old, existed, err := instance.GetSet("foo", 2); err != nil {
+			t.Error("Instance.GetSet: got error:\n", err)
+		} else if !existed || old != 1 {
+			t.Errorf("Instance.GetSet: got '%v', '%t' expected '1', 'true'", old, existed)
+		}
+
+		if _, _, err := instance.GetSet("foo", "bar"); err == nil {
+			t.Error("Instance.GetSet: expected error with new value of different type than existing")
+		}
+
+		if value := instance.MustGet("foo"); value != 2 {
+			t.Errorf("Instance.GetSet: got '%v' expected '2' after failed write", value)
+		}
+
+		if old, existed := instance.MustGetSet("foo", 3); !existed || old != 2 {
+			t.Errorf("Instance.MustGetSet: got '%v', '%t' expected '2', 'true'", old, existed)
+		}
+
+		if err := panicked(func() { instance.MustGetSet("foo", "bar") }); err == nil {
+			t.Error("Instance.MustGetSet: expected panic with new value of different type than existing")
+		}
+
+		if old, existed, err := instance.ForceGetSet("foo", "bar"); err != nil {
+			t.Error("Instance.ForceGetSet: got error:\n", err)
+		} else if !existed || old != 3 {
+			t.Errorf("Instance.ForceGetSet: got '%v', '%t' expected '3', 'true'", old, existed)
+		}
+
+		if old, existed := instance.MustForceGetSet("foo", true); !existed || old != "bar" {
+			t.Errorf("Instance.MustForceGetSet: got '%v', '%t' expected 'bar', 'true'", old, existed)
+		}
+
+		if err := panicked(func() { instance.MustForceGetSet("foo", map[string]string{"disallowed": "type"}) }); err == nil {
+			t.Error("Instance.MustForceGetSet: expected panic with new value of disallowed type")
+		}
+	})
+}
+
+// TestPop ensures that Pop returns the value of an entry while deleting it, and
+// an ErrNoEntry once it no longer exists.
+func TestPop(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("job", []string{"resize", "image.png"})
+
+		if value, err := instance.Pop("job"); err != nil {
+			t.Fatal("Instance.Pop: got error:\n", err)
+		} else if !equal(value, []string{"resize", "image.png"}) {
+			t.Errorf("Instance.Pop: got '%v' expected '[resize image.png]'", value)
+		}
+
+		if instance.Exists("job") {
+			t.Error("Instance.Pop: expected entry to be deleted")
+		}
+
+		if _, err := instance.Pop("job"); err == nil {
+			t.Error("Instance.Pop: expected error with missing entry")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Errorf("Instance.Pop: got '%v' expected ErrNoEntry", err)
+		}
+
+		instance.MustSet("count", 3)
+		if value := instance.MustPop("count"); value != 3 {
+			t.Errorf("Instance.MustPop: got '%v' expected '3'", value)
+		}
+	})
+}
+
+// TestSetAllIfNotExists ensures that SetAllIfNotExists only creates entries if
+// none of them exist, and creates none if any value is rejected.
+func TestSetAllIfNotExists(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		locks := map[string]interface{}{"lock.a": "owner", "lock.b": "owner"}
+		if created, err := instance.SetAllIfNotExists(locks); err != nil {
+			t.Fatal("Instance.SetAllIfNotExists: got error:\n", err)
+		} else if !created {
+			t.Error("Instance.SetAllIfNotExists: got 'false' expected 'true'")
+		}
+
+		others := map[string]interface{}{"lock.b": "other", "lock.c": "other"}
+		if created := instance.MustSetAllIfNotExists(others); created {
+			t.Error("Instance.MustSetAllIfNotExists: got 'true' expected 'false' with existing entry")
+		}
+
+		if instance.Exists("lock.c") || instance.MustGetString("lock.b") != "owner" {
+			t.Error("Instance.SetAllIfNotExists: expected no entries to be modified")
+		}
+
+		invalid := map[string]interface{}{"lock.d": "owner", "lock.e": struct{}{}}
+		if _, err := instance.SetAllIfNotExists(invalid); err == nil {
+			t.Error("Instance.SetAllIfNotExists: expected error with disallowed type")
+		}
+
+		if instance.Exists("lock.d") {
+			t.Error("Instance.SetAllIfNotExists: expected no entries to be created after error")
+		}
+	})
+}
+
+// TestToggle ensures that Toggle inverts bool entries, including those with a
+// codec, and rejects missing entries and entries of other types.
+func TestToggle(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("flag", false)
+		if res, err := instance.Toggle("flag"); err != nil {
+			t.Error("Instance.Toggle: got error:\n", err)
+		} else if !res || instance.MustGet("flag") != true {
+			t.Errorf("Instance.Toggle: got '%t' expected 'true'", res)
+		}
+
+		if res := instance.MustToggle("flag"); res || instance.MustGet("flag") != false {
+			t.Errorf("Instance.MustToggle: got '%t' expected 'false'", res)
+		}
+
+		instance.SetValueCodec("encoded", reverse, reverse)
+		instance.MustSet("encoded", true)
+		if res := instance.MustToggle("encoded"); res || instance.MustGet("encoded") != false {
+			t.Errorf("Instance.MustToggle: got '%t' expected 'false' with codec", res)
+		}
+
+		// bools stored natively are toggled even without Options.NativeTypes
+		InsertFixtures(instance, []EntryFixture{{Name: "native", Value: 1, ValueType: TypeBool}})
+		if res := instance.MustToggle("native"); res || instance.MustGet("native") != false {
+			t.Errorf("Instance.MustToggle: got '%t' expected 'false' with natively stored bool", res)
+		}
+
+		if _, err := instance.Toggle("missing"); err == nil {
+			t.Error("Instance.Toggle: expected error with non-existent entry")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Error("Instance.Toggle: expected error of type *ErrNoEntry")
+		}
+
+		instance.MustSet("string", "true")
+		if _, err := instance.Toggle("string"); err == nil {
+			t.Error("Instance.Toggle: expected error with entry of type string")
+		} else if _, ok := err.(*ErrWrongType); !ok {
+			t.Error("Instance.Toggle: expected error of type *ErrWrongType")
+		}
+
+		if res := instance.MustGet("string"); res != "true" {
+			t.Errorf("Instance.Toggle: got '%v' expected entry of type string to be unchanged", res)
+		}
+
+		if err := panicked(func() { instance.MustToggle("missing") }); err == nil {
+			t.Error("Instance.MustToggle: expected panic with non-existent entry")
+		}
+	})
+}
+
+// TestCompareAndDelete ensures that entries are only deleted if they hold the
+// expected value, and that values of another type are rejected.
+func TestCompareAndDelete(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("lease", "owner-a")
+
+		if deleted, err := instance.CompareAndDelete("lease", "owner-b"); err != nil {
+			t.Error("Instance.CompareAndDelete: got error:\n", err)
+		} else if deleted || !instance.Exists("lease") {
+			t.Error("Instance.CompareAndDelete: expected entry holding another value not to be deleted")
+		}
+
+		if _, err := instance.CompareAndDelete("lease", 1); err == nil {
+			t.Error("Instance.CompareAndDelete: expected error with value of another type")
+		} else if _, ok := err.(*ErrWrongType); !ok {
+			t.Error("Instance.CompareAndDelete: expected error of type *ErrWrongType")
+		}
+
+		if deleted := instance.MustCompareAndDelete("lease", "owner-a"); !deleted || instance.Exists("lease") {
+			t.Error("Instance.MustCompareAndDelete: expected entry holding expected value to be deleted")
+		}
+
+		if deleted := instance.MustCompareAndDelete("lease", "owner-a"); deleted {
+			t.Error("Instance.MustCompareAndDelete: got 'true' expected 'false' for non-existent entry")
+		}
+
+		instance.SetValueCodec("encoded", reverse, reverse)
+		instance.MustSet("encoded", "abc")
+		if deleted := instance.MustCompareAndDelete("encoded", "abc"); !deleted || instance.Exists("encoded") {
+			t.Error("Instance.MustCompareAndDelete: expected entry with codec to be deleted")
+		}
+
+		// values stored natively are matched even without Options.NativeTypes
+		InsertFixtures(instance, []EntryFixture{{Name: "native", Value: 5, ValueType: TypeInt}, {Name: "bool", Value: 1, ValueType: TypeBool}})
+		if deleted := instance.MustCompareAndDelete("native", 5); !deleted || instance.Exists("native") {
+			t.Error("Instance.MustCompareAndDelete: expected natively stored int to be deleted")
+		}
+
+		if deleted := instance.MustCompareAndDelete("bool", true); !deleted || instance.Exists("bool") {
+			t.Error("Instance.MustCompareAndDelete: expected natively stored bool to be deleted")
+		}
+
+		instance.MustSet("lease", "owner-a")
+		instance.db = noRowsAffected{instance.db}
+		if deleted := instance.MustCompareAndDelete("lease", "owner-a"); !deleted {
+			t.Error("Instance.MustCompareAndDelete: got 'false' expected 'true' without RowsAffected")
+		}
+
+		if err := panicked(func() { instance.MustCompareAndDelete("lease", map[string]string{"disallowed": "type"}) }); err == nil {
+			t.Error("Instance.MustCompareAndDelete: expected panic with value of disallowed type")
+		}
+	})
+}
+
+// TestAppend ensures that Append extends string entries, including those with
+// a codec, and rejects missing entries and entries of other types.
+func TestAppend(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("log", "a")
+		if res, err := instance.Append("log", "b"); err != nil {
+			t.Error("Instance.Append: got error:\n", err)
+		} else if res != "ab" || instance.MustGet("log") != "ab" {
+			t.Errorf("Instance.Append: got '%s' expected 'ab'", res)
+		}
+
+		instance.SetValueCodec("encoded", reverse, reverse)
+		instance.MustSet("encoded", "a")
+		if res := instance.MustAppend("encoded", "bc"); res != "abc" || instance.MustGet("encoded") != "abc" {
+			t.Errorf("Instance.MustAppend: got '%s' expected 'abc' with codec", res)
+		}
+
+		if _, err := instance.Append("missing", "a"); err == nil {
+			t.Error("Instance.Append: expected error with non-existent entry")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Error("Instance.Append: expected error of type *ErrNoEntry")
+		}
+
+		instance.MustSet("int", 1)
+		if _, err := instance.Append("int", "0"); err == nil {
+			t.Error("Instance.Append: expected error with entry of type int")
+		} else if _, ok := err.(*ErrWrongType); !ok {
+			t.Error("Instance.Append: expected error of type *ErrWrongType")
+		}
+
+		if res := instance.MustGet("int"); res != 1 {
+			t.Errorf("Instance.Append: got '%v' expected entry of type int to be unchanged", res)
+		}
+
+		if err := panicked(func() { instance.MustAppend("missing", "a") }); err == nil {
+			t.Error("Instance.MustAppend: expected panic with non-existent entry")
+		}
+	})
+
+	RunWithOptions(Options{MaxValueBytes: 2}, func(instance *Instance) {
+		instance.MustSet("log", "a")
+		instance.MustAppend("log", "b")
+		if _, err := instance.Append("log", "c"); err == nil {
+			t.Error("Instance.Append: expected error with value exceeding MaxValueBytes")
+		}
+	})
+}
+
+// TestSetMax ensures that SetMax and SetMin keep the greater and lesser value
+// respectively, create missing entries, and reject entries of other types,
+// whether or not the entry is modified directly by the database.
+func TestSetMax(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.SetValueCodec("encoded", reverse, reverse)
+
+		for _, name := range []string{"plain", "encoded"} {
+			if res, err := instance.SetMax(name, 5); err != nil {
+				t.Errorf("Instance.SetMax: got error creating '%s':\n%s", name, err)
+			} else if res != 5 {
+				t.Errorf("Instance.SetMax: got '%d' expected '5' for '%s'", res, name)
+			}
+
+			for _, candidate := range []int{3, 12, 9} {
+				instance.MustSetMax(name, candidate)
+			}
+
+			if res := instance.MustGetInt(name); res != 12 {
+				t.Errorf("Instance.SetMax: got '%d' expected '12' for '%s'", res, name)
+			}
+
+			if res := instance.MustSetMin(name, 15); res != 12 {
+				t.Errorf("Instance.SetMin: got '%d' expected '12' for '%s'", res, name)
+			}
+
+			if res := instance.MustSetMin(name, -4); res != -4 {
+				t.Errorf("Instance.SetMin: got '%d' expected '-4' for '%s'", res, name)
+			}
+		}
+
+		instance.MustSet("string", "10")
+		if _, err := instance.SetMax("string", 20); err == nil {
+			t.Error("Instance.SetMax: expected error with string entry")
+		} else if _, ok := err.(*ErrWrongType); !ok {
+			t.Errorf("Instance.SetMax: got '%v' expected ErrWrongType", err)
+		}
+
+		if res := instance.MustGet("string"); res != "10" {
+			t.Errorf("Instance.SetMax: got '%v' expected string entry to be unchanged", res)
+		}
+	})
+}
@@ -0,0 +1,486 @@
+package metadb
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrWrongType is returned when an entry holds data of a different type than
+// that which was requested.
+type ErrWrongType struct {
+	Name     string
+	Expected ValueType
+	Got      ValueType
+}
+
+// Error implements the error interface for ErrWrongType.
+func (err *ErrWrongType) Error() string {
+	return fmt.Sprintf("metadb: entry for '%s' is of type %s, expected %s", err.Name, err.Got, err.Expected)
+}
+
+// getTyped returns the data within the requested entry if it is of the given
+// ValueType, and an ErrWrongType otherwise, which is wrapped like the errors
+// returned by Get if Options.WrapErrors is set. No conversion is performed.
+func (instance *Instance) getTyped(name string, valueType ValueType) (interface{}, error) {
+	value, err := instance.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if got, _ := toValueType(value); got != valueType {
+		return nil, instance.wrap("Get", name, &ErrWrongType{name, valueType, got})
+	}
+
+	return value, nil
+}
+
+// GetBool returns the bool within the requested entry. If the entry does not
+// exist or holds data of another type, an error is returned.
+func (instance *Instance) GetBool(name string) (bool, error) {
+	value, err := instance.getTyped(name, TypeBool)
+	if err != nil {
+		return false, err
+	}
+
+	return value.(bool), nil
+}
+
+// MustGetBool does the same as GetBool, but panics if an error is returned.
+func (instance *Instance) MustGetBool(name string) bool {
+	if res, err := instance.GetBool(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetInt returns the int within the requested entry. If the entry does not
+// exist or holds data of another type, including int64, an error is returned.
+func (instance *Instance) GetInt(name string) (int, error) {
+	value, err := instance.getTyped(name, TypeInt)
+	if err != nil {
+		return 0, err
+	}
+
+	return value.(int), nil
+}
+
+// MustGetInt does the same as GetInt, but panics if an error is returned.
+func (instance *Instance) MustGetInt(name string) int {
+	if res, err := instance.GetInt(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetInt64 returns the int64 within the requested entry. If the entry does not
+// exist or holds data of another type, including int, an error is returned.
+func (instance *Instance) GetInt64(name string) (int64, error) {
+	value, err := instance.getTyped(name, TypeInt64)
+	if err != nil {
+		return 0, err
+	}
+
+	return value.(int64), nil
+}
+
+// MustGetInt64 does the same as GetInt64, but panics if an error is returned.
+func (instance *Instance) MustGetInt64(name string) int64 {
+	if res, err := instance.GetInt64(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetFloat returns the float64 within the requested entry. Entries holding an
+// int or int64 are promoted to float64, as with JSON numbers, though int64
+// values beyond 2^53 may lose precision. If the entry does not exist or holds
+// data of any other type, an error is returned.
+func (instance *Instance) GetFloat(name string) (float64, error) {
+	value, err := instance.Get(name)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := toFloat(name, value)
+	return res, instance.wrap("Get", name, err)
+}
+
+// toFloat returns the value of the named entry as a float64 for GetFloat,
+// promoting ints and int64s. For values of any other type, an ErrWrongType is
+// returned.
+func toFloat(name string, value interface{}) (float64, error) {
+	switch value := value.(type) {
+	case float64:
+		return value, nil
+	case int:
+		return float64(value), nil
+	case int64:
+		return float64(value), nil
+	default:
+		got, _ := toValueType(value)
+		return 0, &ErrWrongType{name, TypeFloat, got}
+	}
+}
+
+// MustGetFloat does the same as GetFloat, but panics if an error is returned.
+func (instance *Instance) MustGetFloat(name string) float64 {
+	if res, err := instance.GetFloat(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetString returns the string within the requested entry. If the entry does
+// not exist or holds data of another type, an error is returned.
+func (instance *Instance) GetString(name string) (string, error) {
+	value, err := instance.getTyped(name, TypeString)
+	if err != nil {
+		return "", err
+	}
+
+	return value.(string), nil
+}
+
+// MustGetString does the same as GetString, but panics if an error is
+// returned.
+func (instance *Instance) MustGetString(name string) string {
+	if res, err := instance.GetString(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetStringSlice returns the []string within the requested entry. If the entry
+// does not exist or holds data of another type, an error is returned.
+func (instance *Instance) GetStringSlice(name string) ([]string, error) {
+	value, err := instance.getTyped(name, TypeStringSlice)
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]string), nil
+}
+
+// MustGetStringSlice does the same as GetStringSlice, but panics if an error is
+// returned.
+func (instance *Instance) MustGetStringSlice(name string) []string {
+	if res, err := instance.GetStringSlice(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetComplex returns the complex128 within the requested entry. If the entry
+// does not exist or holds data of another type, an error is returned.
+func (instance *Instance) GetComplex(name string) (complex128, error) {
+	value, err := instance.getTyped(name, TypeComplex)
+	if err != nil {
+		return 0, err
+	}
+
+	return value.(complex128), nil
+}
+
+// MustGetComplex does the same as GetComplex, but panics if an error is
+// returned.
+func (instance *Instance) MustGetComplex(name string) complex128 {
+	if res, err := instance.GetComplex(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetDuration returns the time.Duration within the requested entry. If the
+// entry does not exist or holds data of another type, including an int64 or a
+// string, an error is returned.
+func (instance *Instance) GetDuration(name string) (time.Duration, error) {
+	value, err := instance.getTyped(name, TypeDuration)
+	if err != nil {
+		return 0, err
+	}
+
+	return value.(time.Duration), nil
+}
+
+// MustGetDuration does the same as GetDuration, but panics if an error is
+// returned.
+func (instance *Instance) MustGetDuration(name string) time.Duration {
+	if res, err := instance.GetDuration(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetBigInt returns the *big.Int within the requested entry. If the entry does
+// not exist or holds data of another type, including an int or int64, an error
+// is returned.
+func (instance *Instance) GetBigInt(name string) (*big.Int, error) {
+	value, err := instance.getTyped(name, TypeBigInt)
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*big.Int), nil
+}
+
+// MustGetBigInt does the same as GetBigInt, but panics if an error is
+// returned.
+func (instance *Instance) MustGetBigInt(name string) *big.Int {
+	if res, err := instance.GetBigInt(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetBigFloat returns the *big.Float within the requested entry, with the same
+// precision as the value which was stored. If the entry does not exist or
+// holds data of another type, including a float64, an error is returned.
+func (instance *Instance) GetBigFloat(name string) (*big.Float, error) {
+	value, err := instance.getTyped(name, TypeBigFloat)
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*big.Float), nil
+}
+
+// MustGetBigFloat does the same as GetBigFloat, but panics if an error is
+// returned.
+func (instance *Instance) MustGetBigFloat(name string) *big.Float {
+	if res, err := instance.GetBigFloat(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetBoolOr returns the bool within the requested entry, or def if the entry
+// does not exist, holds data of another type, or cannot be read. As no error
+// is ever returned, it suits settings for which a fallback is always
+// acceptable.
+func (instance *Instance) GetBoolOr(name string, def bool) bool {
+	if res, err := instance.GetBool(name); err == nil {
+		return res
+	}
+
+	return def
+}
+
+// GetIntOr does the same as GetBoolOr, but for an int.
+func (instance *Instance) GetIntOr(name string, def int) int {
+	if res, err := instance.GetInt(name); err == nil {
+		return res
+	}
+
+	return def
+}
+
+// GetFloatOr does the same as GetBoolOr, but for a float64.
+func (instance *Instance) GetFloatOr(name string, def float64) float64 {
+	if res, err := instance.GetFloat(name); err == nil {
+		return res
+	}
+
+	return def
+}
+
+// GetStringOr does the same as GetBoolOr, but for a string.
+func (instance *Instance) GetStringOr(name string, def string) string {
+	if res, err := instance.GetString(name); err == nil {
+		return res
+	}
+
+	return def
+}
+
+// SetStringSlice does the same as Set, but only accepts a []string, which is
+// stored as a JSON array.
+func (instance *Instance) SetStringSlice(name string, value []string) error {
+	return instance.Set(name, value)
+}
+
+// MustSetStringSlice does the same as SetStringSlice, but panics if an error is
+// returned.
+func (instance *Instance) MustSetStringSlice(name string, value []string) {
+	if err := instance.SetStringSlice(name, value); err != nil {
+		panic(err)
+	}
+}
+
+// SetStringer does the same as Set, but stores the value returned by the
+// String method of a fmt.Stringer, such as a net.IP, as a string. The value
+// may be read back with GetString and parsed as appropriate, or converted
+// transparently by a codec registered with SetValueCodec. If the Stringer is
+// nil, an error is returned. A time.Duration should instead be stored with Set
+// and read back with GetDuration, as GetDuration does not parse its string
+// form.
+func (instance *Instance) SetStringer(name string, value fmt.Stringer) error {
+	if value == nil {
+		return instance.wrap("SetStringer", name, fmt.Errorf("metadb: got nil Stringer for '%s'", name))
+	}
+
+	return instance.Set(name, value.String())
+}
+
+// MustSetStringer does the same as SetStringer, but panics if an error is
+// returned.
+func (instance *Instance) MustSetStringer(name string, value fmt.Stringer) {
+	if err := instance.SetStringer(name, value); err != nil {
+		panic(err)
+	}
+}
+
+// valuesOfType returns the decoded values of every entry within the scope of
+// the Instance holding data of any of the given types, keyed by name.
+func (instance *Instance) valuesOfType(types ...ValueType) (map[string]interface{}, error) {
+	scope, args := instance.scope()
+	typeArgs := make([]interface{}, len(types))
+	for i, valueType := range types {
+		typeArgs[i] = valueType
+	}
+
+	rows, err := instance.query("SELECT {name}, {value}, {type} FROM {table} WHERE {type} IN (?"+
+		strings.Repeat(", ?", len(types)-1)+") AND "+scope+";", append(typeArgs, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+	defer rows.Close()
+
+	values := make(map[string]interface{})
+	for rows.Next() {
+		var name, blob string
+		var valueType ValueType
+		if err := rows.Scan(&name, &blob, &valueType); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+		}
+
+		name = instance.unkey(name)
+		if values[name], err = instance.decode(name, blob, valueType); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+
+	return values, nil
+}
+
+// GetAllBools returns the value of every entry holding a bool, keyed by name.
+// Entries holding data of any other type are excluded.
+func (instance *Instance) GetAllBools() (map[string]bool, error) {
+	values, err := instance.valuesOfType(TypeBool)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]bool, len(values))
+	for name, value := range values {
+		res[name] = value.(bool)
+	}
+
+	return res, nil
+}
+
+// MustGetAllBools does the same as GetAllBools, but panics if an error is
+// returned.
+func (instance *Instance) MustGetAllBools() map[string]bool {
+	if res, err := instance.GetAllBools(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetAllInts returns the value of every entry holding an int, keyed by name.
+// Entries holding data of any other type, including int64, are excluded.
+func (instance *Instance) GetAllInts() (map[string]int, error) {
+	values, err := instance.valuesOfType(TypeInt)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]int, len(values))
+	for name, value := range values {
+		res[name] = value.(int)
+	}
+
+	return res, nil
+}
+
+// MustGetAllInts does the same as GetAllInts, but panics if an error is
+// returned.
+func (instance *Instance) MustGetAllInts() map[string]int {
+	if res, err := instance.GetAllInts(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetAllFloats returns the value of every entry holding a float64, keyed by
+// name. As with GetFloat, entries holding an int or int64 are promoted to
+// float64. Entries holding data of any other type are excluded.
+func (instance *Instance) GetAllFloats() (map[string]float64, error) {
+	values, err := instance.valuesOfType(TypeFloat, TypeInt, TypeInt64)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]float64, len(values))
+	for name, value := range values {
+		if res[name], err = toFloat(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// MustGetAllFloats does the same as GetAllFloats, but panics if an error is
+// returned.
+func (instance *Instance) MustGetAllFloats() map[string]float64 {
+	if res, err := instance.GetAllFloats(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetAllStrings returns the value of every entry holding a string, keyed by
+// name. Entries holding data of any other type are excluded.
+func (instance *Instance) GetAllStrings() (map[string]string, error) {
+	values, err := instance.valuesOfType(TypeString)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]string, len(values))
+	for name, value := range values {
+		res[name] = value.(string)
+	}
+
+	return res, nil
+}
+
+// MustGetAllStrings does the same as GetAllStrings, but panics if an error is
+// returned.
+func (instance *Instance) MustGetAllStrings() map[string]string {
+	if res, err := instance.GetAllStrings(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
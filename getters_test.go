@@ -0,0 +1,308 @@
+package metadb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestTypedGetters ensures that each typed getter returns values of its own
+// type and an ErrWrongType for entries of any other type, including int and
+// int64 which must not be used interchangeably. GetFloat alone also accepts
+// ints and int64s.
+func TestTypedGetters(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("bool", true)
+		instance.MustSet("int", 42)
+		instance.MustSet("int64", int64(1)<<40)
+		instance.MustSet("float", 2.5)
+		instance.MustSet("string", "hello")
+		instance.MustSet("complex", complex(1, -1))
+		instance.MustSet("duration", 30*time.Second)
+
+		if res, err := instance.GetBool("bool"); err != nil || res != true {
+			t.Errorf("Instance.GetBool: got '%v', '%v' expected 'true', '<nil>'", res, err)
+		}
+
+		if res, err := instance.GetInt("int"); err != nil || res != 42 {
+			t.Errorf("Instance.GetInt: got '%v', '%v' expected '42', '<nil>'", res, err)
+		}
+
+		if res, err := instance.GetInt64("int64"); err != nil || res != int64(1)<<40 {
+			t.Errorf("Instance.GetInt64: got '%v', '%v' expected '%d', '<nil>'", res, err, int64(1)<<40)
+		}
+
+		if res, err := instance.GetFloat("float"); err != nil || res != 2.5 {
+			t.Errorf("Instance.GetFloat: got '%v', '%v' expected '2.5', '<nil>'", res, err)
+		}
+
+		if res, err := instance.GetFloat("int"); err != nil || res != 42 {
+			t.Errorf("Instance.GetFloat: got '%v', '%v' expected '42', '<nil>'", res, err)
+		}
+
+		if res, err := instance.GetFloat("int64"); err != nil || res != float64(int64(1)<<40) {
+			t.Errorf("Instance.GetFloat: got '%v', '%v' expected '%v', '<nil>'", res, err, float64(int64(1)<<40))
+		}
+
+		if res, err := instance.GetString("string"); err != nil || res != "hello" {
+			t.Errorf("Instance.GetString: got '%v', '%v' expected 'hello', '<nil>'", res, err)
+		}
+
+		if res, err := instance.GetComplex("complex"); err != nil || res != complex(1, -1) {
+			t.Errorf("Instance.GetComplex: got '%v', '%v' expected '(1-1i)', '<nil>'", res, err)
+		}
+
+		if res, err := instance.GetDuration("duration"); err != nil || res != 30*time.Second {
+			t.Errorf("Instance.GetDuration: got '%v', '%v' expected '30s', '<nil>'", res, err)
+		}
+
+		expectWrongType := func(method string, fn func() error) {
+			if err := fn(); err == nil {
+				t.Errorf("Instance.%s: expected error with entry of another type", method)
+			} else if _, ok := err.(*ErrWrongType); !ok {
+				t.Errorf("Instance.%s: expected error of type *ErrWrongType", method)
+			}
+		}
+
+		expectWrongType("GetBool", func() error { _, err := instance.GetBool("int"); return err })
+		expectWrongType("GetInt", func() error { _, err := instance.GetInt("int64"); return err })
+		expectWrongType("GetInt64", func() error { _, err := instance.GetInt64("int"); return err })
+		expectWrongType("GetFloat", func() error { _, err := instance.GetFloat("string"); return err })
+		expectWrongType("GetFloat", func() error { _, err := instance.GetFloat("bool"); return err })
+		expectWrongType("GetString", func() error { _, err := instance.GetString("bool"); return err })
+		expectWrongType("GetComplex", func() error { _, err := instance.GetComplex("float"); return err })
+		expectWrongType("GetDuration", func() error { _, err := instance.GetDuration("int64"); return err })
+
+		if _, err := instance.GetInt("missing"); err == nil {
+			t.Error("Instance.GetInt: expected error with non-existent entry")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Error("Instance.GetInt: expected error of type *ErrNoEntry")
+		}
+
+		if instance.MustGetBool("bool") != true || instance.MustGetInt("int") != 42 ||
+			instance.MustGetInt64("int64") != int64(1)<<40 || instance.MustGetFloat("float") != 2.5 ||
+			instance.MustGetString("string") != "hello" || instance.MustGetComplex("complex") != complex(1, -1) ||
+			instance.MustGetDuration("duration") != 30*time.Second {
+			t.Error("Instance.MustGet*: got unexpected value")
+		}
+
+		for name, fn := range map[string]func(){
+			"MustGetBool":     func() { instance.MustGetBool("string") },
+			"MustGetInt":      func() { instance.MustGetInt("float") },
+			"MustGetInt64":    func() { instance.MustGetInt64("missing") },
+			"MustGetFloat":    func() { instance.MustGetFloat("bool") },
+			"MustGetString":   func() { instance.MustGetString("int64") },
+			"MustGetComplex":  func() { instance.MustGetComplex("string") },
+			"MustGetDuration": func() { instance.MustGetDuration("string") },
+		} {
+			if err := panicked(fn); err == nil {
+				t.Errorf("Instance.%s: expected panic", name)
+			}
+		}
+	})
+}
+
+// TestGetOr ensures that each of the GetOr getters returns the stored value if
+// it is of the requested type, and the default otherwise.
+func TestGetOr(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("bool", true)
+		instance.MustSet("int", 42)
+		instance.MustSet("float", 2.5)
+		instance.MustSet("string", "hello")
+
+		if res := instance.GetBoolOr("bool", false); res != true {
+			t.Errorf("Instance.GetBoolOr: got '%v' expected 'true'", res)
+		}
+
+		if res := instance.GetIntOr("int", 7); res != 42 {
+			t.Errorf("Instance.GetIntOr: got '%d' expected '42'", res)
+		}
+
+		if res := instance.GetFloatOr("float", 1); res != 2.5 {
+			t.Errorf("Instance.GetFloatOr: got '%v' expected '2.5'", res)
+		}
+
+		if res := instance.GetStringOr("string", "default"); res != "hello" {
+			t.Errorf("Instance.GetStringOr: got '%s' expected 'hello'", res)
+		}
+
+		if res := instance.GetBoolOr("missing", true); res != true {
+			t.Errorf("Instance.GetBoolOr: got '%v' expected default 'true'", res)
+		}
+
+		if res := instance.GetIntOr("string", 7); res != 7 {
+			t.Errorf("Instance.GetIntOr: got '%d' expected default '7'", res)
+		}
+
+		if res := instance.GetFloatOr("string", 1.5); res != 1.5 {
+			t.Errorf("Instance.GetFloatOr: got '%v' expected default '1.5'", res)
+		}
+
+		if res := instance.GetFloatOr("int", 1.5); res != 42 {
+			t.Errorf("Instance.GetFloatOr: got '%v' expected promoted '42'", res)
+		}
+
+		if res := instance.GetStringOr("missing", "default"); res != "default" {
+			t.Errorf("Instance.GetStringOr: got '%s' expected default 'default'", res)
+		}
+	})
+}
+
+// TestGetTypeAndEntries ensures that GetType and Entries preserve the exact
+// type with which each value was stored.
+func TestGetTypeAndEntries(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("int", 7)
+		instance.MustSet("int64", int64(7))
+		instance.MustSet("string", "7")
+
+		if res, err := instance.GetType("int64"); err != nil {
+			t.Error("Instance.GetType: got error:\n", err)
+		} else if res != TypeInt64 {
+			t.Errorf("Instance.GetType: got '%s' expected 'int64'", res)
+		}
+
+		if _, err := instance.GetType("missing"); err == nil {
+			t.Error("Instance.GetType: expected error with non-existent entry")
+		}
+
+		entries, err := instance.Entries()
+		if err != nil {
+			t.Fatal("Instance.Entries: got error:\n", err)
+		}
+
+		expected := []Entry{
+			{Name: "int", Value: 7, Type: TypeInt},
+			{Name: "int64", Value: int64(7), Type: TypeInt64},
+			{Name: "string", Value: "7", Type: TypeString},
+		}
+
+		if len(entries) != len(expected) {
+			t.Fatalf("Instance.Entries: got %d entries expected %d", len(entries), len(expected))
+		}
+
+		for i, entry := range entries {
+			if entry != expected[i] {
+				t.Errorf("Instance.Entries: got '%#v' expected '%#v'", entry, expected[i])
+			}
+		}
+	})
+}
+
+// TestStringSlice ensures that string slices round-trip through their JSON
+// encoding and that reading an entry of another type as one fails.
+func TestStringSlice(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		hosts := []string{"example.com", "with \"quotes\", and commas", ""}
+		if err := instance.SetStringSlice("hosts", hosts); err != nil {
+			t.Fatal("Instance.SetStringSlice: got error:\n", err)
+		}
+
+		if res, err := instance.GetStringSlice("hosts"); err != nil {
+			t.Error("Instance.GetStringSlice: got error:\n", err)
+		} else if !reflect.DeepEqual(res, hosts) {
+			t.Errorf("Instance.GetStringSlice: got '%v' expected '%v'", res, hosts)
+		}
+
+		if value, _ := GetFixtures(instance)["hosts"].Value.(string); value[0] != '[' {
+			t.Errorf("Instance.SetStringSlice: got stored value '%s' expected JSON array", value)
+		}
+
+		instance.MustSetStringSlice("empty", []string{})
+		if res := instance.MustGetStringSlice("empty"); res == nil || len(res) != 0 {
+			t.Errorf("Instance.MustGetStringSlice: got '%#v' expected empty slice", res)
+		}
+
+		if err := instance.Set("hosts", "not a slice"); err == nil {
+			t.Error("Instance.Set: expected error with new value of different type than existing")
+		}
+
+		instance.MustSet("string", "[\"a\"]")
+		if _, err := instance.GetStringSlice("string"); err == nil {
+			t.Error("Instance.GetStringSlice: expected error with entry of another type")
+		} else if _, ok := err.(*ErrWrongType); !ok {
+			t.Error("Instance.GetStringSlice: expected error of type *ErrWrongType")
+		}
+
+		if err := panicked(func() { instance.MustGetStringSlice("missing") }); err == nil {
+			t.Error("Instance.MustGetStringSlice: expected panic with non-existent entry")
+		}
+
+		InsertFixtures(instance, []EntryFixture{{Name: "invalid", Value: "[1, 2]", ValueType: TypeStringSlice}})
+		if _, err := instance.GetStringSlice("invalid"); err == nil {
+			t.Error("Instance.GetStringSlice: expected error with invalid JSON array")
+		} else if _, ok := err.(*ErrFailedToParse); !ok {
+			t.Error("Instance.GetStringSlice: expected error of type *ErrFailedToParse")
+		}
+
+		if names, err := instance.FindByValue(hosts); err != nil {
+			t.Error("Instance.FindByValue: got error:\n", err)
+		} else if !reflect.DeepEqual(names, []string{"hosts"}) {
+			t.Errorf("Instance.FindByValue: got '%v' expected '[hosts]'", names)
+		}
+	})
+}
+
+// TestSetStringer ensures that SetStringer stores the string form of a
+// fmt.Stringer, and rejects a nil Stringer.
+func TestSetStringer(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		if err := instance.SetStringer("timeout", 90*time.Second); err != nil {
+			t.Fatal("Instance.SetStringer: got error:\n", err)
+		}
+
+		if res := instance.MustGetString("timeout"); res != "1m30s" {
+			t.Errorf("Instance.GetString: got '%s' expected '1m30s'", res)
+		}
+
+		if err := instance.SetStringer("timeout", nil); err == nil {
+			t.Error("Instance.SetStringer: expected error with nil Stringer")
+		}
+
+		instance.MustSet("count", 1)
+		if err := panicked(func() { instance.MustSetStringer("count", time.Second) }); err == nil {
+			t.Error("Instance.MustSetStringer: expected panic with entry of another type")
+		}
+	})
+}
+
+// TestGetAll ensures that each of the GetAll getters returns every entry of
+// its own type within the scope of the Instance, and no others, with
+// GetAllFloats promoting ints as GetFloat does.
+func TestGetAll(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("debug", true)
+		instance.MustSet("server.port", 8080)
+		instance.MustSet("server.workers", 4)
+		instance.MustSet("server.big", int64(1)<<40)
+		instance.MustSet("ratio", 0.5)
+		instance.MustSet("server.host", "localhost")
+		instance.MustSet("name", "metadb")
+
+		if res := instance.MustGetAllBools(); !reflect.DeepEqual(res, map[string]bool{"debug": true}) {
+			t.Errorf("Instance.GetAllBools: got '%v'", res)
+		}
+
+		if res := instance.MustGetAllInts(); !reflect.DeepEqual(res, map[string]int{"server.port": 8080, "server.workers": 4}) {
+			t.Errorf("Instance.GetAllInts: got '%v'", res)
+		}
+
+		expectedFloats := map[string]float64{"ratio": 0.5, "server.port": 8080, "server.workers": 4, "server.big": 1 << 40}
+		if res := instance.MustGetAllFloats(); !reflect.DeepEqual(res, expectedFloats) {
+			t.Errorf("Instance.GetAllFloats: got '%v' expected '%v'", res, expectedFloats)
+		}
+
+		expected := map[string]string{"host": "localhost"}
+		if res, err := instance.WithPrefix("server.").GetAllStrings(); err != nil {
+			t.Error("Instance.GetAllStrings: got error:\n", err)
+		} else if !reflect.DeepEqual(res, expected) {
+			t.Errorf("Instance.GetAllStrings: got '%v' expected '%v'", res, expected)
+		}
+
+		instance.MustDelete("ratio")
+		if res := instance.MustGetAllFloats(); len(res) != 3 {
+			t.Errorf("Instance.GetAllFloats: got '%v' expected only the promoted ints", res)
+		}
+	})
+}
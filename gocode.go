@@ -0,0 +1,155 @@
+package metadb
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportGo writes every metadata entry to w as Go source declaring a variable
+// of type map[string]interface{} named varName, mapping the name of each
+// entry to a literal of exactly the Go type with which it was stored, such
+// that the entries may be compiled into a program as defaults and seeded with
+// SeedDefaults. Entries are written in order of name, and values as decoded by
+// any registered codecs.
+//
+// The output is formatted as by gofmt, and consists of the declaration along
+// with any imports it requires, e.g. "time" for durations, but no package
+// clause, which must be written to w beforehand:
+//
+//	fmt.Fprintln(w, "package defaults")
+//	err := instance.ExportGo(w, "Defaults")
+//
+// If varName is not a valid Go identifier, an error is returned.
+func (instance *Instance) ExportGo(w io.Writer, varName string) error {
+	if !token.IsIdentifier(varName) {
+		return fmt.Errorf("metadb: '%s' is not a valid Go identifier", varName)
+	}
+
+	entries, err := instance.Entries()
+	if err != nil {
+		return err
+	}
+
+	imports := make(map[string]bool)
+	var body bytes.Buffer
+	for _, entry := range entries {
+		literal, err := goLiteral(entry.Value, imports)
+		if err != nil {
+			return fmt.Errorf("metadb: failed to export value for '%s':\n%s", entry.Name, err)
+		}
+
+		fmt.Fprintf(&body, "%s: %s,\n", strconv.Quote(entry.Name), literal)
+	}
+
+	var paths []string
+	for _, path := range []string{"math", "math/big", "time"} {
+		if imports[path] {
+			paths = append(paths, strconv.Quote(path))
+		}
+	}
+
+	var buf bytes.Buffer
+	if len(paths) == 1 {
+		fmt.Fprintf(&buf, "import %s\n", paths[0])
+	} else if len(paths) > 1 {
+		fmt.Fprintf(&buf, "import (\n%s\n)\n", strings.Join(paths, "\n"))
+	}
+
+	fmt.Fprintf(&buf, "\n// %s holds the metadata entries exported by metadb.\n", varName)
+	fmt.Fprintf(&buf, "var %s = map[string]interface{}{\n%s}\n", varName, body.Bytes())
+
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("metadb: failed to format Go source:\n%s", err)
+	}
+
+	if _, err := w.Write(out); err != nil {
+		return fmt.Errorf("metadb: failed to write Go source:\n%s", err)
+	}
+
+	return nil
+}
+
+// MustExportGo does the same as ExportGo, but panics if an error is returned.
+func (instance *Instance) MustExportGo(w io.Writer, varName string) {
+	if err := instance.ExportGo(w, varName); err != nil {
+		panic(err)
+	}
+}
+
+// goLiteral returns a Go expression evaluating to value when assigned to an
+// interface{}, recording the import paths which it requires in imports.
+func goLiteral(value interface{}, imports map[string]bool) (string, error) {
+	switch value := value.(type) {
+	case bool:
+		return strconv.FormatBool(value), nil
+	case int:
+		return strconv.Itoa(value), nil
+	case float64:
+		return "float64(" + goFloat(value, imports) + ")", nil
+	case string:
+		return strconv.Quote(value), nil
+	case int64:
+		return "int64(" + strconv.FormatInt(value, 10) + ")", nil
+	case []string:
+		var buf bytes.Buffer
+		buf.WriteString("[]string{")
+		for i, element := range value {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+
+			buf.WriteString(strconv.Quote(element))
+		}
+
+		buf.WriteByte('}')
+		return buf.String(), nil
+	case complex128:
+		return "complex128(complex(" + goFloat(real(value), imports) + ", " + goFloat(imag(value), imports) + "))", nil
+	case time.Duration:
+		imports["time"] = true
+		return "time.Duration(" + strconv.FormatInt(int64(value), 10) + ")", nil
+	case *big.Int:
+		imports["math/big"] = true
+		return "func() *big.Int { v, _ := new(big.Int).SetString(" + strconv.Quote(value.String()) +
+			", 10); return v }()", nil
+	case *big.Float:
+		// the value is parsed at its own precision, such that it is restored
+		// exactly as by fromBlobString
+		imports["math/big"] = true
+		return "func() *big.Float { v, _ := new(big.Float).SetPrec(" + strconv.FormatUint(uint64(value.Prec()), 10) +
+			").SetString(" + strconv.Quote(value.Text('g', -1)) + "); return v }()", nil
+	}
+
+	_, err := toValueType(value)
+	return "", err
+}
+
+// goFloat returns a Go expression evaluating to the float64 f, including NaN
+// and the infinities, recording the import paths which it requires in imports.
+func goFloat(f float64, imports map[string]bool) string {
+	switch {
+	case math.IsNaN(f):
+		imports["math"] = true
+		return "math.NaN()"
+	case math.IsInf(f, 1):
+		imports["math"] = true
+		return "math.Inf(1)"
+	case math.IsInf(f, -1):
+		imports["math"] = true
+		return "math.Inf(-1)"
+	case f == 0 && math.Signbit(f):
+		imports["math"] = true
+		return "math.Copysign(0, -1)"
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
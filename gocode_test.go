@@ -0,0 +1,64 @@
+package metadb
+
+import (
+	"bytes"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestExportGo ensures that entries are exported as formatted Go source with a
+// literal of the stored type for each value.
+func TestExportGo(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		if err := instance.ExportGo(&bytes.Buffer{}, "not valid"); err == nil {
+			t.Error("Instance.ExportGo: expected error with invalid variable name")
+		}
+
+		instance.MustSet("bool", true)
+		instance.MustSet("int", 42)
+		instance.MustSet("float", 2.0)
+		instance.MustSet("nan", math.NaN())
+		instance.MustSet("string", "say \"hi\"\n")
+		instance.MustSet("int64", int64(-7))
+		instance.MustSet("slice", []string{"a", "b"})
+		instance.MustSet("complex", complex(1.5, -2))
+		instance.MustSet("duration", 90*time.Second)
+		instance.MustSet("bigInt", bigInt("123456789012345678901234567890"))
+
+		var buf bytes.Buffer
+		instance.MustExportGo(&buf, "Defaults")
+		source := "package defaults\n\n" + buf.String()
+
+		if _, err := parser.ParseFile(token.NewFileSet(), "defaults.go", source, 0); err != nil {
+			t.Fatalf("Instance.ExportGo: got invalid Go source:\n%s\n%s", err, source)
+		}
+
+		if formatted, err := format.Source([]byte(source)); err != nil || string(formatted) != source {
+			t.Errorf("Instance.ExportGo: expected gofmt-formatted source, got:\n%s", source)
+		}
+
+		for _, expected := range []string{
+			"import (\n\t\"math\"\n\t\"math/big\"\n\t\"time\"\n)",
+			"var Defaults = map[string]interface{}{",
+			`"bool":     true,`,
+			`"int":      42,`,
+			`"float":    float64(2),`,
+			`"nan":      float64(math.NaN()),`,
+			`"string":   "say \"hi\"\n",`,
+			`"int64":    int64(-7),`,
+			`"slice":    []string{"a", "b"},`,
+			`"complex":  complex128(complex(1.5, -2)),`,
+			`"duration": time.Duration(90000000000),`,
+			`new(big.Int).SetString("123456789012345678901234567890", 10)`,
+		} {
+			if !strings.Contains(source, expected) {
+				t.Errorf("Instance.ExportGo: expected output to contain '%s', got:\n%s", expected, source)
+			}
+		}
+	})
+}
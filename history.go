@@ -0,0 +1,121 @@
+package metadb
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// HistoricalValue represents a previous value of an entry, as recorded when
+// Options.History is set.
+type HistoricalValue struct {
+	Value      interface{}
+	Type       ValueType
+	ReplacedAt time.Time // time at which the value was overwritten
+}
+
+// createHistory creates the table in which previous values are recorded, if it
+// does not already exist.
+func (instance *Instance) createHistory() error {
+	_, err := instance.exec(`
+		CREATE TABLE IF NOT EXISTS {history}(
+			ID INT AUTO_INCREMENT PRIMARY KEY,
+			{name} VARCHAR(255) NOT NULL,
+			{value} BLOB NOT NULL,
+			{type} TINYINT NOT NULL,
+			ReplacedAt BIGINT NOT NULL -- nanoseconds since the Unix epoch
+		);
+	`)
+
+	return err
+}
+
+// record appends the blob string and ValueType about to be overwritten in the
+// named entry to its history, discarding the oldest values beyond
+// Options.MaxHistoryPerKey.
+func (instance *Instance) record(name string, blob string, valueType ValueType) error {
+	if _, err := instance.exec(`INSERT INTO {history} ({name}, {value}, {type}, ReplacedAt) VALUES (?, ?, ?, ?);`,
+		instance.key(name), blob, valueType, time.Now().UnixNano()); err != nil {
+		return fmt.Errorf("metadb: failed to record history for '%s':\n%s", name, err)
+	}
+
+	max := instance.options.MaxHistoryPerKey
+	if max <= 0 {
+		return nil
+	}
+
+	// find the newest of the values which no longer fit, if any
+	row := instance.queryRow(`SELECT ReplacedAt FROM {history} WHERE {name} = ?
+		ORDER BY ReplacedAt DESC LIMIT 1 OFFSET ?;`, instance.key(name), max)
+	var oldest int64
+	if err := row.Scan(&oldest); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+
+		return fmt.Errorf("metadb: failed to read history for '%s':\n%s", name, err)
+	}
+
+	if _, err := instance.exec(`DELETE FROM {history} WHERE {name} = ? AND ReplacedAt <= ?;`,
+		instance.key(name), oldest); err != nil {
+		return fmt.Errorf("metadb: failed to discard history for '%s':\n%s", name, err)
+	}
+
+	return nil
+}
+
+// History returns the previous values of the requested entry, most recently
+// replaced first. Values are only recorded if the Instance was created with
+// Options.History, and only when an entry is overwritten by a different value;
+// deleting or renaming an entry neither records its value nor removes its
+// history. If Options.History is not set, an error is returned.
+func (instance *Instance) History(name string) ([]HistoricalValue, error) {
+	history, err := instance.history(name)
+	return history, instance.wrap("History", name, err)
+}
+
+// history implements History.
+func (instance *Instance) history(name string) ([]HistoricalValue, error) {
+	if !instance.options.History {
+		return nil, fmt.Errorf("metadb: history is not enabled")
+	}
+
+	rows, err := instance.query(`SELECT {value}, {type}, ReplacedAt FROM {history} WHERE {name} = ?
+		ORDER BY ReplacedAt DESC;`, instance.key(name))
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read history for '%s':\n%s", name, err)
+	}
+	defer rows.Close()
+
+	history := make([]HistoricalValue, 0)
+	for rows.Next() {
+		var blob string
+		var valueType ValueType
+		var replacedAt int64
+		if err := rows.Scan(&blob, &valueType, &replacedAt); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan history:\n%s", err)
+		}
+
+		value, err := instance.decode(name, blob, valueType)
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, HistoricalValue{value, valueType, time.Unix(0, replacedAt)})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read history for '%s':\n%s", name, err)
+	}
+
+	return history, nil
+}
+
+// MustHistory does the same as History, but panics if an error is returned.
+func (instance *Instance) MustHistory(name string) []HistoricalValue {
+	if res, err := instance.History(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
@@ -0,0 +1,63 @@
+package metadb
+
+import (
+	"reflect"
+	"testing"
+)
+
+// historyValues returns the values of a list of HistoricalValues.
+func historyValues(history []HistoricalValue) []interface{} {
+	values := make([]interface{}, len(history))
+	for i, entry := range history {
+		values[i] = entry.Value
+	}
+
+	return values
+}
+
+// TestHistory ensures that previous values are recorded when entries are
+// overwritten, most recent first, and that the history is capped.
+func TestHistory(t *testing.T) {
+	RunWithOptions(Options{History: true, MaxHistoryPerKey: 2}, func(instance *Instance) {
+		instance.MustSet("foo", 1)
+		if history := instance.MustHistory("foo"); len(history) != 0 {
+			t.Errorf("Instance.History: got '%v' expected no history for new entry", history)
+		}
+
+		instance.MustSet("foo", 2)
+		instance.MustSet("foo", 2)
+		history, err := instance.History("foo")
+		if err != nil {
+			t.Fatal("Instance.History: got error:\n", err)
+		}
+
+		if len(history) != 1 || history[0].Value != 1 || history[0].Type != TypeInt {
+			t.Errorf("Instance.History: got '%v' expected a single int '1'", history)
+		} else if history[0].ReplacedAt.IsZero() {
+			t.Error("Instance.History: got zero time at which value was replaced")
+		}
+
+		instance.MustSet("foo", 3)
+		instance.MustForceSet("foo", "four")
+		if values := historyValues(instance.MustHistory("foo")); !reflect.DeepEqual(values, []interface{}{3, 2}) {
+			t.Errorf("Instance.History: got '%v' expected '[3 2]'", values)
+		}
+
+		instance.SetValueCodec("encoded", reverse, reverse)
+		instance.MustSet("encoded", "abc")
+		instance.MustSet("encoded", "def")
+		if values := historyValues(instance.MustHistory("encoded")); !reflect.DeepEqual(values, []interface{}{"abc"}) {
+			t.Errorf("Instance.History: got '%v' expected '[abc]' with codec", values)
+		}
+	})
+
+	RunWithInstance(func(instance *Instance) {
+		if _, err := instance.History("foo"); err == nil {
+			t.Error("Instance.History: expected error with history disabled")
+		}
+
+		if err := panicked(func() { instance.MustHistory("foo") }); err == nil {
+			t.Error("Instance.MustHistory: expected panic with history disabled")
+		}
+	})
+}
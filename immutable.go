@@ -0,0 +1,36 @@
+package metadb
+
+import "fmt"
+
+// ErrImmutable is returned when modifying or deleting an entry which has been
+// marked as immutable with MarkImmutable.
+type ErrImmutable struct {
+	Name string
+}
+
+// Error implements the error interface for ErrImmutable.
+func (err *ErrImmutable) Error() string {
+	return fmt.Sprintf("metadb: entry for '%s' is immutable", err.Name)
+}
+
+// MarkImmutable marks the entry with the given name as immutable, such that
+// once it exists, it may no longer be changed or deleted. Set, ForceSet,
+// Delete, Rename, Clear, and the methods built upon them return an
+// ErrImmutable for such an entry instead, even if the value would be left
+// unchanged. An immutable entry may still be created if it does not yet
+// exist. Immutability is only tracked by the Instance and those derived from
+// it, and is not stored in the database.
+func (instance *Instance) MarkImmutable(name string) {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	instance.immutable[instance.key(name)] = true
+}
+
+// isImmutable returns true if the named entry has been marked as immutable.
+func (instance *Instance) isImmutable(name string) bool {
+	instance.mu.RLock()
+	defer instance.mu.RUnlock()
+
+	return instance.immutable[instance.key(name)]
+}
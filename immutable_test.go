@@ -0,0 +1,56 @@
+package metadb
+
+import "testing"
+
+// TestMarkImmutable ensures that an immutable entry may be created, but that
+// every attempt to change or delete it afterward results in an ErrImmutable.
+func TestMarkImmutable(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MarkImmutable("install.id")
+		instance.MarkImmutable("install.flag")
+
+		if err := instance.Delete("install.id"); err == nil {
+			t.Error("Instance.Delete: expected error with missing entry")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Errorf("Instance.Delete: got '%v' expected ErrNoEntry", err)
+		}
+
+		if err := instance.Set("install.id", "abc123"); err != nil {
+			t.Fatal("Instance.Set: got error creating immutable entry:\n", err)
+		}
+		instance.MustSet("install.flag", true)
+
+		scoped := instance.WithPrefix("install.")
+		attempts := map[string]func() error{
+			"Set":      func() error { return instance.Set("install.id", "def456") },
+			"SetEqual": func() error { return instance.Set("install.id", "abc123") },
+			"ForceSet": func() error { return instance.ForceSet("install.id", 1) },
+			"Delete":   func() error { return scoped.Delete("id") },
+			"Pop":      func() error { _, err := instance.Pop("install.id"); return err },
+			"Rename":   func() error { return instance.Rename("install.id", "id") },
+			"Clear":    func() error { _, err := scoped.Clear(); return err },
+			"Toggle":   func() error { _, err := instance.Toggle("install.flag"); return err },
+			"Append":   func() error { _, err := instance.Append("install.id", "x"); return err },
+			"CompareAndDelete": func() error {
+				_, err := instance.CompareAndDelete("install.id", "abc123")
+				return err
+			},
+		}
+
+		for op, fn := range attempts {
+			if err := fn(); err == nil {
+				t.Errorf("Instance.%s: expected error with immutable entry", op)
+			} else if _, ok := err.(*ErrImmutable); !ok {
+				t.Errorf("Instance.%s: got '%v' expected ErrImmutable", op, err)
+			}
+		}
+
+		if value := instance.MustGetString("install.id"); value != "abc123" {
+			t.Errorf("Instance.Get: got '%s' expected 'abc123'", value)
+		}
+
+		if value := instance.MustGetBool("install.flag"); !value {
+			t.Error("Instance.Get: got 'false' expected 'true'")
+		}
+	})
+}
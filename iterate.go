@@ -0,0 +1,165 @@
+package metadb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// IterateContext passes every entry within the scope of the Instance to fn in
+// batches of at most batchSize entries, in order of name. Only a single batch
+// is held in memory at a time, and the query for each batch is completed
+// before it is passed to fn, such that fn may itself operate on the Instance.
+// Queries are bound to ctx, which is also checked between batches, such that
+// once it is cancelled iteration stops and ctx.Err() is returned. If fn
+// returns an error, iteration stops and the error is returned.
+//
+// Batches are paginated by name rather than by ID, as the ID column is not
+// populated by every database. As such, entries created or renamed during
+// iteration are only visited if their name follows that of the last entry of
+// the previous batch.
+func (instance *Instance) IterateContext(ctx context.Context, batchSize int, fn func([]Entry) error) error {
+	if batchSize <= 0 {
+		return errors.New("metadb: batch size must be positive")
+	}
+
+	bound := instance.WithContext(ctx)
+	var last *string
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch, err := bound.batch(last, batchSize)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+
+			return err
+		}
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := fn(batch); err != nil {
+			return err
+		}
+
+		if len(batch) < batchSize {
+			return nil
+		}
+
+		key := bound.key(batch[len(batch)-1].Name)
+		last = &key
+	}
+}
+
+// batch returns at most limit entries in order of name, starting after the
+// entry with the given full name if not nil.
+func (instance *Instance) batch(after *string, limit int) ([]Entry, error) {
+	scope, args := instance.scope()
+	query := "SELECT {name}, {value}, {type} FROM {table} WHERE " + scope
+	if after != nil {
+		query += " AND {name} > ?"
+		args = append(args, *after)
+	}
+
+	rows, err := instance.query(query+" ORDER BY {name} LIMIT ?;", append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+	defer rows.Close()
+
+	entries := make([]Entry, 0, limit)
+	for rows.Next() {
+		var name, blob string
+		var valueType ValueType
+		if err := rows.Scan(&name, &blob, &valueType); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+		}
+
+		name = instance.unkey(name)
+		value, err := instance.decode(name, blob, valueType)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, Entry{Name: name, Value: value, Type: valueType})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+
+	return entries, nil
+}
+
+// Stream reads every entry within the scope of the Instance in order of name
+// from a separate goroutine, sending each on the returned entry channel as
+// soon as it has been read, such that entries may be processed incrementally.
+// Once every entry has been sent, or reading fails, both channels are closed,
+// with the error being sent on the error channel first if any. Cancelling ctx
+// stops the stream, in which case ctx.Err() is sent. The error channel is
+// buffered, so that the goroutine never blocks on it and always releases the
+// underlying rows, but the entry channel must be drained or ctx cancelled for
+// it to do so.
+func (instance *Instance) Stream(ctx context.Context) (<-chan Entry, <-chan error) {
+	entries := make(chan Entry)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(entries)
+
+		if err := instance.WithContext(ctx).stream(ctx, entries); err != nil {
+			errs <- err
+		}
+	}()
+
+	return entries, errs
+}
+
+// stream implements Stream, sending each entry on entries until ctx is
+// cancelled.
+func (instance *Instance) stream(ctx context.Context, entries chan<- Entry) error {
+	scope, args := instance.scope()
+	rows, err := instance.query("SELECT {name}, {value}, {type} FROM {table} WHERE "+scope+" ORDER BY {name};", args...)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		return fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, blob string
+		var valueType ValueType
+		if err := rows.Scan(&name, &blob, &valueType); err != nil {
+			return fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+		}
+
+		name = instance.unkey(name)
+		value, err := instance.decode(name, blob, valueType)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case entries <- Entry{Name: name, Value: value, Type: valueType}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	} else if err := rows.Err(); err != nil {
+		return fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+
+	return nil
+}
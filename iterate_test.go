@@ -0,0 +1,116 @@
+package metadb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestIterateContext ensures that IterateContext visits every entry in order
+// of name in batches of the requested size, and stops once its context is
+// cancelled or the callback returns an error.
+func TestIterateContext(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		for i := 0; i < 7; i++ {
+			instance.MustSet(fmt.Sprintf("scoped.%d", i), i)
+		}
+		instance.MustSet("other", true)
+
+		scoped := instance.WithPrefix("scoped.")
+		var sizes []int
+		var names []string
+		err := scoped.IterateContext(context.Background(), 3, func(batch []Entry) error {
+			sizes = append(sizes, len(batch))
+			for _, entry := range batch {
+				names = append(names, entry.Name)
+				if entry.Value != int(entry.Name[0]-'0') || entry.Type != TypeInt {
+					t.Errorf("Instance.IterateContext: got '%v' (%s) for '%s'", entry.Value, entry.Type, entry.Name)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal("Instance.IterateContext: got error:\n", err)
+		}
+
+		if fmt.Sprint(sizes) != "[3 3 1]" {
+			t.Errorf("Instance.IterateContext: got batches of '%v' expected '[3 3 1]'", sizes)
+		}
+
+		if fmt.Sprint(names) != "[0 1 2 3 4 5 6]" {
+			t.Errorf("Instance.IterateContext: got '%v' expected '[0 1 2 3 4 5 6]'", names)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		batches := 0
+		err = scoped.IterateContext(ctx, 2, func(batch []Entry) error {
+			batches++
+			cancel()
+			return nil
+		})
+		if err != context.Canceled {
+			t.Errorf("Instance.IterateContext: got '%v' expected '%v'", err, context.Canceled)
+		}
+
+		if batches != 1 {
+			t.Errorf("Instance.IterateContext: got '%d' batches after cancellation expected '1'", batches)
+		}
+
+		stop := errors.New("stop")
+		if err := scoped.IterateContext(context.Background(), 2, func([]Entry) error {
+			return stop
+		}); err != stop {
+			t.Errorf("Instance.IterateContext: got '%v' expected '%v'", err, stop)
+		}
+
+		if err := scoped.IterateContext(context.Background(), 0, func([]Entry) error {
+			return nil
+		}); err == nil {
+			t.Error("Instance.IterateContext: expected error with batch size of 0")
+		}
+	})
+}
+
+// TestStream ensures that Stream sends every entry in order of name before
+// closing its channels, and stops once its context is cancelled.
+func TestStream(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		for i := 0; i < 5; i++ {
+			instance.MustSet(fmt.Sprintf("entry%d", i), i)
+		}
+
+		entries, errs := instance.Stream(context.Background())
+		var names []string
+		for entry := range entries {
+			names = append(names, entry.Name)
+		}
+
+		if err := <-errs; err != nil {
+			t.Error("Instance.Stream: got error:\n", err)
+		}
+
+		if fmt.Sprint(names) != "[entry0 entry1 entry2 entry3 entry4]" {
+			t.Errorf("Instance.Stream: got '%v' expected '[entry0 entry1 entry2 entry3 entry4]'", names)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		entries, errs = instance.Stream(ctx)
+		if entry := <-entries; entry.Name != "entry0" || entry.Value != 0 {
+			t.Errorf("Instance.Stream: got '%s' = '%v' expected 'entry0' = '0'", entry.Name, entry.Value)
+		}
+		cancel()
+
+		for range entries {
+			// entries may have been sent before the cancellation was observed
+		}
+
+		if err := <-errs; err != context.Canceled {
+			t.Errorf("Instance.Stream: got '%v' expected '%v'", err, context.Canceled)
+		}
+
+		// the rows must have been released for the table to be written to
+		instance.MustSet("after", true)
+	})
+}
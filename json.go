@@ -0,0 +1,303 @@
+package metadb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JSONOptions configures the output of ExportJSON. The zero value of
+// JSONOptions produces compact output with entries in no particular order.
+type JSONOptions struct {
+	// Sorted causes entries to be written in order of name, such that the
+	// output is deterministic and suitable for comparison with diff.
+	Sorted bool
+
+	// Indent, if not empty, causes the output to be pretty-printed with each
+	// level of nesting indented by Indent (e.g. "\t" or "  ").
+	Indent string
+}
+
+// jsonEntry is the representation of a single entry written by ExportJSON.
+type jsonEntry struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// jsonValue returns the given value in a form which may be encoded as JSON.
+// Complex numbers and non-finite floats have no JSON representation, durations
+// would otherwise be encoded as a bare number of nanoseconds, and
+// arbitrary-precision numbers would lose precision when read by most decoders,
+// so all are returned as their stored string form (e.g. "NaN" or "+Inf" for
+// floats), while values of every other allowed type are returned as-is.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			blob, _ := toBlobString(value)
+			return blob
+		}
+
+		return value
+	case complex128, time.Duration, *big.Int, *big.Float:
+		blob, _ := toBlobString(value)
+		return blob
+	default:
+		return value
+	}
+}
+
+// ExportJSON writes every metadata entry to w as a JSON object, mapping the
+// name of each entry to an object holding the name of its type as returned by
+// ValueType.String and its value as the corresponding JSON type, or as its
+// stored string form for complex128, time.Duration (e.g. "1m30s"), *big.Int,
+// and *big.Float values, the latter being preceded by its precision
+// (e.g. "64:1.5"), as well as for NaN and infinite float64 values (e.g.
+// "NaN" or "-Inf"), which JSON numbers cannot represent:
+//
+//	{"port":{"type":"int","value":8080},"hosts":{"type":"[]string","value":["a","b"]}}
+//
+// Values are written as decoded by any registered codecs. The output is
+// formatted according to the provided JSONOptions.
+func (instance *Instance) ExportJSON(w io.Writer, options JSONOptions) error {
+	return instance.exportJSON(w, options, nil)
+}
+
+// ExportJSONFiltered does the same as ExportJSON with the zero value of
+// JSONOptions, but only writes the entries whose names begin with any of the
+// given prefixes (e.g. "server." and "auth."), such as for a selective backup.
+// The output may be imported in the same way. If no prefixes are given, every
+// entry is written.
+func (instance *Instance) ExportJSONFiltered(w io.Writer, prefixes []string) error {
+	if len(prefixes) == 0 {
+		return instance.exportJSON(w, JSONOptions{}, nil)
+	}
+
+	return instance.exportJSON(w, JSONOptions{}, func(name string) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// exportJSON implements ExportJSON, writing only the entries for which
+// include returns true, or every entry if include is nil.
+func (instance *Instance) exportJSON(w io.Writer, options JSONOptions, include func(name string) bool) error {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	err := instance.iterate(options.Sorted, func(name string, value interface{}) error {
+		if include != nil && !include(name) {
+			return nil
+		}
+
+		valueType, err := toValueType(value)
+		if err != nil {
+			return err
+		}
+
+		raw, err := json.Marshal(jsonValue(value))
+		if err != nil {
+			return fmt.Errorf("metadb: failed to encode value for '%s':\n%s", name, err)
+		}
+
+		key, err := json.Marshal(name)
+		if err != nil {
+			return fmt.Errorf("metadb: failed to encode name '%s':\n%s", name, err)
+		}
+
+		entry, err := json.Marshal(jsonEntry{valueType.String(), raw})
+		if err != nil {
+			return fmt.Errorf("metadb: failed to encode entry for '%s':\n%s", name, err)
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	buf.WriteByte('}')
+
+	out := buf.Bytes()
+	if options.Indent != "" {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, out, "", options.Indent); err != nil {
+			return fmt.Errorf("metadb: failed to indent JSON:\n%s", err)
+		}
+
+		out = append(indented.Bytes(), '\n')
+	}
+
+	if _, err := w.Write(out); err != nil {
+		return fmt.Errorf("metadb: failed to write JSON:\n%s", err)
+	}
+
+	return nil
+}
+
+// ExportJSONMap returns every metadata entry as a map from the name of each
+// entry to its decoded value, ready to be encoded with json.Marshal, such as
+// in an API response. Unlike ExportJSON, types are not included and so the
+// output cannot be imported losslessly. As with ExportJSON, complex128,
+// time.Duration, *big.Int, and *big.Float values are given as strings, as are
+// NaN and infinite float64 values (e.g. "NaN" or "+Inf"), which json.Marshal
+// would otherwise fail to encode.
+func (instance *Instance) ExportJSONMap() (map[string]interface{}, error) {
+	entries := make(map[string]interface{})
+	err := instance.each(func(name string, value interface{}) error {
+		entries[name] = jsonValue(value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// MustExportJSONMap does the same as ExportJSONMap, but panics if an error is
+// returned.
+func (instance *Instance) MustExportJSONMap() map[string]interface{} {
+	if res, err := instance.ExportJSONMap(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// MarshalJSON implements json.Marshaler for View, encoding the entries of the
+// View as a JSON object in the same form as ExportJSONMap, including NaN and
+// infinite floats as strings.
+func (view View) MarshalJSON() ([]byte, error) {
+	entries := make(map[string]interface{}, len(view.entries))
+	for name, value := range view.entries {
+		entries[name] = jsonValue(value)
+	}
+
+	return json.Marshal(entries)
+}
+
+// fromJSON decodes the raw JSON value of an entry as the Go type represented by
+// valueType.
+func fromJSON(raw json.RawMessage, valueType ValueType) (interface{}, error) {
+	var err error
+	switch valueType {
+	case TypeBool:
+		var value bool
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case TypeInt:
+		var value int
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case TypeFloat:
+		// non-finite floats are written in their stored string form
+		var blob string
+		if json.Unmarshal(raw, &blob) == nil {
+			return fromBlobString(blob, TypeFloat)
+		}
+
+		var value float64
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case TypeString:
+		var value string
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case TypeInt64:
+		var value int64
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case TypeStringSlice:
+		var value []string
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case TypeComplex:
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+
+		return fromBlobString(value, TypeComplex)
+	case TypeDuration, TypeBigInt, TypeBigFloat:
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+
+		return fromBlobString(value, valueType)
+	default:
+		return nil, fmt.Errorf("metadb: value type unrecognizable")
+	}
+}
+
+// readJSON reads the object written by ExportJSON from r, in either compact or
+// indented form, returning an Entry for each of its members in order of name.
+// If the input is malformed, an error is returned.
+func readJSON(r io.Reader) ([]Entry, error) {
+	var object map[string]jsonEntry
+	if err := json.NewDecoder(r).Decode(&object); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read JSON:\n%s", err)
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entries := make([]Entry, 0, len(object))
+	for _, name := range names {
+		entry := object[name]
+		valueType, err := ParseValueType(entry.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := fromJSON(entry.Value, valueType)
+		if err != nil {
+			return nil, fmt.Errorf("metadb: invalid JSON value for '%s':\n%s", name, err)
+		}
+
+		entries = append(entries, Entry{Name: name, Value: value, Type: valueType})
+	}
+
+	return entries, nil
+}
+
+// ImportJSON reads entries from r in the format written by ExportJSON and
+// stores each of them within a single transaction, following the same rules as
+// ImportCSV. Both compact and indented input is accepted.
+func (instance *Instance) ImportJSON(r io.Reader, overwrite bool) error {
+	entries, err := readJSON(r)
+	if err != nil {
+		return err
+	}
+
+	return instance.withTx(func(tx *Instance) error {
+		changes, err := tx.planImport(entries, overwrite)
+		if err != nil {
+			return err
+		}
+
+		return tx.applyChanges(changes)
+	})
+}
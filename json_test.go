@@ -0,0 +1,192 @@
+package metadb
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestExportAndImportJSON ensures that entries exported as JSON in every format
+// are imported losslessly, and that malformed input is rejected.
+func TestExportAndImportJSON(t *testing.T) {
+	values := map[string]interface{}{
+		"bool":     true,
+		"int":      -42,
+		"float":    3.0,
+		"string":   "quotes \" and\nnewlines",
+		"int64":    int64(1) << 62,
+		"slice":    []string{"a", "b"},
+		"complex":  complex(1.5, -2.5),
+		"duration": 1500 * time.Millisecond,
+	}
+
+	for _, options := range []JSONOptions{{}, {Sorted: true}, {Sorted: true, Indent: "\t"}} {
+		var buf bytes.Buffer
+		RunWithInstance(func(instance *Instance) {
+			for name, value := range values {
+				instance.MustSet(name, value)
+			}
+
+			if err := instance.ExportJSON(&buf, options); err != nil {
+				t.Fatal("Instance.ExportJSON: got error:\n", err)
+			}
+		})
+
+		if indented := strings.Contains(buf.String(), "\n\t"); indented != (options.Indent != "") {
+			t.Errorf("Instance.ExportJSON: got indented '%t' with options '%+v'", indented, options)
+		}
+
+		RunWithInstance(func(instance *Instance) {
+			if err := instance.ImportJSON(bytes.NewReader(buf.Bytes()), false); err != nil {
+				t.Fatal("Instance.ImportJSON: got error:\n", err)
+			}
+
+			for name, expected := range values {
+				if value := instance.MustGet(name); !reflect.DeepEqual(value, expected) {
+					t.Errorf("Instance.ImportJSON: got '%v' (%T) expected '%v' (%T)", value, value, expected, expected)
+				}
+			}
+		})
+	}
+
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("b", 2)
+		instance.MustSet("a", 1)
+
+		var buf bytes.Buffer
+		if err := instance.ExportJSON(&buf, JSONOptions{Sorted: true}); err != nil {
+			t.Fatal("Instance.ExportJSON: got error:\n", err)
+		}
+
+		expected := `{"a":{"type":"int","value":1},"b":{"type":"int","value":2}}`
+		if buf.String() != expected {
+			t.Errorf("Instance.ExportJSON: got '%s' expected '%s'", buf.String(), expected)
+		}
+
+		for _, input := range []string{
+			`{"a":{"type":"int","value":"1"}}`,
+			`{"a":{"type":"complex","value":1}}`,
+			`{"a":1}`,
+			`[]`,
+		} {
+			if err := instance.ImportJSON(strings.NewReader(input), true); err == nil {
+				t.Errorf("Instance.ImportJSON: expected error with input '%s'", input)
+			}
+		}
+
+		if value := instance.MustGet("a"); value != 1 {
+			t.Errorf("Instance.ImportJSON: got '%v' expected '1' after failed import", value)
+		}
+	})
+}
+
+// TestExportAndImportJSONNonFinite ensures that NaN and infinite floats, which
+// JSON numbers cannot represent, are exported as strings and imported as-is.
+func TestExportAndImportJSONNonFinite(t *testing.T) {
+	values := map[string]float64{"nan": math.NaN(), "inf": math.Inf(1), "negInf": math.Inf(-1)}
+
+	var buf bytes.Buffer
+	RunWithInstance(func(instance *Instance) {
+		for name, value := range values {
+			instance.MustSet(name, value)
+		}
+
+		if err := instance.ExportJSON(&buf, JSONOptions{Sorted: true}); err != nil {
+			t.Fatal("Instance.ExportJSON: got error:\n", err)
+		}
+
+		expected := `{"inf":{"type":"float64","value":"+Inf"},"nan":{"type":"float64","value":"NaN"},` +
+			`"negInf":{"type":"float64","value":"-Inf"}}`
+		if buf.String() != expected {
+			t.Errorf("Instance.ExportJSON: got '%s' expected '%s'", buf.String(), expected)
+		}
+	})
+
+	RunWithInstance(func(instance *Instance) {
+		if err := instance.ImportJSON(bytes.NewReader(buf.Bytes()), false); err != nil {
+			t.Fatal("Instance.ImportJSON: got error:\n", err)
+		}
+
+		for name, expected := range values {
+			if value := instance.MustGetFloat(name); value != expected && !(math.IsNaN(value) && math.IsNaN(expected)) {
+				t.Errorf("Instance.ImportJSON: got '%v' expected '%v' for '%s'", value, expected, name)
+			}
+		}
+
+		if err := instance.ImportJSON(strings.NewReader(`{"a":{"type":"float64","value":"none"}}`), false); err == nil {
+			t.Error("Instance.ImportJSON: expected error with malformed float string")
+		}
+	})
+}
+
+// TestExportJSONMap ensures that ExportJSONMap and View.MarshalJSON produce a
+// plain object mapping names to values, including non-finite floats.
+func TestExportJSONMap(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("port", 8080)
+		instance.MustSet("hosts", []string{"a", "b"})
+		instance.MustSet("phase", complex(1, 2))
+		instance.MustSet("limit", math.Inf(1))
+		instance.MustSet("ratio", math.NaN())
+
+		entries, err := instance.ExportJSONMap()
+		if err != nil {
+			t.Fatal("Instance.ExportJSONMap: got error:\n", err)
+		}
+
+		expected := `{"hosts":["a","b"],"limit":"+Inf","phase":"(1+2i)","port":8080,"ratio":"NaN"}`
+		if res, err := json.Marshal(entries); err != nil {
+			t.Error("json.Marshal: got error:\n", err)
+		} else if string(res) != expected {
+			t.Errorf("Instance.ExportJSONMap: got '%s' expected '%s'", res, expected)
+		}
+
+		if res, err := json.Marshal(instance.MustViewSnapshot()); err != nil {
+			t.Error("View.MarshalJSON: got error:\n", err)
+		} else if string(res) != expected {
+			t.Errorf("View.MarshalJSON: got '%s' expected '%s'", res, expected)
+		}
+	})
+}
+
+// TestExportJSONFiltered ensures that only entries matching any of the given
+// prefixes are exported, that no prefixes export every entry, and that the
+// output imports cleanly.
+func TestExportJSONFiltered(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("server.port", 8080)
+		instance.MustSet("auth.secret", "hunter2")
+		instance.MustSet("cache.hits", 42)
+
+		var buf bytes.Buffer
+		if err := instance.ExportJSONFiltered(&buf, []string{"server.", "auth."}); err != nil {
+			t.Fatal("Instance.ExportJSONFiltered: got error:\n", err)
+		}
+		exported := buf.String()
+
+		instance.MustDelete("server.port")
+		instance.MustDelete("auth.secret")
+		instance.MustDelete("cache.hits")
+
+		if err := instance.ImportJSON(strings.NewReader(exported), false); err != nil {
+			t.Fatal("Instance.ImportJSON: got error:\n", err)
+		}
+
+		expected := map[string]interface{}{"server.port": 8080, "auth.secret": "hunter2"}
+		if res := instance.MustExportJSONMap(); !reflect.DeepEqual(res, expected) {
+			t.Errorf("Instance.ExportJSONFiltered: got '%v' expected '%v' once imported", res, expected)
+		}
+
+		buf.Reset()
+		instance.MustSet("cache.hits", 42)
+		if err := instance.ExportJSONFiltered(&buf, nil); err != nil {
+			t.Error("Instance.ExportJSONFiltered: got error:\n", err)
+		} else if res := strings.Count(buf.String(), `"type"`); res != 3 {
+			t.Errorf("Instance.ExportJSONFiltered: got %d entries expected 3 with no prefixes", res)
+		}
+	})
+}
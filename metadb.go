@@ -18,10 +18,19 @@ instance:
 package metadb
 
 import (
+	"context"
+	"crypto/cipher"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // ErrNoEntry is returned by Get when a requested entry does not exist.
@@ -34,6 +43,12 @@ func (err *ErrNoEntry) Error() string {
 	return fmt.Sprintf("metadb: no entry for '%s'", err.Name)
 }
 
+// Is reports whether target is sql.ErrNoRows, allowing an ErrNoEntry to be
+// matched by errors.Is(err, sql.ErrNoRows) as well as by its own type.
+func (err *ErrNoEntry) Is(target error) bool {
+	return target == sql.ErrNoRows
+}
+
 // ErrFailedToParse is returned indirectly by Get when a blob string cannot be
 // parsed.
 type ErrFailedToParse struct {
@@ -45,118 +60,685 @@ func (err *ErrFailedToParse) Error() string {
 	return fmt.Sprintf("metadb: failed to parse value blob string:\n%s", err.Err)
 }
 
+// ErrValueTooLarge is returned by Set when the encoded value of an entry exceeds
+// Options.MaxValueBytes.
+type ErrValueTooLarge struct {
+	Name string
+	Size int
+	Max  int
+}
+
+// Error implements the error interface for ErrValueTooLarge.
+func (err *ErrValueTooLarge) Error() string {
+	return fmt.Sprintf("metadb: value for '%s' is %d bytes, exceeding the maximum of %d", err.Name, err.Size, err.Max)
+}
+
+// ErrRoundTrip is returned by Set when Options.VerifyEncoding is set and the
+// encoded value of an entry cannot be decoded, in which case Err is the error
+// while decoding it, or decodes to a different value, Decoded.
+type ErrRoundTrip struct {
+	Name    string
+	Value   interface{}
+	Decoded interface{}
+	Err     error
+}
+
+// Error implements the error interface for ErrRoundTrip.
+func (err *ErrRoundTrip) Error() string {
+	if err.Err != nil {
+		return fmt.Sprintf("metadb: value for '%s' cannot be decoded once encoded:\n%s", err.Name, err.Err)
+	}
+
+	return fmt.Sprintf("metadb: value for '%s' decodes to '%v' rather than '%v' once encoded", err.Name, err.Decoded, err.Value)
+}
+
+// ErrDuplicateKey is returned by Set when inserting an entry fails because an
+// entry by the same name was created concurrently, e.g. by another process,
+// after it was found not to exist. Retrying the operation updates the entry
+// instead.
+type ErrDuplicateKey struct {
+	Name string
+}
+
+// Error implements the error interface for ErrDuplicateKey.
+func (err *ErrDuplicateKey) Error() string {
+	return fmt.Sprintf("metadb: entry for '%s' was created concurrently", err.Name)
+}
+
+// isDuplicateKey returns true if err reports the violation of a unique
+// constraint. As the drivers do not share a common error type, this is
+// detected by the messages of SQLite, MySQL, and PostgreSQL respectively.
+func isDuplicateKey(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "unique constraint failed") ||
+		strings.Contains(message, "duplicate entry") ||
+		strings.Contains(message, "duplicate key value violates unique constraint")
+}
+
+// Entry represents a single metadata entry along with its decoded value.
+type Entry struct {
+	Name  string
+	Value interface{}
+	Type  ValueType
+
+	// UpdatedAt is the time at which the entry was last written. It is only
+	// set by methods which document doing so, and only if the Instance was
+	// created with Options.Timestamps.
+	UpdatedAt time.Time
+
+	// LastReadAt is the time at which the entry was last read by Get. It is
+	// only set by methods which document doing so, and only if the Instance
+	// was created with Options.TrackReads.
+	LastReadAt time.Time
+
+	// Description is the human-readable description of the entry set with
+	// SetDescription. It is only set by methods which document doing so, and
+	// only if the Instance was created with Options.Descriptions.
+	Description string
+}
+
 // Instance represents a single database connection with which metadata create,
 // read, update, and delete operation may be performed. It is not intended to
 // be manipulated manually, but rather through NewInstance and a variety of
 // methods.
 type Instance struct {
 	DB *sql.DB
+
+	db       executor        // handle on which operations are performed, DB or a transaction
+	ctx      context.Context // parent of the context of every operation
+	pending  *[]string       // full names of entries changed within the bound transaction, if any
+	prefix   string          // prepended to the name of every entry, see WithPrefix
+	fallback *Instance       // read from for entries which do not exist, see WithFallback
+	options  Options
+	schema   schema
+	aead     cipher.AEAD // encrypts every stored value, see Options.EncryptionKey
+	*registry
+}
+
+// registry holds the per-entry configuration of an Instance, which is shared
+// with any Instance derived from it (e.g. one bound to a transaction).
+type registry struct {
+	mu         sync.RWMutex
+	codecs     map[string]valueCodec
+	validators map[string]func(value interface{}) error
+	defaults   map[string]interface{}
+	bindings   map[string][]reflect.Value
+	immutable  map[string]bool
+	enums      map[string][]string
+	waiters    map[string][]chan struct{} // closed once the entry is written, see WaitFor
+	statements map[string]*sql.Stmt       // prepared statements, keyed by query
+	worker     *worker                    // performs routed operations, see NewSerializedInstance
 }
 
 // NewInstance takes a database handle and uses it to initialize the metadata
 // table within that database and perform all operations thereafter. If this is
 // successful, a pointer to an Instance is returned. Otherwise, an error is
-// returned.
+// returned. If the metadata table already exists but is missing any of the
+// expected columns or any of them has an incompatible type, an
+// ErrSchemaMismatch is returned.
 func NewInstance(db *sql.DB) (*Instance, error) {
+	return NewInstanceWithOptions(db, Options{})
+}
+
+// NewInstanceWithOptions does the same as NewInstance, but configures the
+// returned Instance according to the provided Options.
+func NewInstanceWithOptions(db *sql.DB, options Options) (*Instance, error) {
 	if db == nil {
 		return nil, fmt.Errorf("NewInstance: got nil database handle")
 	}
 
-	if _, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS metadata(
+	return newInstance(db, db, options)
+}
+
+// NewInstanceWithRetry does the same as NewInstanceWithOptions, but waits for
+// the database to become available, as it may not be while it is still
+// starting up. The database is pinged and the schema initialized up to the
+// given number of attempts, waiting for delay after the first failed attempt
+// and twice as long after each following one. If every attempt fails, the last
+// error is returned, while if ctx is cancelled, ctx.Err() is returned
+// immediately. An ErrSchemaMismatch is returned without further attempts, as
+// it would only recur. The returned Instance is not bound to ctx.
+func NewInstanceWithRetry(ctx context.Context, db *sql.DB, options Options, attempts int, delay time.Duration) (*Instance, error) {
+	if attempts < 1 {
+		return nil, fmt.Errorf("NewInstance: got %d attempts, expected at least 1", attempts)
+	}
+
+	// the schema is initialized below, as part of each attempt
+	deferred := options
+	deferred.SkipInitSchema = true
+	instance, err := NewInstanceWithOptions(db, deferred)
+	if err != nil {
+		return nil, err
+	}
+	instance.options = options
+
+	for attempt := 1; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil && !options.SkipInitSchema {
+			err = instance.WithContext(ctx).InitSchema()
+		}
+
+		if err == nil {
+			return instance, nil
+		} else if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		} else if _, ok := err.(*ErrSchemaMismatch); ok {
+			return nil, err
+		} else if attempt >= attempts {
+			return nil, fmt.Errorf("NewInstance: failed after %d attempts:\n%s", attempts, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		delay *= 2
+	}
+}
+
+// NewInstanceFromConn does the same as NewInstance, but performs every
+// operation on a single dedicated connection rather than on connections from
+// the pool of an *sql.DB. With SQLite, this avoids SQLITE_BUSY errors caused by
+// concurrent connections entirely. The DB field of the returned Instance is
+// nil.
+func NewInstanceFromConn(conn *sql.Conn) (*Instance, error) {
+	return NewInstanceFromConnWithOptions(conn, Options{})
+}
+
+// NewInstanceFromConnWithOptions does the same as NewInstanceFromConn, but
+// configures the returned Instance according to the provided Options.
+func NewInstanceFromConnWithOptions(conn *sql.Conn, options Options) (*Instance, error) {
+	if conn == nil {
+		return nil, fmt.Errorf("NewInstance: got nil connection")
+	}
+
+	return newInstance(nil, conn, options)
+}
+
+// newInstance implements the code shared between the constructors, taking the
+// *sql.DB to expose through the DB field, if any, and the handle on which
+// operations are to be performed.
+func newInstance(handle *sql.DB, db executor, options Options) (*Instance, error) {
+	schema, err := newSchema(options)
+	if err != nil {
+		return nil, fmt.Errorf("NewInstance: %s", err)
+	}
+
+	var aead cipher.AEAD
+	if options.EncryptionKey != nil {
+		if aead, err = newAEAD(options.EncryptionKey); err != nil {
+			return nil, fmt.Errorf("NewInstance: invalid encryption key: %s", err)
+		}
+	}
+
+	instance := &Instance{
+		DB:      handle,
+		db:      db,
+		ctx:     context.Background(),
+		options: options,
+		schema:  schema,
+		aead:    aead,
+		registry: &registry{
+			codecs:     make(map[string]valueCodec),
+			validators: make(map[string]func(value interface{}) error),
+			defaults:   make(map[string]interface{}),
+			bindings:   make(map[string][]reflect.Value),
+			immutable:  make(map[string]bool),
+			enums:      make(map[string][]string),
+			waiters:    make(map[string][]chan struct{}),
+			statements: make(map[string]*sql.Stmt),
+		},
+	}
+
+	if !options.SkipInitSchema {
+		if err := instance.InitSchema(); err != nil {
+			return nil, err
+		}
+	}
+
+	return instance, nil
+}
+
+// InitSchema creates the metadata table along with any indexes and additional
+// tables required by the Options of the Instance, unless they already exist,
+// and checks that the columns of the table are compatible as described on
+// NewInstance. Once every migration has succeeded, LatestSchemaVersion is
+// recorded as described on SchemaVersion. It is called by NewInstance unless
+// Options.SkipInitSchema is set, and may safely be called any number of times.
+func (instance *Instance) InitSchema() error {
+	options := instance.options
+
+	if _, err := instance.exec(`
+		CREATE TABLE IF NOT EXISTS {table}(
 			ID INT AUTO_INCREMENT PRIMARY KEY,
-			Name VARCHAR(255) NOT NULL UNIQUE,
-			Value BLOB NOT NULL,
-			ValueType TINYINT NOT NULL
-			-- 0 = bool, 1 = int, 2 = float64, 3 = string
+			{name} VARCHAR(255) NOT NULL UNIQUE,
+			{value} BLOB NOT NULL,
+			{type} TINYINT NOT NULL
+			-- 0 = bool, 1 = int, 2 = float64, 3 = string, 4 = int64, 5 = []string, 6 = complex128,
+			-- 7 = time.Duration, 8 = *big.Int, 9 = *big.Float
 		);
 	`); err != nil {
-		// TODO: Should errors such as this really be propagated? If such errors occur with one
-		// call to this function, the same error as was propagated the first time will occur with
-		// every call after until the underlying issue is fixed.
-		return nil, fmt.Errorf("NewInstance: got error while creating metadata table:\n%s", err)
+		// The statement may fail even though the table exists, e.g. if the user lacks the
+		// privilege to create tables or the database is read-only. Such errors would recur with
+		// every call until the underlying issue is fixed, so they may be ignored if requested as
+		// long as a compatible table is found.
+		if !options.TolerateCreateErrors {
+			return fmt.Errorf("InitSchema: got error while creating metadata table:\n%s", err)
+		} else if schemaErr := instance.checkSchema(); schemaErr != nil {
+			return fmt.Errorf("InitSchema: got error while creating metadata table:\n%s", err)
+		}
+	}
+
+	if err := instance.checkSchema(); err != nil {
+		return err
+	}
+
+	if options.Timestamps {
+		// the time is stored in nanoseconds since the Unix epoch, or 0 if unknown
+		if err := instance.addColumn(instance.schema.updated, "BIGINT NOT NULL DEFAULT 0"); err != nil {
+			return fmt.Errorf("InitSchema: got error while adding timestamps to metadata table:\n%s", err)
+		}
+	}
+
+	if options.TrackReads {
+		if err := instance.addColumn(instance.schema.read, "BIGINT NOT NULL DEFAULT 0"); err != nil {
+			return fmt.Errorf("InitSchema: got error while adding read times to metadata table:\n%s", err)
+		}
+	}
+
+	if options.SoftDelete {
+		if err := instance.addColumn(instance.schema.deleted, "TINYINT NOT NULL DEFAULT 0"); err != nil {
+			return fmt.Errorf("InitSchema: got error while adding deletion flags to metadata table:\n%s", err)
+		}
 	}
 
-	return &Instance{db}, nil
+	if options.Descriptions {
+		if err := instance.addColumn(instance.schema.description, "TEXT"); err != nil {
+			return fmt.Errorf("InitSchema: got error while adding descriptions to metadata table:\n%s", err)
+		}
+	}
+
+	if !options.SkipIndexes {
+		if err := instance.createIndexes(); err != nil && !options.TolerateCreateErrors {
+			return fmt.Errorf("InitSchema: got error while creating indexes:\n%s", err)
+		}
+	}
+
+	if options.History {
+		if err := instance.createHistory(); err != nil {
+			return fmt.Errorf("InitSchema: got error while creating history table:\n%s", err)
+		}
+	}
+
+	// the version is recorded last, such that it is only updated once every
+	// migration has succeeded
+	if err := instance.recordVersion(); err != nil && !options.TolerateCreateErrors {
+		return fmt.Errorf("InitSchema: got error while recording schema version:\n%s", err)
+	}
+
+	return nil
 }
 
 // Exists returns true if the requested entry exists, and false if it does not.
+// An entry existing only within the fallback of the Instance, if any, is
+// considered to exist, see WithFallback.
 func (instance *Instance) Exists(name string) bool {
-	row := instance.DB.QueryRow("SELECT Name FROM metadata WHERE name = ?;", name)
+	exists, err := instance.exists(name)
+	if err != nil {
+		panic(fmt.Errorf("Instance.Exists: got error:\n%s", err))
+	}
+
+	if !exists && instance.fallback != nil {
+		return instance.fallback.Exists(name)
+	}
+
+	return exists
+}
+
+// exists does the same as Exists, but returns an error rather than panicking.
+func (instance *Instance) exists(name string) (bool, error) {
+	row := instance.queryRow("SELECT {name} FROM {table} WHERE {name} = ? AND {visible};", instance.key(name))
 	var receivedName string
 	err := row.Scan(&receivedName)
 
 	if err != nil {
 		// if no rows were selected, return false
 		if err == sql.ErrNoRows {
-			return false
+			return false, nil
 		}
 
-		panic(fmt.Errorf("Instance.Exists: got error:\n%s", err))
+		return false, err
 	}
 
-	return true
+	return true, nil
 }
 
-// toValueType takes a value interface and checks its type, returning an
-// unsigned integer representing this type. If the type is not allowed, an
-// error is returned.
-func toValueType(value interface{}) (uint, error) {
-	switch value.(type) {
+// ValueType identifies the type of the data stored within a metadata entry.
+type ValueType uint
+
+// The value types which may be stored within a metadata entry. Their values
+// correspond to those stored in the ValueType column of the metadata table.
+const (
+	TypeBool        ValueType = iota // bool
+	TypeInt                          // int, stored with intBitSize bits
+	TypeFloat                        // float64
+	TypeString                       // string
+	TypeInt64                        // int64
+	TypeStringSlice                  // []string
+	TypeComplex                      // complex128
+	TypeDuration                     // time.Duration
+	TypeBigInt                       // *big.Int
+	TypeBigFloat                     // *big.Float
+)
+
+// intBitSize is the width with which values of TypeInt are stored and parsed,
+// fixed at 64 bits regardless of the size of int on the platform, such that a
+// database is read identically by every platform. Reading an int which does
+// not fit within the int of a 32-bit platform fails with an ErrFailedToParse
+// wrapping strconv.ErrRange, in which case int64 should be stored instead.
+const intBitSize = 64
+
+// valueTypeNames maps each value type to its human-readable name.
+var valueTypeNames = map[ValueType]string{
+	TypeBool:        "bool",
+	TypeInt:         "int",
+	TypeFloat:       "float64",
+	TypeString:      "string",
+	TypeInt64:       "int64",
+	TypeStringSlice: "[]string",
+	TypeComplex:     "complex128",
+	TypeDuration:    "time.Duration",
+	TypeBigInt:      "*big.Int",
+	TypeBigFloat:    "*big.Float",
+}
+
+// String returns the name of the Go type represented by a ValueType, or a
+// placeholder containing its index if it is unrecognized.
+func (valueType ValueType) String() string {
+	if name, ok := valueTypeNames[valueType]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("ValueType(%d)", uint(valueType))
+}
+
+// ParseValueType returns the ValueType with the given name, as returned by
+// ValueType.String. If the name is not recognized, an error is returned.
+func ParseValueType(name string) (ValueType, error) {
+	for valueType, typeName := range valueTypeNames {
+		if typeName == name {
+			return valueType, nil
+		}
+	}
+
+	return 0, fmt.Errorf("metadb: unrecognized value type '%s'", name)
+}
+
+// toValueType takes a value interface and checks its type, returning the
+// ValueType representing this type. If the type is not allowed or the value is
+// a nil *big.Int or *big.Float, an error is returned.
+func toValueType(value interface{}) (ValueType, error) {
+	switch value := value.(type) {
 	case bool:
-		return 0, nil
+		return TypeBool, nil
 	case int:
-		return 1, nil
+		return TypeInt, nil
 	case float64:
-		return 2, nil
+		return TypeFloat, nil
 	case string:
-		return 3, nil
+		return TypeString, nil
+	case int64:
+		return TypeInt64, nil
+	case []string:
+		return TypeStringSlice, nil
+	case complex128:
+		return TypeComplex, nil
+	case time.Duration:
+		return TypeDuration, nil
+	case *big.Int:
+		if value == nil {
+			return 0, errors.New("metadb: value is a nil *big.Int")
+		}
+
+		return TypeBigInt, nil
+	case *big.Float:
+		if value == nil {
+			return 0, errors.New("metadb: value is a nil *big.Float")
+		}
+
+		return TypeBigFloat, nil
 	default:
 		return 0, errors.New("metadb: value is of a disallowed type " +
-			"(allowed: bool, int, float64, string)")
+			"(allowed: bool, int, float64, string, int64, []string, complex128, time.Duration, " +
+			"*big.Int, *big.Float)")
+	}
+}
+
+// coerceNumber converts values of the numeric types which are not allowed to
+// the nearest allowed type, as described by Options.CoerceNumbers. Values of
+// other types are returned as-is, while unsigned values which overflow an
+// int64 cause an error to be returned.
+func coerceNumber(value interface{}) (interface{}, error) {
+	var unsigned uint64
+	switch value := value.(type) {
+	case int8:
+		return int(value), nil
+	case int16:
+		return int(value), nil
+	case int32:
+		return int(value), nil
+	case uint8:
+		return int(value), nil
+	case uint16:
+		return int(value), nil
+	case float32:
+		return float64(value), nil
+	case uint32:
+		return int64(value), nil
+	case uint:
+		unsigned = uint64(value)
+	case uint64:
+		unsigned = value
+	case uintptr:
+		unsigned = uint64(value)
+	default:
+		return value, nil
+	}
+
+	if unsigned > math.MaxInt64 {
+		return nil, fmt.Errorf("metadb: value %d overflows int64", unsigned)
+	}
+
+	return int64(unsigned), nil
+}
+
+// toBlobString takes a value interface and returns the string representation
+// under which it is stored in the database. If the type is not allowed, an
+// error is returned.
+func toBlobString(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case bool:
+		return strconv.FormatBool(value), nil
+	case int:
+		return strconv.FormatInt(int64(value), 10), nil
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64), nil
+	case string:
+		return value, nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case []string:
+		res, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+
+		return string(res), nil
+	case complex128:
+		return strconv.FormatComplex(value, 'g', -1, 128), nil
+	case time.Duration:
+		return value.String(), nil
+	case *big.Int:
+		if value != nil {
+			return value.String(), nil
+		}
+	case *big.Float:
+		// the precision is kept alongside the shortest decimal form which
+		// identifies the value, such that it is restored exactly when parsed
+		if value != nil {
+			return strconv.FormatUint(uint64(value.Prec()), 10) + ":" + value.Text('g', -1), nil
+		}
+	}
+
+	_, err := toValueType(value)
+	return "", err
+}
+
+// equal returns true if both values are of the same allowed type and have the
+// same encoded representation. Unlike ==, it may be used with values of any of
+// the allowed types, including slices.
+func equal(a, b interface{}) bool {
+	aType, aErr := toValueType(a)
+	bType, bErr := toValueType(b)
+	if aErr != nil || bErr != nil || aType != bType {
+		return false
+	}
+
+	aBlob, _ := toBlobString(a)
+	bBlob, _ := toBlobString(b)
+	return aBlob == bBlob
+}
+
+// storedValue returns the value passed to the database when writing the named
+// entry, given its value and encoded blob string. This is the blob string
+// unless the Instance was created with Options.NativeTypes, in which case
+// bools, ints, floats, and int64s without a codec are passed as-is. Either is
+// read back as the same blob string, as database/sql formats numbers in the
+// same way and strconv.ParseBool accepts the 1 and 0 under which drivers store
+// bools.
+func (instance *Instance) storedValue(name string, value interface{}, blob string) interface{} {
+	if !instance.options.NativeTypes {
+		return blob
+	} else if instance.opaque(name) {
+		return blob
+	} else if native, ok := toNative(value); ok {
+		return native
+	}
+
+	return blob
+}
+
+// toNative returns the value passed to the database for a bool, int, float64,
+// or int64 stored natively, and false for values of any other type.
+func toNative(value interface{}) (interface{}, bool) {
+	switch value := value.(type) {
+	case bool, float64, int64:
+		return value, true
+	case int:
+		return int64(value), true
+	default:
+		return nil, false
 	}
 }
 
-// fromBlobString takes a string and an unsigned integer. The string is
-// retrieved directly from the database and contains some raw data, while the
-// unsigned integer represents the type of data retrieved and therefore how it
-// is to be processed. An interface containing the decoded value is returned,
-// or an error if conversion fails or the data type is invalid.
-func fromBlobString(value string, valueType uint) (interface{}, error) {
+// fromBlobString takes a string and a ValueType. The string is retrieved
+// directly from the database and contains some raw data, while the ValueType
+// represents the type of data retrieved and therefore how it is to be
+// processed. An interface containing the decoded value is returned, or an
+// error if conversion fails or the data type is invalid.
+func fromBlobString(value string, valueType ValueType) (interface{}, error) {
 	switch valueType {
-	case 0: // value is a boolean
+	case TypeBool: // value is a boolean
 		res, err := strconv.ParseBool(value)
 		if err != nil {
 			return nil, &ErrFailedToParse{err}
 		}
 
 		return res, nil
-	case 1: // value is an int
-		res, err := strconv.ParseInt(value, 10, 0)
+	case TypeInt: // value is an int
+		res, err := strconv.ParseInt(value, 10, intBitSize)
 		if err != nil {
 			return nil, &ErrFailedToParse{err}
+		} else if res < math.MinInt || res > math.MaxInt {
+			return nil, &ErrFailedToParse{&strconv.NumError{Func: "ParseInt", Num: value, Err: strconv.ErrRange}}
 		}
 
 		return int(res), nil
-	case 2: // value is a float64
+	case TypeFloat: // value is a float64
 		res, err := strconv.ParseFloat(value, 64)
 		if err != nil {
 			return nil, &ErrFailedToParse{err}
 		}
 
 		return res, nil
-	case 3: // value is a string
+	case TypeString: // value is a string
 		return value, nil
+	case TypeInt64: // value is an int64
+		res, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, &ErrFailedToParse{err}
+		}
+
+		return res, nil
+	case TypeStringSlice: // value is a JSON array of strings
+		var res []string
+		if err := json.Unmarshal([]byte(value), &res); err != nil {
+			return nil, &ErrFailedToParse{err}
+		}
+
+		return res, nil
+	case TypeComplex: // value is a complex128
+		res, err := strconv.ParseComplex(value, 128)
+		if err != nil {
+			return nil, &ErrFailedToParse{err}
+		}
+
+		return res, nil
+	case TypeDuration: // value is a time.Duration, as formatted by its String method
+		res, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, &ErrFailedToParse{err}
+		}
+
+		return res, nil
+	case TypeBigInt: // value is a *big.Int in base 10
+		res, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return nil, &ErrFailedToParse{fmt.Errorf("invalid *big.Int '%s'", value)}
+		}
+
+		return res, nil
+	case TypeBigFloat: // value is a *big.Float, optionally preceded by its precision and a colon
+		res := new(big.Float)
+		if i := strings.IndexByte(value, ':'); i >= 0 {
+			prec, err := strconv.ParseUint(value[:i], 10, 32)
+			if err != nil || prec > big.MaxPrec {
+				return nil, &ErrFailedToParse{fmt.Errorf("invalid *big.Float precision '%s'", value[:i])}
+			}
+
+			res.SetPrec(uint(prec))
+			value = value[i+1:]
+		}
+
+		if _, ok := res.SetString(value); !ok {
+			return nil, &ErrFailedToParse{fmt.Errorf("invalid *big.Float '%s'", value)}
+		}
+
+		return res, nil
 	default:
 		return nil, fmt.Errorf("metadb: value type unrecognizable")
 	}
 }
 
-// getValueType returns an unsigned integer representing the type of data
-// stored in the requested metadata entry, or an ErrNoEntry if none exists.
-func (instance *Instance) getValueType(name string) (uint, error) {
-	row := instance.DB.QueryRow("SELECT ValueType FROM metadata WHERE name = ?", name)
-	var valueType uint
+// getValueType returns the ValueType representing the type of data stored in
+// the requested metadata entry, or an ErrNoEntry if none exists.
+func (instance *Instance) getValueType(name string) (ValueType, error) {
+	row := instance.preparedRow("SELECT {type} FROM {table} WHERE {name} = ? AND {visible}", instance.key(name))
+	var valueType ValueType
 	err := row.Scan(&valueType)
 
 	if err != nil {
@@ -172,12 +754,38 @@ func (instance *Instance) getValueType(name string) (uint, error) {
 }
 
 // Get returns an interface containing the data within the requested entry. If
-// the entry does not exist or if the stored data type identifier is invalid,
-// an error is returned.
+// the entry does not exist, it is read from the fallback of the Instance, if
+// any, see WithFallback. If it does not exist there either or if the stored
+// data type identifier is invalid, an error is returned.
 func (instance *Instance) Get(name string) (interface{}, error) {
-	row := instance.DB.QueryRow("SELECT Value, ValueType FROM metadata WHERE name = ?", name)
+	var value interface{}
+	err := instance.around("Get", name, func(held *Instance) (err error) {
+		value, err = held.get(name)
+		return err
+	})
+	if err != nil {
+		// the error may be wrapped if Options.WrapErrors is set
+		var noEntry *ErrNoEntry
+		if instance.fallback != nil && errors.As(err, &noEntry) {
+			return instance.fallback.Get(name)
+		}
+
+		return nil, err
+	}
+
+	if instance.options.TrackReads {
+		instance.recordRead(name)
+	}
+
+	return value, nil
+}
+
+// get implements Get without invoking the configured Middleware, for use by
+// operations which read entries internally.
+func (instance *Instance) get(name string) (interface{}, error) {
+	row := instance.preparedRow("SELECT {value}, {type} FROM {table} WHERE {name} = ? AND {visible}", instance.key(name))
 	var value string
-	var valueType uint
+	var valueType ValueType
 	err := row.Scan(&value, &valueType)
 
 	if err != nil {
@@ -189,7 +797,127 @@ func (instance *Instance) Get(name string) (interface{}, error) {
 		return nil, err
 	}
 
-	return fromBlobString(value, valueType)
+	return instance.decode(name, value, valueType)
+}
+
+// decode takes the name of an entry along with the blob string and ValueType
+// read from its row, and returns the decoded value after applying any codec
+// registered for the entry.
+func (instance *Instance) decode(name string, blob string, valueType ValueType) (interface{}, error) {
+	blob, err := instance.decodeBlob(name, blob)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromBlobString(blob, valueType)
+}
+
+// each calls fn with the name and decoded value of every metadata entry in
+// order of name. Iteration stops at the first error returned either while
+// reading entries or by fn.
+func (instance *Instance) each(fn func(name string, value interface{}) error) error {
+	return instance.iterate(true, fn)
+}
+
+// iterate implements each, only ordering entries by name if sorted is true.
+func (instance *Instance) iterate(sorted bool, fn func(name string, value interface{}) error) error {
+	scope, args := instance.scope()
+	query := "SELECT {name}, {value}, {type} FROM {table} WHERE " + scope
+	if sorted {
+		query += " ORDER BY {name}"
+	}
+
+	rows, err := instance.query(query+";", args...)
+	if err != nil {
+		return fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, blob string
+		var valueType ValueType
+		if err := rows.Scan(&name, &blob, &valueType); err != nil {
+			return fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+		}
+
+		name = instance.unkey(name)
+		value, err := instance.decode(name, blob, valueType)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(name, value); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetType returns the ValueType of the data stored in the requested entry, or
+// an ErrNoEntry if none exists. Each allowed Go type has a distinct ValueType,
+// so that e.g. an int and an int64 entry can be told apart.
+func (instance *Instance) GetType(name string) (ValueType, error) {
+	valueType, err := instance.getValueType(name)
+	return valueType, instance.wrap("GetType", name, err)
+}
+
+// Entries returns every metadata entry in order of name. Each value is decoded
+// as exactly the Go type with which it was stored. If the Instance was created
+// with Options.Descriptions, the Description field of each Entry is set.
+func (instance *Instance) Entries() ([]Entry, error) {
+	var descriptions map[string]string
+	if instance.options.Descriptions {
+		var err error
+		if descriptions, err = instance.descriptions(); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make([]Entry, 0)
+	err := instance.each(func(name string, value interface{}) error {
+		valueType, err := toValueType(value)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, Entry{Name: name, Value: value, Type: valueType, Description: descriptions[name]})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Lookup does the same as Get, but reports whether the entry exists rather
+// than returning an ErrNoEntry for a missing entry, in which case the value is
+// nil and found is false. Any other error, such as one while parsing the value,
+// is returned as by Get.
+func (instance *Instance) Lookup(name string) (value interface{}, found bool, err error) {
+	value, err = instance.Get(name)
+	if err != nil {
+		// the error may be wrapped if Options.WrapErrors is set
+		var noEntry *ErrNoEntry
+		if errors.As(err, &noEntry) {
+			return nil, false, nil
+		}
+
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+// MustLookup does the same as Lookup, but panics if an error is returned.
+func (instance *Instance) MustLookup(name string) (value interface{}, found bool) {
+	value, found, err := instance.Lookup(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return value, found
 }
 
 // MustGet does the same as Get, but panics if an error is returned.
@@ -204,44 +932,190 @@ func (instance *Instance) MustGet(name string) interface{} {
 // set implements the code shared between Set and ForceSet, using an additional
 // parameter to differentiate between the two.
 func (instance *Instance) set(name string, value interface{}, force bool) error {
+	_, err := instance.write(name, value, force)
+	return err
+}
+
+// write implements set, additionally reporting whether the entry was changed.
+// Entries which already hold an equal value of the same type are left as-is.
+func (instance *Instance) write(name string, value interface{}, force bool) (changed bool, err error) {
+	// recording the previous value and overwriting it must succeed together
+	_, serial := instance.db.(*serialConn)
+	if _, ok := instance.db.(beginner); (ok || serial) && instance.options.History {
+		err = instance.withTx(func(tx *Instance) (err error) {
+			changed, err = tx.write(name, value, force)
+			return err
+		})
+
+		return changed, err
+	}
+
+	if instance.options.CoerceNumbers {
+		if value, err = coerceNumber(value); err != nil {
+			return false, err
+		}
+	}
+
 	valueType, err := toValueType(value)
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	// if configured to do so, delete the entry rather than storing an empty string
+	if value == "" && instance.options.DeleteEmptyStrings {
+		if err := instance.remove(name); err != nil {
+			if _, ok := err.(*ErrNoEntry); !ok {
+				return false, err
+			}
+
+			return false, nil
+		}
+
+		return true, nil
 	}
 
-	currentType, err := instance.getValueType(name)
+	blob, err := toBlobString(value)
 	if err != nil {
-		// if error indicates that there is no entry by this name, insert one
-		if _, ok := err.(*ErrNoEntry); ok {
-			_, err = instance.DB.Exec(`INSERT INTO metadata (Name, Value, ValueType) VALUES (?, ?, ?);`, name, value, valueType)
+		return false, err
+	}
+
+	if blob, err = instance.encodeBlob(name, blob); err != nil {
+		return false, err
+	}
+
+	if instance.options.VerifyEncoding {
+		if decoded, err := instance.decode(name, blob, valueType); err != nil {
+			return false, &ErrRoundTrip{name, value, nil, err}
+		} else if !equal(decoded, value) {
+			return false, &ErrRoundTrip{name, value, decoded, nil}
+		}
+	}
+
+	if max := instance.options.MaxValueBytes; max > 0 && len(blob) > max {
+		return false, &ErrValueTooLarge{name, len(blob), max}
+	}
+
+	stored := instance.storedValue(name, value, blob)
+
+	row := instance.queryRow("SELECT {value}, {type} FROM {table} WHERE {name} = ? AND {visible}", instance.key(name))
+	var current string
+	var currentType ValueType
+	if err := row.Scan(&current, &currentType); err != nil {
+		// if no rows were selected, insert an entry
+		if err == sql.ErrNoRows {
+			if err := instance.validate(name, value); err != nil {
+				return false, err
+			}
+
+			if err := instance.purge(name); err != nil {
+				return false, fmt.Errorf("metadb: failed to insert entry for '%s':\n%s", name, err)
+			}
+
+			columns, values := "{name}, {value}, {type}", "?, ?, ?"
+			args := []interface{}{instance.key(name), stored, valueType}
+			if instance.options.Timestamps {
+				columns, values = columns+", {updated}", values+", ?"
+				args = append(args, time.Now().UnixNano())
+			}
+
+			_, err = instance.exec(`INSERT INTO {table} (`+columns+`) VALUES (`+values+`);`, args...)
 			if err != nil {
-				return fmt.Errorf("metadb: failed to insert entry for '%s':\n%s", name, err)
+				if isDuplicateKey(err) {
+					return false, &ErrDuplicateKey{name}
+				}
+
+				return false, fmt.Errorf("metadb: failed to insert entry for '%s':\n%s", name, err)
 			}
+
+			instance.changed(name)
+			return true, nil
 		}
 
-		return err // Otherwise, return the error
+		return false, err // Otherwise, return the error
+	}
+
+	if instance.isImmutable(name) {
+		return false, &ErrImmutable{name}
 	}
 
 	// if force is not true and valueType does not match currentType, return an error
 	if !force && valueType != currentType {
-		return fmt.Errorf("metadb: cannot change value for '%s' to one of a different type", name)
+		return false, fmt.Errorf("metadb: cannot change value for '%s' to one of a different type", name)
+	}
+
+	if err := instance.validate(name, value); err != nil {
+		return false, err
+	}
+
+	// if the entry already holds the same value, there is nothing to update. The
+	// stored value is compared once decoded, as codecs need not be deterministic
+	// and values may have been stored either natively or as blob strings.
+	if valueType == currentType {
+		if decoded, err := instance.decode(name, current, currentType); err == nil && equal(decoded, value) {
+			return false, nil
+		}
+	}
+
+	if instance.options.History {
+		if err := instance.record(name, current, currentType); err != nil {
+			return false, err
+		}
 	}
 
 	// Update entry
-	_, err = instance.DB.Exec(`UPDATE metadata SET Value = ? WHERE Name = ?;`, value, name)
+	assignments, args := "{value} = ?, {type} = ?", []interface{}{stored, valueType}
+	if instance.options.Timestamps {
+		assignments += ", {updated} = ?"
+		args = append(args, time.Now().UnixNano())
+	}
+
+	_, err = instance.exec(`UPDATE {table} SET `+assignments+` WHERE {name} = ?;`, append(args, instance.key(name))...)
 	if err != nil {
-		return fmt.Errorf("metadb: failed to update entry for '%s':\n%s", name, err)
+		return false, fmt.Errorf("metadb: failed to update entry for '%s':\n%s", name, err)
 	}
 
-	return nil
+	instance.changed(name)
+	return true, nil
 }
 
 // Set inserts or updates a metadata entry. If the type of the new value is not
-// one of bool, int, float64, or string, an error is returned. Or, if the entry
-// already exists and the data type of the new value is different than that of
-// the current, an error is also returned.
+// one of bool, int, float64, string, int64, []string, complex128,
+// time.Duration, *big.Int, or *big.Float, an error is returned.
+// Or, if the entry already exists and the data type of the new value is
+// different than that of the current, an error is also returned. Empty strings
+// are stored as-is unless the Instance was created with
+// Options.DeleteEmptyStrings, in which case setting an entry to the empty
+// string deletes it. If Options.MaxValueBytes is set and the encoded value is
+// larger, an ErrValueTooLarge is returned, and if a validator registered with
+// SetValidator rejects the value, its error is returned. If the entry already
+// holds an equal value of the same type, it is left as-is. If the entry is
+// created concurrently while being inserted, an ErrDuplicateKey is returned.
 func (instance *Instance) Set(name string, value interface{}) error {
-	return instance.set(name, value, false)
+	return instance.around("Set", name, func(held *Instance) error {
+		return held.set(name, value, false)
+	})
+}
+
+// SetChanged does the same as Set, but also reports whether the entry was
+// changed. As with Set, an entry which already holds an equal value of the same
+// type is not written to at all, in which case false is returned.
+func (instance *Instance) SetChanged(name string, value interface{}) (changed bool, err error) {
+	err = instance.around("SetChanged", name, func(held *Instance) (err error) {
+		changed, err = held.write(name, value, false)
+		return err
+	})
+
+	return changed, err
+}
+
+// MustSetChanged does the same as SetChanged, but panics if an error is
+// returned.
+func (instance *Instance) MustSetChanged(name string, value interface{}) bool {
+	if res, err := instance.SetChanged(name, value); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
 }
 
 // MustSet does the same as Set, but panics if an error is returned.
@@ -255,7 +1129,9 @@ func (instance *Instance) MustSet(name string, value interface{}) {
 // already exists and the data type of the new value is different than that of
 // the current.
 func (instance *Instance) ForceSet(name string, value interface{}) error {
-	return instance.set(name, value, true)
+	return instance.around("ForceSet", name, func(held *Instance) error {
+		return held.set(name, value, true)
+	})
 }
 
 // MustForceSet does the same as ForceSet, but panics if an error is returned.
@@ -267,16 +1143,48 @@ func (instance *Instance) MustForceSet(name string, value interface{}) {
 
 // Delete removes a metadata entry. If the entry does not exist it returns an
 // error. If the database or database driver does not support `RowsAffected`,
-// no error is returned even if the entry does not exist.
+// no error is returned even if the entry does not exist, unless the Instance
+// was created with Options.VerifyDelete. If the Instance was created with
+// Options.SoftDelete, the entry is only flagged as deleted, such that it may
+// be restored with Restore.
 func (instance *Instance) Delete(name string) error {
-	if res, err := instance.DB.Exec(`DELETE FROM metadata WHERE name = ?;`, name); err != nil {
-		panic(fmt.Errorf("metadb: failed to delete entry for '%s':\n%s", name, err))
-	} else if affected, err := res.RowsAffected(); err != nil {
-		return nil
-	} else if affected == 0 {
+	return instance.around("Delete", name, func(held *Instance) error {
+		return held.remove(name)
+	})
+}
+
+// remove implements Delete without invoking the configured Middleware, for use
+// by operations which delete entries internally.
+func (instance *Instance) remove(name string) error {
+	if instance.isImmutable(name) {
+		if exists, err := instance.exists(name); err != nil {
+			return fmt.Errorf("metadb: failed to delete entry for '%s':\n%s", name, err)
+		} else if !exists {
+			return &ErrNoEntry{name}
+		}
+
+		return &ErrImmutable{name}
+	}
+
+	if instance.options.VerifyDelete {
+		if exists, err := instance.exists(name); err != nil {
+			return fmt.Errorf("metadb: failed to delete entry for '%s':\n%s", name, err)
+		} else if !exists {
+			return &ErrNoEntry{name}
+		}
+	}
+
+	res, err := instance.exec(instance.deletion("{name} = ?"), instance.key(name))
+	if err != nil {
+		return fmt.Errorf("metadb: failed to delete entry for '%s':\n%s", name, err)
+	}
+
+	// if RowsAffected is unsupported, assume that the entry existed
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
 		return &ErrNoEntry{name}
 	}
 
+	instance.changed(name)
 	return nil
 }
 
@@ -1,11 +1,17 @@
 package metadb
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -78,11 +84,23 @@ func RunWithInstance(fn func(*Instance)) {
 	})
 }
 
+// RunWithOptions runs a closure passing it an Instance created with the
+// provided Options.
+func RunWithOptions(options Options, fn func(*Instance)) {
+	RunWithDB(func(db *sql.DB) {
+		if instance, err := NewInstanceWithOptions(db, options); err != nil {
+			panic(err)
+		} else {
+			fn(instance)
+		}
+	})
+}
+
 // EntryFixture contains the basic data required for a metadata entry.
 type EntryFixture struct {
 	Name      string
 	Value     interface{}
-	ValueType uint
+	ValueType ValueType
 }
 
 // InsertFixtures takes a list of EntryFixtures and inserts them into the
@@ -135,6 +153,350 @@ func TestNewInstance(t *testing.T) {
 	})
 }
 
+// TestNewInstanceWithRetry ensures that NewInstanceWithRetry gives up after the
+// requested number of attempts or once its context is cancelled.
+func TestNewInstanceWithRetry(t *testing.T) {
+	ctx := context.Background()
+	RunWithDB(func(db *sql.DB) {
+		instance, err := NewInstanceWithRetry(ctx, db, Options{}, 3, time.Millisecond)
+		if err != nil {
+			t.Fatal("NewInstanceWithRetry: got error:\n", err)
+		}
+
+		instance.MustSet("foo", "bar")
+	})
+
+	unavailable, err := sql.Open("sqlite3", "file:./missing/test.sqlite?mode=ro")
+	if err != nil {
+		t.Fatal("tests: failed to open database:\n", err)
+	}
+	defer unavailable.Close()
+
+	start := time.Now()
+	if _, err := NewInstanceWithRetry(ctx, unavailable, Options{}, 3, 10*time.Millisecond); err == nil {
+		t.Error("NewInstanceWithRetry: expected error with unavailable database")
+	} else if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("NewInstanceWithRetry: got error after '%s' expected at least '30ms' of delays", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := NewInstanceWithRetry(cancelled, unavailable, Options{}, 3, time.Hour); err != context.Canceled {
+		t.Errorf("NewInstanceWithRetry: got '%v' expected '%v'", err, context.Canceled)
+	}
+}
+
+// TestInitSchema ensures that the metadata table is only created once
+// InitSchema is called with Options.SkipInitSchema, and that InitSchema may be
+// called repeatedly.
+func TestInitSchema(t *testing.T) {
+	RunWithOptions(Options{SkipInitSchema: true}, func(instance *Instance) {
+		if err := instance.Set("foo", "bar"); err == nil {
+			t.Error("Instance.Set: expected error before InitSchema")
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := instance.InitSchema(); err != nil {
+				t.Fatal("Instance.InitSchema: got error:\n", err)
+			}
+		}
+
+		instance.MustSet("foo", "bar")
+	})
+}
+
+// failCreate wraps an executor, failing every statement creating a table as if
+// the user lacked the privilege to do so.
+type failCreate struct {
+	executor
+}
+
+// ExecContext does the same as that of the wrapped executor, but fails for
+// CREATE TABLE statements.
+func (db failCreate) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if strings.Contains(query, "CREATE TABLE") {
+		return nil, errors.New("tests: permission denied")
+	}
+
+	return db.executor.ExecContext(ctx, query, args...)
+}
+
+// TestTolerateCreateErrors ensures that errors while creating the metadata
+// table are only ignored when requested and a compatible table exists.
+func TestTolerateCreateErrors(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		options := Options{TolerateCreateErrors: true}
+		if _, err := newInstance(db, failCreate{db}, options); err == nil {
+			t.Error("NewInstance: expected error with missing table")
+		}
+
+		if _, err := NewInstance(db); err != nil {
+			t.Fatal("NewInstance: got error:\n", err)
+		}
+
+		if _, err := newInstance(db, failCreate{db}, Options{}); err == nil {
+			t.Error("NewInstance: expected error without Options.TolerateCreateErrors")
+		}
+
+		instance, err := newInstance(db, failCreate{db}, options)
+		if err != nil {
+			t.Fatal("NewInstance: got error with existing table:\n", err)
+		}
+
+		instance.MustSet("foo", "bar")
+	})
+}
+
+// TestErrNoEntryIs ensures that an ErrNoEntry matches sql.ErrNoRows, but not
+// other errors.
+func TestErrNoEntryIs(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		_, err := instance.Get("missing")
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Error("ErrNoEntry: expected errors.Is to match sql.ErrNoRows")
+		}
+
+		if errors.Is(err, sql.ErrTxDone) {
+			t.Error("ErrNoEntry: expected errors.Is not to match sql.ErrTxDone")
+		}
+
+		var noEntry *ErrNoEntry
+		if !errors.As(err, &noEntry) || noEntry.Name != "missing" {
+			t.Error("ErrNoEntry: expected errors.As to match *ErrNoEntry")
+		}
+	})
+}
+
+// TestEmptyStrings ensures that empty strings are stored as-is by default, and
+// that they delete the entry when Options.DeleteEmptyStrings is set.
+func TestEmptyStrings(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("foo", "")
+
+		if !instance.Exists("foo") {
+			t.Error("Instance.Set: expected entry with empty string to exist")
+		} else if value := instance.MustGet("foo"); value != "" {
+			t.Errorf("Instance.Get: got '%v' expected ''", value)
+		}
+	})
+
+	RunWithOptions(Options{DeleteEmptyStrings: true}, func(instance *Instance) {
+		instance.MustSet("foo", "bar")
+		instance.MustSet("foo", "")
+
+		if instance.Exists("foo") {
+			t.Error("Instance.Set: expected entry set to empty string to be deleted")
+		}
+
+		if err := instance.Set("foo", ""); err != nil {
+			t.Error("Instance.Set: got error setting missing entry to empty string:\n", err)
+		}
+	})
+}
+
+// TestMaxValueBytes ensures that values larger than Options.MaxValueBytes are
+// rejected without modifying the entry.
+func TestMaxValueBytes(t *testing.T) {
+	RunWithOptions(Options{MaxValueBytes: 8}, func(instance *Instance) {
+		instance.MustSet("foo", "12345678")
+
+		if err := instance.Set("foo", "123456789"); err == nil {
+			t.Error("Instance.Set: expected error with value exceeding MaxValueBytes")
+		} else if tooLarge, ok := err.(*ErrValueTooLarge); !ok {
+			t.Error("Instance.Set: expected error of type *ErrValueTooLarge")
+		} else if tooLarge.Size != 9 || tooLarge.Max != 8 {
+			t.Errorf("Instance.Set: got size '%d' and maximum '%d' expected '9' and '8'", tooLarge.Size, tooLarge.Max)
+		}
+
+		if value := instance.MustGet("foo"); value != "12345678" {
+			t.Errorf("Instance.Get: got '%v' expected '12345678' after failed write", value)
+		}
+
+		if err := instance.Set("bar", []string{"1234", "5678"}); err == nil {
+			t.Error("Instance.Set: expected error with encoded value exceeding MaxValueBytes")
+		}
+	})
+}
+
+// TestVerifyEncoding ensures that values which do not survive encoding are
+// rejected without being written, while every other value is written as usual.
+func TestVerifyEncoding(t *testing.T) {
+	RunWithOptions(Options{VerifyEncoding: true}, func(instance *Instance) {
+		for name, value := range map[string]interface{}{
+			"float":    0.1,
+			"nan":      math.NaN(),
+			"slice":    []string{"a", "b"},
+			"bigFloat": new(big.Float).SetPrec(200).SetFloat64(1.5),
+		} {
+			if err := instance.Set(name, value); err != nil {
+				t.Errorf("Instance.Set: got error for '%s':\n%s", name, err)
+			}
+		}
+
+		// a codec which fails to reverse its encoding corrupts the value
+		instance.SetValueCodec("corrupt", reverse, func(value string) (string, error) { return value, nil })
+		if err := instance.Set("corrupt", "abc"); err == nil {
+			t.Error("Instance.Set: expected error with value decoding differently")
+		} else if roundTrip, ok := err.(*ErrRoundTrip); !ok {
+			t.Errorf("Instance.Set: got '%v' expected ErrRoundTrip", err)
+		} else if roundTrip.Decoded != "cba" || roundTrip.Err != nil {
+			t.Errorf("Instance.Set: got '%+v' expected decoded value 'cba'", roundTrip)
+		}
+
+		instance.SetValueCodec("broken", reverse, func(string) (string, error) { return "", errors.New("broken") })
+		if err := instance.Set("broken", "abc"); err == nil {
+			t.Error("Instance.Set: expected error with value failing to decode")
+		} else if roundTrip, ok := err.(*ErrRoundTrip); !ok || roundTrip.Err == nil {
+			t.Errorf("Instance.Set: got '%v' expected ErrRoundTrip with decoding error", err)
+		}
+
+		for _, name := range []string{"corrupt", "broken"} {
+			if instance.Exists(name) {
+				t.Errorf("Instance.Set: expected '%s' not to be written", name)
+			}
+		}
+	})
+}
+
+// TestNativeTypes ensures that numeric values stored natively may be compared
+// in SQL, and that they are read identically to blob strings.
+func TestNativeTypes(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		instance, err := NewInstanceWithOptions(db, Options{NativeTypes: true})
+		if err != nil {
+			t.Fatal("NewInstanceWithOptions: got error:\n", err)
+		}
+
+		values := map[string]interface{}{
+			"small":  50,
+			"large":  150,
+			"int64":  int64(1) << 40,
+			"float":  0.1,
+			"bool":   true,
+			"string": "200",
+		}
+		for name, value := range values {
+			instance.MustSet(name, value)
+		}
+
+		var name, storage string
+		if err := db.QueryRow(`SELECT Name, typeof(Value) FROM metadata WHERE Value > 100 AND ValueType = 1;`).
+			Scan(&name, &storage); err != nil {
+			t.Error("tests: failed to compare values:\n", err)
+		} else if name != "large" || storage != "integer" {
+			t.Errorf("Instance.Set: got '%s' stored as '%s' expected 'large' stored as 'integer'", name, storage)
+		}
+
+		if names, err := instance.FindByValue(150); err != nil {
+			t.Error("Instance.FindByValue: got error:\n", err)
+		} else if len(names) != 1 || names[0] != "large" {
+			t.Errorf("Instance.FindByValue: got '%v' expected '[large]'", names)
+		}
+
+		if value := instance.MustToggle("bool"); value {
+			t.Error("Instance.Toggle: got 'true' expected 'false'")
+		}
+
+		instance.MustSet("lease", 7)
+		if deleted := instance.MustCompareAndDelete("lease", 7); !deleted {
+			t.Error("Instance.CompareAndDelete: got 'false' expected 'true'")
+		}
+
+		// values are read identically regardless of how they were stored
+		plain, err := NewInstance(db)
+		if err != nil {
+			t.Fatal("NewInstance: got error:\n", err)
+		}
+
+		values["bool"] = false
+		for _, instance := range []*Instance{instance, plain} {
+			for name, expected := range values {
+				if value := instance.MustGet(name); value != expected {
+					t.Errorf("Instance.Get: got '%v' (%T) expected '%v' (%T)", value, value, expected, expected)
+				}
+
+				if changed := instance.MustSetChanged(name, expected); changed {
+					t.Errorf("Instance.SetChanged: got 'true' expected 'false' for '%s'", name)
+				}
+			}
+		}
+	})
+}
+
+// TestSetChanged ensures that SetChanged reports whether an entry was changed,
+// and that writes of an equal value leave the entry as-is.
+func TestSetChanged(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		if changed, err := instance.SetChanged("foo", 1); err != nil {
+			t.Error("Instance.SetChanged: got error:\n", err)
+		} else if !changed {
+			t.Error("Instance.SetChanged: got 'false' expected 'true' for new entry")
+		}
+
+		if changed := instance.MustSetChanged("foo", 1); changed {
+			t.Error("Instance.MustSetChanged: got 'true' expected 'false' for equal value")
+		}
+
+		if changed := instance.MustSetChanged("foo", 2); !changed {
+			t.Error("Instance.MustSetChanged: got 'false' expected 'true' for different value")
+		}
+
+		if _, err := instance.SetChanged("foo", "2"); err == nil {
+			t.Error("Instance.SetChanged: expected error with new value of different type than existing")
+		}
+
+		// equal encodings of different types are not considered equal
+		instance.MustForceSet("foo", int64(2))
+		if valueType, _ := instance.GetType("foo"); valueType != TypeInt64 {
+			t.Errorf("Instance.ForceSet: got type '%s' expected 'int64'", valueType)
+		}
+
+		instance.SetValueCodec("encoded", reverse, reverse)
+		instance.MustSet("encoded", "abc")
+		if changed := instance.MustSetChanged("encoded", "abc"); changed {
+			t.Error("Instance.MustSetChanged: got 'true' expected 'false' for equal value with codec")
+		}
+	})
+}
+
+// TestCoerceNumbers ensures that Options.CoerceNumbers converts other numeric
+// types to the nearest allowed type, rejecting unsigned values which overflow.
+func TestCoerceNumbers(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		if err := instance.Set("strict", int32(5)); err == nil {
+			t.Error("Instance.Set: expected error with int32 value")
+		}
+	})
+
+	RunWithOptions(Options{CoerceNumbers: true}, func(instance *Instance) {
+		values := []struct {
+			value, expected interface{}
+		}{
+			{int8(-8), -8},
+			{int16(16), 16},
+			{int32(-32), -32},
+			{uint8(8), 8},
+			{uint16(16), 16},
+			{uint32(1) << 31, int64(1) << 31},
+			{uint(7), int64(7)},
+			{uint64(math.MaxInt64), int64(math.MaxInt64)},
+			{float32(0.5), 0.5},
+			{"string", "string"},
+		}
+
+		for _, entry := range values {
+			instance.MustForceSet("value", entry.value)
+			if value := instance.MustGet("value"); value != entry.expected {
+				t.Errorf("Instance.Get: got '%v' (%T) expected '%v' (%T)", value, value, entry.expected, entry.expected)
+			}
+		}
+
+		if err := instance.Set("value", uint64(math.MaxInt64)+1); err == nil {
+			t.Error("Instance.Set: expected error with overflowing uint64 value")
+		}
+	})
+}
+
 // TestExists ensures that Instance.Exists is accurate.
 func TestExists(t *testing.T) {
 	RunWithInstance(func(instance *Instance) {
@@ -155,7 +517,7 @@ func TestExists(t *testing.T) {
 // TestToValueType ensures that the correct type index is returned for each of
 // the allowed types.
 func TestToValueType(t *testing.T) {
-	testValid := func(value interface{}, expected uint) {
+	testValid := func(value interface{}, expected ValueType) {
 		if res, err := toValueType(value); err != nil {
 			t.Error("toValueType: got error:\n", err)
 		} else if res != expected {
@@ -167,10 +529,53 @@ func TestToValueType(t *testing.T) {
 	testValid(281, 1)
 	testValid(43.183, 2)
 	testValid("hello world!", 3)
-
-	if _, err := toValueType([]string{"disallowed", "type"}); err == nil {
+	testValid(int64(281), 4)
+	testValid([]string{"hello", "world!"}, 5)
+	testValid(complex(1.5, 2.5), 6)
+	testValid(30*time.Second, 7)
+	testValid(big.NewInt(281), 8)
+	testValid(big.NewFloat(43.183), 9)
+
+	if _, err := toValueType(map[string]string{"disallowed": "type"}); err == nil {
 		t.Error("toValueType: expected error with disallowed type")
 	}
+
+	if _, err := toValueType((*big.Int)(nil)); err == nil {
+		t.Error("toValueType: expected error with nil *big.Int")
+	}
+}
+
+// TestToBlobString ensures that each of the allowed types is encoded to a blob
+// string which decodes back to the original value.
+func TestToBlobString(t *testing.T) {
+	testValid := func(value interface{}, expected string) {
+		res, err := toBlobString(value)
+		if err != nil {
+			t.Error("toBlobString: got error:\n", err)
+			return
+		} else if res != expected {
+			t.Errorf("toBlobString: got '%s' expected '%s'", res, expected)
+		}
+
+		valueType, _ := toValueType(value)
+		if decoded, err := fromBlobString(res, valueType); err != nil {
+			t.Error("fromBlobString: got error:\n", err)
+		} else if decoded != value {
+			t.Errorf("fromBlobString: got '%v' expected '%v'", decoded, value)
+		}
+	}
+
+	testValid(true, "true")
+	testValid(-281, "-281")
+	testValid(0.1, "0.1")
+	testValid("hello world!", "hello world!")
+	testValid(int64(-1)<<62, "-4611686018427387904")
+	testValid(complex(1.5, -2.5), "(1.5-2.5i)")
+	testValid(90*time.Minute, "1h30m0s")
+
+	if _, err := toBlobString(map[string]string{"disallowed": "type"}); err == nil {
+		t.Error("toBlobString: expected error with disallowed type")
+	}
 }
 
 // TestFromBlobString ensures that the correct data is returned for a number
@@ -185,6 +590,12 @@ func TestFromBlobString(t *testing.T) {
 			{Name: "float", Value: 21.42, ValueType: 2},
 			{Name: "invalidFloat", Value: "21.48aje21", ValueType: 2},
 			{Name: "string", Value: "hello world!", ValueType: 3},
+			{Name: "complex", Value: "(1.5+2.5i)", ValueType: 6},
+			{Name: "invalidComplex", Value: "(1.5+i2.5)", ValueType: 6},
+			{Name: "duration", Value: "1m30s", ValueType: 7},
+			{Name: "invalidDuration", Value: "30", ValueType: 7},
+			{Name: "invalidBigInt", Value: "1.5", ValueType: 8},
+			{Name: "invalidBigFloat", Value: "precise:1.5", ValueType: 9},
 			{Name: "unknown", Value: "nothing", ValueType: 100},
 		})
 
@@ -211,14 +622,104 @@ func TestFromBlobString(t *testing.T) {
 		testFixture("int", 239)
 		testFixture("float", 21.42)
 		testFixture("string", "hello world!")
+		testFixture("complex", complex(1.5, 2.5))
+		testFixture("duration", 90*time.Second)
 
 		expectError("invalidBool", "invalid boolean blob string")
 		expectError("invalidInt", "invalid integer blob string")
 		expectError("invalidFloat", "invalid float blob string")
+		expectError("invalidComplex", "invalid complex blob string")
+		expectError("invalidDuration", "invalid duration blob string")
+		expectError("invalidBigInt", "invalid *big.Int blob string")
+		expectError("invalidBigFloat", "invalid *big.Float precision")
 		expectError("unknown", "invalid value type")
 	})
 }
 
+// TestIntBitSize ensures that ints are stored and parsed with a fixed width
+// regardless of platform, such that an int above 2^31 written on a 64-bit
+// platform is read back identically, or rejected on a 32-bit one.
+func TestIntBitSize(t *testing.T) {
+	const blob = "3000000000" // above 2^31
+	value, err := fromBlobString(blob, TypeInt)
+	if strconv.IntSize < intBitSize {
+		if parseErr, ok := err.(*ErrFailedToParse); !ok || !errors.Is(parseErr.Err, strconv.ErrRange) {
+			t.Errorf("fromBlobString: got error '%v' expected range error", err)
+		}
+
+		return
+	} else if err != nil {
+		t.Fatal("fromBlobString: got error:\n", err)
+	}
+
+	if res, err := toBlobString(value); err != nil {
+		t.Error("toBlobString: got error:\n", err)
+	} else if res != blob {
+		t.Errorf("toBlobString: got '%s' expected '%s'", res, blob)
+	}
+
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("large", value)
+		if res := instance.MustGetInt("large"); res != value {
+			t.Errorf("Instance.GetInt: got '%d' expected '%v'", res, value)
+		}
+
+		if _, err := fromBlobString("9223372036854775808", TypeInt); err == nil {
+			t.Error("fromBlobString: expected error with value above 64 bits")
+		}
+	})
+}
+
+// bigInt returns the *big.Int represented by a base 10 string.
+func bigInt(value string) *big.Int {
+	res, _ := new(big.Int).SetString(value, 10)
+	return res
+}
+
+// TestBigNumbers ensures that *big.Int and *big.Float values are stored and
+// read back exactly, including the precision of each *big.Float.
+func TestBigNumbers(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		integer := bigInt("-123456789012345678901234567890")
+		float, _, _ := big.ParseFloat("0.1000000000000000000000000000001", 10, 200, big.ToNearestEven)
+
+		instance.MustSet("integer", integer)
+		instance.MustSet("float", float)
+
+		if res, err := instance.GetBigInt("integer"); err != nil {
+			t.Error("Instance.GetBigInt: got error:\n", err)
+		} else if res.Cmp(integer) != 0 {
+			t.Errorf("Instance.GetBigInt: got '%v' expected '%v'", res, integer)
+		}
+
+		if res, err := instance.GetBigFloat("float"); err != nil {
+			t.Error("Instance.GetBigFloat: got error:\n", err)
+		} else if res.Cmp(float) != 0 || res.Prec() != 200 {
+			t.Errorf("Instance.GetBigFloat: got '%s' with precision %d expected '%s' with precision 200",
+				res.Text('g', -1), res.Prec(), float.Text('g', -1))
+		}
+
+		// an equal value is not written again
+		if changed := instance.MustSetChanged("integer", bigInt("-123456789012345678901234567890")); changed {
+			t.Error("Instance.SetChanged: expected equal *big.Int to be unchanged")
+		}
+
+		if _, err := instance.GetBigFloat("integer"); err == nil {
+			t.Error("Instance.GetBigFloat: expected error with *big.Int entry")
+		}
+
+		if err := instance.Set("nil", (*big.Float)(nil)); err == nil {
+			t.Error("Instance.Set: expected error with nil *big.Float")
+		}
+
+		view := instance.MustViewSnapshot()
+		view.MustGetBigInt("integer").SetInt64(0)
+		if res := view.MustGetBigInt("integer"); res.Cmp(integer) != 0 {
+			t.Errorf("View.GetBigInt: got '%v' expected '%v' after modifying a returned value", res, integer)
+		}
+	})
+}
+
 // TestGetValueType ensures that getValueType returns accurate data.
 func TestGetValueType(t *testing.T) {
 	RunWithInstance(func(instance *Instance) {
@@ -227,7 +728,7 @@ func TestGetValueType(t *testing.T) {
 			{Name: "bar", Value: "1011", ValueType: 1},
 		})
 
-		testValueType := func(name string, expected uint) {
+		testValueType := func(name string, expected ValueType) {
 			if res, err := instance.getValueType(name); err != nil {
 				t.Error("Instance.getValueType: got error:\n", err)
 			} else if res != expected {
@@ -289,7 +790,7 @@ func TestGetAndSet(t *testing.T) {
 			t.Error("Instance.MustGet: expected error of type *ErrNoEntry")
 		}
 
-		if err := instance.Set("foo", []string{"disallowed", "type"}); err == nil {
+		if err := instance.Set("foo", map[string]string{"disallowed": "type"}); err == nil {
 			t.Error("Instance.Set: expected error with new value of disallowed type")
 		}
 
@@ -307,13 +808,15 @@ func TestGetAndSet(t *testing.T) {
 
 		if err := instance.ForceSet("foo", 1873); err != nil {
 			t.Error("Instance.ForceSet: got error:\n", err)
+		} else if foo := instance.MustGet("foo"); foo != 1873 {
+			t.Errorf("Instance.ForceSet: got '%v' expected '1873'", foo)
 		}
 
 		if err := panicked(func() { instance.MustForceSet("foo", 1891) }); err != nil {
 			t.Error("Instance.MustForceSet: got panic:\n", err)
 		}
 
-		if err := panicked(func() { instance.MustForceSet("foo", []string{"disallowed", "type"}) }); err == nil {
+		if err := panicked(func() { instance.MustForceSet("foo", map[string]string{"disallowed": "type"}) }); err == nil {
 			t.Error("Instance.MustForceSet: expected panic with new value of disallowed type")
 		}
 	})
@@ -321,6 +824,71 @@ func TestGetAndSet(t *testing.T) {
 
 // TestDelete ensures that metadata entries inserted by means of a fixture are
 // properly deleted and that attempting to delete a non-existent entry results
+// TestLookup ensures that Lookup reports missing entries without an error,
+// while still returning errors other than ErrNoEntry.
+func TestLookup(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		if value, found, err := instance.Lookup("foo"); err != nil {
+			t.Error("Instance.Lookup: got error:\n", err)
+		} else if found || value != nil {
+			t.Errorf("Instance.Lookup: got '%v', '%t' expected '<nil>', 'false'", value, found)
+		}
+
+		instance.MustSet("foo", 12)
+		if value, found := instance.MustLookup("foo"); !found || value != 12 {
+			t.Errorf("Instance.MustLookup: got '%v', '%t' expected '12', 'true'", value, found)
+		}
+
+		if _, err := instance.DB.Exec(`INSERT INTO metadata (Name, Value, ValueType) VALUES ('bad', 'x', 1);`); err != nil {
+			t.Fatal("tests: failed to insert entry:\n", err)
+		}
+
+		if _, found, err := instance.Lookup("bad"); err == nil {
+			t.Error("Instance.Lookup: expected error with unparsable value")
+		} else if found {
+			t.Error("Instance.Lookup: got 'true' expected 'false' with error")
+		}
+	})
+}
+
+// unseen wraps an executor, hiding every entry from the query used by set to
+// read the current value, as if each were created concurrently afterward.
+type unseen struct {
+	executor
+}
+
+// QueryRowContext does the same as that of the wrapped executor, but selects
+// no rows for queries reading the current value of an entry.
+func (db unseen) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if strings.HasPrefix(query, "SELECT Value, ValueType") {
+		query = strings.Replace(query, "WHERE", "WHERE 0 AND", 1)
+	}
+
+	return db.executor.QueryRowContext(ctx, query, args...)
+}
+
+// TestErrDuplicateKey ensures that inserting an entry which was created
+// concurrently results in an ErrDuplicateKey.
+func TestErrDuplicateKey(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		instance, err := newInstance(db, unseen{db}, Options{})
+		if err != nil {
+			t.Fatal("NewInstance: got error:\n", err)
+		}
+
+		instance.MustSet("foo", "bar")
+		if err := instance.Set("foo", "baz"); err == nil {
+			t.Error("Instance.Set: expected error with concurrently created entry")
+		} else if dup, ok := err.(*ErrDuplicateKey); !ok || dup.Name != "foo" {
+			t.Errorf("Instance.Set: got '%v' expected ErrDuplicateKey for 'foo'", err)
+		}
+
+		if err := instance.Set("other", "baz"); err != nil {
+			t.Error("Instance.Set: got error:\n", err)
+		}
+	})
+}
+
 // in an ErrNoEntry.
 func TestDelete(t *testing.T) {
 	RunWithInstance(func(instance *Instance) {
@@ -348,3 +916,59 @@ func TestDelete(t *testing.T) {
 		}
 	})
 }
+
+// noRowsAffected wraps an executor, returning results which do not support
+// RowsAffected like those of some drivers.
+type noRowsAffected struct {
+	executor
+}
+
+// noRowsAffectedResult is an sql.Result which does not support RowsAffected.
+type noRowsAffectedResult struct {
+	sql.Result
+}
+
+// RowsAffected implements sql.Result, always returning an error.
+func (noRowsAffectedResult) RowsAffected() (int64, error) {
+	return 0, errors.New("tests: RowsAffected is not supported")
+}
+
+// ExecContext does the same as that of the wrapped executor, but returns a
+// result which does not support RowsAffected.
+func (db noRowsAffected) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := db.executor.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return noRowsAffectedResult{res}, nil
+}
+
+// TestVerifyDelete ensures that Delete returns an ErrNoEntry for missing
+// entries with drivers lacking RowsAffected only with Options.VerifyDelete.
+func TestVerifyDelete(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.db = noRowsAffected{instance.db}
+
+		if err := instance.Delete("foo"); err != nil {
+			t.Error("Instance.Delete: expected no error without RowsAffected:\n", err)
+		}
+	})
+
+	RunWithOptions(Options{VerifyDelete: true}, func(instance *Instance) {
+		instance.db = noRowsAffected{instance.db}
+		instance.MustSet("foo", "bar")
+
+		if err := instance.Delete("foo"); err != nil {
+			t.Error("Instance.Delete: got error:\n", err)
+		} else if instance.Exists("foo") {
+			t.Error("Instance.Delete: expected entry to be deleted")
+		}
+
+		if err := instance.Delete("foo"); err == nil {
+			t.Error("Instance.Delete: expected error with non-existent entry")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Error("Instance.Delete: expected error of type *ErrNoEntry")
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package metadb
+
+import "time"
+
+// Options configures the behaviour of an Instance created with
+// NewInstanceWithOptions. The zero value of Options provides the default
+// behaviour, as used by NewInstance.
+type Options struct {
+	// Table is the name of the table in which entries are stored. If empty,
+	// "metadata" is used.
+	Table string
+
+	// NameColumn, ValueColumn, and TypeColumn are the names of the columns in
+	// which the name, value, and value type of each entry are stored. If empty,
+	// "Name", "Value", and "ValueType" are used respectively. Like Table, they
+	// must consist only of letters, digits, and underscores, and must not
+	// begin with a digit.
+	NameColumn  string
+	ValueColumn string
+	TypeColumn  string
+
+	// Placeholders is the syntax of the bound parameters expected by the
+	// driver, such as PlaceholderDollar for PostgreSQL. By default, the ?
+	// placeholders understood by SQLite and MySQL are used.
+	Placeholders PlaceholderStyle
+
+	// SkipInitSchema prevents NewInstance from calling InitSchema, such that
+	// the metadata table is neither created nor checked until InitSchema is
+	// called explicitly, e.g. during a migration step.
+	SkipInitSchema bool
+
+	// TolerateCreateErrors causes NewInstance to succeed even if creating the
+	// metadata table fails, as long as the table already exists with a
+	// compatible schema. This allows an Instance to be created with a user
+	// lacking the privilege to create tables, or on a read-only database. By
+	// default, any such error is returned.
+	TolerateCreateErrors bool
+
+	// SkipIndexes prevents NewInstance from creating indexes on the value type
+	// column of the metadata table and, if Timestamps is set, on the column
+	// recording the time at which each entry was last written. By default,
+	// these indexes are created if they do not already exist, including for
+	// existing tables. Errors while creating them are ignored along with
+	// those while creating the table if TolerateCreateErrors is set.
+	SkipIndexes bool
+
+	// DeleteEmptyStrings causes an entry to be deleted when it is set to the
+	// empty string, rather than storing the empty string. By default, empty
+	// strings are stored as-is and remain distinguishable from missing entries
+	// through Exists.
+	DeleteEmptyStrings bool
+
+	// VerifyDelete causes Delete to check that an entry exists before deleting
+	// it, so that an ErrNoEntry is returned for missing entries even if the
+	// driver does not support RowsAffected. This costs an additional query
+	// per deletion, and is unnecessary with drivers such as SQLite.
+	VerifyDelete bool
+
+	// SoftDelete causes deleted entries to be flagged as such rather than
+	// being removed from the table, such that they may later be brought back
+	// with Restore, or removed for good with PurgeDeleted. Flagged entries are
+	// hidden from every other method, as if they did not exist, and are
+	// replaced by any entry later created by the same name. The flag is stored
+	// in a Deleted column, which is added to the metadata table if it is
+	// missing.
+	SoftDelete bool
+
+	// Descriptions allows each entry to carry a human-readable description,
+	// such as help text to display next to a setting, through SetDescription
+	// and GetDescription. Descriptions are also included by Entries. They are
+	// stored in a Description column, which is added to the metadata table if
+	// it is missing.
+	Descriptions bool
+
+	// EncryptionKey, if set, causes the value of every entry to be encrypted
+	// with AES-GCM before being written and decrypted after being read, such
+	// that values are protected at rest while the API is otherwise unchanged.
+	// It must be 16, 24, or 32 bytes long to select AES-128, AES-192, or
+	// AES-256. Names and value types are stored in the clear. Encryption is
+	// applied after any codec registered through SetValueCodec. Values stored
+	// in plaintext, such as those written before the key was set, cause an
+	// ErrNotEncrypted to be returned when read, and may be found with
+	// PlaintextEntries and encrypted with EncryptPlaintext. As stored values
+	// are opaque, EntriesSortedByValue cannot be used, while FindByValue must
+	// decrypt every entry.
+	EncryptionKey []byte
+
+	// CoerceNumbers causes values of the other Go numeric types to be
+	// accepted when setting entries, converting them to the nearest allowed
+	// type: int8, int16, int32, uint8, and uint16 to int, uint32, uint, uint64,
+	// and uintptr to int64, and float32 to float64. Unsigned values which
+	// overflow an int64 are rejected with an error. By default, such values
+	// are rejected as being of a disallowed type.
+	CoerceNumbers bool
+
+	// NativeTypes causes bool, int, float64, and int64 values to be passed to
+	// the database as-is rather than as blob strings. With SQLite, whose
+	// columns are dynamically typed, they are then stored as integers and
+	// reals, such that they may be compared and ordered numerically in SQL
+	// (e.g. WHERE Value > 100). Databases with statically typed columns
+	// convert them to the type of the value column instead. Values are read
+	// identically in either case, so the option may be enabled for an existing
+	// table, in which case entries are only converted once they are next
+	// written. Entries with a codec are always stored as blob strings.
+	NativeTypes bool
+
+	// Separator separates the components of hierarchical entry names, such as
+	// "server.http.port", as used by Tree. If empty, "." is used.
+	Separator string
+
+	// MaxValueBytes limits the size in bytes of the value of each entry as
+	// stored in the database, after it has been encoded along with any codec.
+	// Writes of larger values fail with an ErrValueTooLarge. If zero, the size
+	// of values is unlimited.
+	MaxValueBytes int
+
+	// VerifyEncoding causes every value to be decoded again after it has been
+	// encoded for storage, along with any codec or encryption, and compared
+	// with the value being written before anything is written. If it cannot
+	// be decoded or decodes to a different value, the write fails with an
+	// ErrRoundTrip and nothing is written. This catches encoding bugs as they
+	// occur rather than once the value is read, at the cost of decoding every
+	// value written, and is intended for testing and staging environments.
+	VerifyEncoding bool
+
+	// Timestamps causes the time at which each entry was last written to be
+	// recorded, such that entries may be listed by it through RecentlyChanged
+	// and OldestUnchanged. The time is stored in an UpdatedAt column, which is
+	// added to the metadata table if it is missing. Entries written before the
+	// column was added are treated as never having been written.
+	Timestamps bool
+
+	// TrackReads causes the time at which each entry was last read by Get to
+	// be recorded, such that entries which are never consulted may be found
+	// through LeastRecentlyRead. As this costs an additional write per read,
+	// it is intended to be enabled temporarily, e.g. while auditing which
+	// entries are in use. The time is stored in a LastReadAt column, which is
+	// added to the metadata table if it is missing. Failing to record a read
+	// does not cause Get to fail.
+	TrackReads bool
+
+	// History causes the previous value of an entry to be recorded whenever it
+	// is overwritten, such that it may later be retrieved through History.
+	// Previous values are stored in a separate table named after Table with
+	// the suffix "_history", which is created if it does not exist.
+	History bool
+
+	// MaxHistoryPerKey limits the number of previous values recorded for each
+	// entry when History is set, discarding the oldest ones first. If zero,
+	// every previous value is kept.
+	MaxHistoryPerKey int
+
+	// Timeout bounds the time which each statement executed by the Instance,
+	// or each transaction as a whole, may take. This applies equally to the
+	// Must variants of each method, which panic once the timeout expires
+	// rather than blocking indefinitely (e.g. while waiting for a connection).
+	// Note that drivers may not observe cancellation at every point, such as
+	// while SQLite waits on a locked database for its busy timeout. If zero,
+	// statements are only bounded by the context of the Instance.
+	Timeout time.Duration
+
+	// WrapErrors causes every error returned by an operation on a single
+	// entry, such as Get, Set, Delete, Toggle, Pop, Rename (named by its old
+	// name), or the typed getters, including through their Must variants, to
+	// be wrapped in an ErrOperation naming the operation and entry (e.g.
+	// metadb Set "server.port": ...), which helps trace errors passed up
+	// through several layers. Errors returned by operations on several entries
+	// at once, such as RenameMany or Entries, are not wrapped. The original
+	// errors may still be matched with errors.Is and errors.As, but no longer
+	// with a type assertion. By default, errors are returned as-is.
+	WrapErrors bool
+
+	// Middleware, if set, wraps every call to Get, Set, SetChanged, ForceSet,
+	// and Delete, including those made through their Must variants and
+	// through the typed getters. It may be used for instrumentation such as
+	// logging, metrics, or tracing.
+	Middleware Middleware
+}
+
+// Middleware wraps an operation performed by an Instance. It is passed the name
+// of the operation (e.g. "Get") and the full name of the entry to which it
+// applies, including any prefix given by WithPrefix, and must call next
+// exactly once, returning the error which it returns.
+type Middleware func(op, name string, next func() error) error
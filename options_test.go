@@ -0,0 +1,41 @@
+package metadb
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// TestMiddleware ensures that the configured Middleware wraps each operation,
+// including those performed through Must variants, exactly once.
+func TestMiddleware(t *testing.T) {
+	var calls []string
+	middleware := func(op, name string, next func() error) error {
+		err := next()
+		calls = append(calls, fmt.Sprintf("%s %s %t", op, name, err == nil))
+		return err
+	}
+
+	RunWithOptions(Options{Middleware: middleware}, func(instance *Instance) {
+		instance.MustSet("foo", 1)
+		instance.MustGet("foo")
+		instance.GetInt("bar")
+		instance.ForceSet("foo", "baz")
+		instance.GetSet("foo", "qux")
+		instance.Delete("foo")
+		panicked(func() { instance.MustDelete("foo") })
+
+		expected := []string{
+			"Set foo true",
+			"Get foo true",
+			"Get bar false",
+			"ForceSet foo true",
+			"Delete foo true",
+			"Delete foo false",
+		}
+
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Middleware: got calls '%v' expected '%v'", calls, expected)
+		}
+	})
+}
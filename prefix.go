@@ -0,0 +1,207 @@
+package metadb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// WithPrefix returns a copy of the Instance scoped to the entries whose names
+// begin with prefix. Every method of the returned Instance operates only
+// within that scope: names passed to it are relative to the prefix, names
+// returned by it have the prefix removed, and methods which operate on all
+// entries, such as Keys, Count, Clear, and Entries, only see entries within
+// the scope. Calling WithPrefix on a scoped Instance nests the scopes, such
+// that
+//
+//	instance.WithPrefix("server.").WithPrefix("http.").Get("port")
+//
+// reads the entry named "server.http.port". The returned Instance shares its
+// configuration with the original.
+func (instance *Instance) WithPrefix(prefix string) *Instance {
+	scoped := *instance
+	scoped.prefix = instance.prefix + prefix
+	if instance.fallback != nil {
+		scoped.fallback = instance.fallback.WithPrefix(prefix)
+	}
+
+	return &scoped
+}
+
+// key returns the full name of the named entry within the scope of the
+// Instance.
+func (instance *Instance) key(name string) string {
+	return instance.prefix + name
+}
+
+// unkey returns the name relative to the scope of the Instance of an entry
+// read from the database by its full name.
+func (instance *Instance) unkey(key string) string {
+	return key[len(instance.prefix):]
+}
+
+// scope returns an SQL condition matching only the entries within the scope of
+// the Instance which have not been soft deleted, along with its arguments.
+func (instance *Instance) scope() (string, []interface{}) {
+	condition, args := instance.prefixed()
+	return condition + " AND {visible}", args
+}
+
+// prefixed returns an SQL condition matching every entry within the scope of
+// the Instance, including those which have been soft deleted, along with its
+// arguments. Names are matched by LIKE against the prefix followed by '%',
+// with any '%', '_', and '\' within the prefix escaped, such that the Name
+// index may be used where the database supports it. As LIKE is
+// case-insensitive with some databases, including SQLite, the leading
+// characters of each name are additionally compared with the prefix. The
+// escape character is passed as an argument, as the literal '\' is itself an
+// escape sequence with MySQL.
+func (instance *Instance) prefixed() (string, []interface{}) {
+	if instance.prefix == "" {
+		return "1 = 1", nil
+	}
+
+	return "{name} LIKE ? ESCAPE ? AND substr({name}, 1, ?) = ?", []interface{}{
+		likeEscaper.Replace(instance.prefix) + "%", `\`, utf8.RuneCountInString(instance.prefix), instance.prefix,
+	}
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns with '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Keys returns the names of all entries in order of name.
+func (instance *Instance) Keys() ([]string, error) {
+	scope, args := instance.scope()
+	rows, err := instance.query("SELECT {name} FROM {table} WHERE "+scope+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entry names:\n%s", err)
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+		}
+
+		names = append(names, instance.unkey(name))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entry names:\n%s", err)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// MustKeys does the same as Keys, but panics if an error is returned.
+func (instance *Instance) MustKeys() []string {
+	if res, err := instance.Keys(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// Count returns the number of entries.
+func (instance *Instance) Count() (int, error) {
+	scope, args := instance.scope()
+	row := instance.queryRow("SELECT COUNT(*) FROM {table} WHERE "+scope+";", args...)
+	var count int
+	if err := row.Scan(&count); err != nil {
+		return 0, fmt.Errorf("metadb: failed to count entries:\n%s", err)
+	}
+
+	return count, nil
+}
+
+// MustCount does the same as Count, but panics if an error is returned.
+func (instance *Instance) MustCount() int {
+	if res, err := instance.Count(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// CountWithPrefix returns the number of entries whose names begin with prefix.
+// The prefix is matched literally, such that characters like '%' and '_' are
+// not treated as wildcards. It is equivalent to WithPrefix(prefix).Count().
+func (instance *Instance) CountWithPrefix(prefix string) (int, error) {
+	return instance.WithPrefix(prefix).Count()
+}
+
+// MustCountWithPrefix does the same as CountWithPrefix, but panics if an error
+// is returned.
+func (instance *Instance) MustCountWithPrefix(prefix string) int {
+	if res, err := instance.CountWithPrefix(prefix); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// IsEmpty returns true if there are no entries. Unlike Count, it stops at the
+// first entry found.
+func (instance *Instance) IsEmpty() (bool, error) {
+	scope, args := instance.scope()
+	row := instance.queryRow("SELECT EXISTS(SELECT 1 FROM {table} WHERE "+scope+");", args...)
+	var exists bool
+	if err := row.Scan(&exists); err != nil {
+		return false, fmt.Errorf("metadb: failed to check for entries:\n%s", err)
+	}
+
+	return !exists, nil
+}
+
+// MustIsEmpty does the same as IsEmpty, but panics if an error is returned.
+func (instance *Instance) MustIsEmpty() bool {
+	if res, err := instance.IsEmpty(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// Clear deletes every entry, returning the names of the deleted entries in
+// order of name. Variables bound to the entries are left as-is. If any entry
+// is immutable, an ErrImmutable is returned and nothing is deleted.
+func (instance *Instance) Clear() ([]string, error) {
+	var names []string
+	err := instance.withTx(func(tx *Instance) (err error) {
+		if names, err = tx.Keys(); err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if tx.isImmutable(name) {
+				return &ErrImmutable{name}
+			}
+		}
+
+		scope, args := tx.scope()
+		if _, err := tx.exec(tx.deletion(scope), args...); err != nil {
+			return fmt.Errorf("metadb: failed to clear entries:\n%s", err)
+		}
+
+		tx.changed(names...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// MustClear does the same as Clear, but panics if an error is returned.
+func (instance *Instance) MustClear() []string {
+	if res, err := instance.Clear(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
@@ -0,0 +1,157 @@
+package metadb
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestWithPrefix ensures that a scoped Instance only operates on entries within
+// its prefix, and that nested scopes compose.
+func TestWithPrefix(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("server.host", "localhost")
+		instance.MustSet("server.http.port", 8080)
+		instance.MustSet("server.http.tls", true)
+		instance.MustSet("SERVER.host", "uppercase")
+		instance.MustSet("server_host", "wildcard")
+		instance.MustSet("client.host", "remote")
+
+		server := instance.WithPrefix("server.")
+		if res, err := server.Get("host"); err != nil {
+			t.Error("Instance.Get: got error:\n", err)
+		} else if res != "localhost" {
+			t.Errorf("Instance.Get: got '%v' expected 'localhost'", res)
+		}
+
+		if keys := server.MustKeys(); !reflect.DeepEqual(keys, []string{"host", "http.port", "http.tls"}) {
+			t.Errorf("Instance.Keys: got '%v' expected '[host http.port http.tls]'", keys)
+		}
+
+		if count := server.MustCount(); count != 3 {
+			t.Errorf("Instance.Count: got '%d' expected '3'", count)
+		}
+
+		if count := instance.MustCount(); count != 6 {
+			t.Errorf("Instance.Count: got '%d' expected '6'", count)
+		}
+
+		http := server.WithPrefix("http.")
+		http.MustSet("port", 9090)
+		if res := instance.MustGet("server.http.port"); res != 9090 {
+			t.Errorf("Instance.Set: got '%v' expected '9090'", res)
+		}
+
+		entries, err := http.Entries()
+		if err != nil {
+			t.Fatal("Instance.Entries: got error:\n", err)
+		}
+
+		expected := []Entry{{Name: "port", Value: 9090, Type: TypeInt}, {Name: "tls", Value: true, Type: TypeBool}}
+		if !reflect.DeepEqual(entries, expected) {
+			t.Errorf("Instance.Entries: got '%v' expected '%v'", entries, expected)
+		}
+
+		if names, err := server.FindByValue("localhost"); err != nil {
+			t.Error("Instance.FindByValue: got error:\n", err)
+		} else if !reflect.DeepEqual(names, []string{"host"}) {
+			t.Errorf("Instance.FindByValue: got '%v' expected '[host]'", names)
+		}
+
+		if _, err := server.Get("server.host"); err == nil {
+			t.Error("Instance.Get: expected error with name outside of scope")
+		}
+
+		deleted, err := http.Clear()
+		if err != nil {
+			t.Fatal("Instance.Clear: got error:\n", err)
+		}
+
+		if !reflect.DeepEqual(deleted, []string{"port", "tls"}) {
+			t.Errorf("Instance.Clear: got '%v' expected '[port tls]'", deleted)
+		}
+
+		if keys := instance.MustKeys(); !reflect.DeepEqual(keys,
+			[]string{"SERVER.host", "client.host", "server.host", "server_host"}) {
+			t.Errorf("Instance.Keys: got '%v' expected only the cleared scope to be deleted", keys)
+		}
+	})
+}
+
+// TestWithPrefixRegistry ensures that codecs, defaults, and bindings registered
+// through a scoped Instance apply to the full name of the entry.
+func TestWithPrefixRegistry(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		scoped := instance.WithPrefix("scoped.")
+		scoped.SetValueCodec("secret", reverse, reverse)
+		scoped.MustSet("secret", "value")
+		if blob, err := instance.getBlob("scoped.secret"); err != nil {
+			t.Error("Instance.getBlob: got error:\n", err)
+		} else if blob != "eulav" {
+			t.Errorf("Instance.SetValueCodec: got stored '%s' expected 'eulav'", blob)
+		}
+
+		if err := scoped.RegisterDefault("limit", 10); err != nil {
+			t.Fatal("Instance.RegisterDefault: got error:\n", err)
+		}
+
+		scoped.MustReset("limit")
+		if res := instance.MustGet("scoped.limit"); res != 10 {
+			t.Errorf("Instance.Reset: got '%v' expected '10'", res)
+		}
+
+		var limit int
+		scoped.MustBind("limit", &limit)
+		instance.MustSet("scoped.limit", 20)
+		if limit != 20 {
+			t.Errorf("Instance.Bind: got '%d' expected '20'", limit)
+		}
+
+		scoped.MustRename("limit", "max")
+		if res := instance.MustGet("scoped.max"); res != 20 {
+			t.Errorf("Instance.Rename: got '%v' expected '20'", res)
+		}
+	})
+}
+
+// TestCountWithPrefix ensures that prefixes are matched literally, including
+// characters which are wildcards in LIKE patterns.
+func TestCountWithPrefix(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		for _, name := range []string{"100%.a", "100%.b", "100x.c", "a_b", "axb", "A_b", `c\d`, "cd"} {
+			instance.MustSet(name, true)
+		}
+
+		for prefix, expected := range map[string]int{"100%.": 2, "a_": 1, `c\`: 1, "": 8, "none": 0, "é": 0} {
+			if count, err := instance.CountWithPrefix(prefix); err != nil {
+				t.Error("Instance.CountWithPrefix: got error:\n", err)
+			} else if count != expected {
+				t.Errorf("Instance.CountWithPrefix: got '%d' expected '%d' for '%s'", count, expected, prefix)
+			}
+		}
+
+		if count := instance.MustCountWithPrefix("a"); count != 2 {
+			t.Errorf("Instance.MustCountWithPrefix: got '%d' expected '2'", count)
+		}
+	})
+}
+
+// TestIsEmpty ensures that IsEmpty reports whether any entry exists within the
+// scope of the Instance.
+func TestIsEmpty(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		if empty, err := instance.IsEmpty(); err != nil {
+			t.Error("Instance.IsEmpty: got error:\n", err)
+		} else if !empty {
+			t.Error("Instance.IsEmpty: got 'false' expected 'true'")
+		}
+
+		instance.MustSet("server.host", "localhost")
+		if instance.MustIsEmpty() {
+			t.Error("Instance.MustIsEmpty: got 'true' expected 'false'")
+		}
+
+		if !instance.WithPrefix("client.").MustIsEmpty() {
+			t.Error("Instance.MustIsEmpty: got 'false' expected 'true' for empty scope")
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package metadb
+
+import (
+	"fmt"
+	"time"
+)
+
+// recordRead records the current time as that at which the named entry was
+// last read. Errors are ignored, as failing to record a read must not cause the
+// read itself to fail.
+func (instance *Instance) recordRead(name string) {
+	instance.exec("UPDATE {table} SET {read} = ? WHERE {name} = ?;", time.Now().UnixNano(), instance.key(name))
+}
+
+// LeastRecentlyRead returns at most limit entries, least recently read by Get
+// first, with their LastReadAt field set. Entries which have not been read
+// since reads were first tracked have a zero LastReadAt and are listed first.
+// If the Instance was not created with Options.TrackReads, an error is
+// returned.
+func (instance *Instance) LeastRecentlyRead(limit int) ([]Entry, error) {
+	if !instance.options.TrackReads {
+		return nil, fmt.Errorf("metadb: read tracking is not enabled")
+	}
+
+	return instance.byTime(instance.schema.read, "ASC", limit)
+}
+
+// MustLeastRecentlyRead does the same as LeastRecentlyRead, but panics if an
+// error is returned.
+func (instance *Instance) MustLeastRecentlyRead(limit int) []Entry {
+	if res, err := instance.LeastRecentlyRead(limit); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
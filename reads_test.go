@@ -0,0 +1,55 @@
+package metadb
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestTrackReads ensures that an existing table is migrated, and that entries
+// are listed by the time at which they were last read.
+func TestTrackReads(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		legacy, err := NewInstance(db)
+		if err != nil {
+			t.Fatal("NewInstance: got error:\n", err)
+		}
+
+		for _, name := range []string{"a", "b", "c", "d"} {
+			legacy.MustSet(name, name)
+		}
+
+		if _, err := legacy.LeastRecentlyRead(1); err == nil {
+			t.Error("Instance.LeastRecentlyRead: expected error with read tracking disabled")
+		}
+
+		instance, err := NewInstanceWithOptions(db, Options{TrackReads: true})
+		if err != nil {
+			t.Fatal("NewInstanceWithOptions: got error while adding read times:\n", err)
+		}
+
+		before := time.Now()
+		for _, name := range []string{"c", "a", "d"} {
+			instance.MustGet(name)
+			time.Sleep(time.Millisecond)
+		}
+		instance.MustGetString("c")
+		legacy.MustGet("b") // not tracked
+
+		entries, err := instance.LeastRecentlyRead(3)
+		if err != nil {
+			t.Fatal("Instance.LeastRecentlyRead: got error:\n", err)
+		}
+
+		if names := entryNames(entries); !reflect.DeepEqual(names, []string{"b", "a", "d"}) {
+			t.Errorf("Instance.LeastRecentlyRead: got '%v' expected '[b a d]'", names)
+		}
+
+		if !entries[0].LastReadAt.IsZero() {
+			t.Errorf("Instance.LeastRecentlyRead: got '%s' expected zero time for unread entry", entries[0].LastReadAt)
+		} else if entries[1].LastReadAt.Before(before) || !entries[1].UpdatedAt.IsZero() {
+			t.Errorf("Instance.LeastRecentlyRead: got unexpected times for '%s'", entries[1].Name)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package metadb
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ErrEntryExists is returned when an operation would overwrite an entry which
+// already exists.
+type ErrEntryExists struct {
+	Name string
+}
+
+// Error implements the error interface for ErrEntryExists.
+func (err *ErrEntryExists) Error() string {
+	return fmt.Sprintf("metadb: entry for '%s' already exists", err.Name)
+}
+
+// getBlob returns the raw blob string stored in the requested entry, or an
+// ErrNoEntry if none exists.
+func (instance *Instance) getBlob(name string) (string, error) {
+	row := instance.queryRow("SELECT {value} FROM {table} WHERE {name} = ? AND {visible};", instance.key(name))
+	var blob string
+	if err := row.Scan(&blob); err != nil {
+		// if no rows were selected, return ErrNoEntry
+		if err == sql.ErrNoRows {
+			return "", &ErrNoEntry{name}
+		}
+
+		return "", err
+	}
+
+	return blob, nil
+}
+
+// RenameMany renames every entry named by a key of mapping to the
+// corresponding value within a single transaction. If any entry does not
+// exist, an ErrNoEntry is returned, and if any new name is already taken by an
+// entry which is not itself being renamed, or is the target of more than one
+// rename, an ErrEntryExists is returned. If any entry is immutable, an
+// ErrImmutable is returned. In any such case no entry is renamed.
+// Renames may be chained or swap names (e.g. a -> b and b -> a), as entries
+// are first moved to temporary names before being given their new ones.
+func (instance *Instance) RenameMany(mapping map[string]string) error {
+	return instance.withTx(func(tx *Instance) error {
+		targets := make(map[string]bool, len(mapping))
+		blobs := make(map[string]string, len(mapping))
+		for oldName, newName := range mapping {
+			if targets[newName] {
+				return &ErrEntryExists{newName}
+			}
+			targets[newName] = true
+
+			blob, err := tx.getBlob(oldName)
+			if err != nil {
+				return err
+			}
+
+			if tx.isImmutable(oldName) {
+				return &ErrImmutable{oldName}
+			}
+
+			if _, renamed := mapping[newName]; !renamed {
+				if exists, err := tx.exists(newName); err != nil {
+					return err
+				} else if exists {
+					return &ErrEntryExists{newName}
+				}
+			}
+
+			// re-encode the value in case different codecs apply to each name
+			if blob, err = tx.decodeBlob(oldName, blob); err != nil {
+				return err
+			}
+
+			if blob, err = tx.encodeBlob(newName, blob); err != nil {
+				return err
+			}
+
+			blobs[oldName] = blob
+		}
+
+		// soft deleted entries by the new names would otherwise conflict
+		for _, newName := range mapping {
+			if err := tx.purge(newName); err != nil {
+				return fmt.Errorf("metadb: failed to rename entry to '%s':\n%s", newName, err)
+			}
+		}
+
+		// move every entry to a temporary name so that no rename conflicts with
+		// an entry which has yet to be renamed itself
+		i := 0
+		temporary := make(map[string]string, len(mapping))
+		for oldName := range mapping {
+			temporary[oldName] = fmt.Sprintf("\x00metadb-rename-%d", i)
+			i++
+
+			if _, err := tx.exec(`UPDATE {table} SET {name} = ? WHERE {name} = ?;`,
+				tx.key(temporary[oldName]), tx.key(oldName)); err != nil {
+				return fmt.Errorf("metadb: failed to rename entry for '%s':\n%s", oldName, err)
+			}
+		}
+
+		for oldName, newName := range mapping {
+			if _, err := tx.exec(`UPDATE {table} SET {name} = ?, {value} = ? WHERE {name} = ?;`,
+				tx.key(newName), blobs[oldName], tx.key(temporary[oldName])); err != nil {
+				return fmt.Errorf("metadb: failed to rename entry for '%s':\n%s", oldName, err)
+			}
+
+			tx.changed(oldName, newName)
+		}
+
+		return nil
+	})
+}
+
+// MustRenameMany does the same as RenameMany, but panics if an error is
+// returned.
+func (instance *Instance) MustRenameMany(mapping map[string]string) {
+	if err := instance.RenameMany(mapping); err != nil {
+		panic(err)
+	}
+}
+
+// Rename renames a single entry. If the entry does not exist, an ErrNoEntry is
+// returned, and if an entry by the new name already exists, an ErrEntryExists
+// is returned.
+func (instance *Instance) Rename(oldName, newName string) error {
+	return instance.wrap("Rename", oldName, instance.RenameMany(map[string]string{oldName: newName}))
+}
+
+// MustRename does the same as Rename, but panics if an error is returned.
+func (instance *Instance) MustRename(oldName, newName string) {
+	if err := instance.Rename(oldName, newName); err != nil {
+		panic(err)
+	}
+}
+
+// Move copies the value and type of the entry src to dst, overwriting any
+// entry by that name regardless of its type, and then deletes src, all within
+// a single transaction. If src does not exist, an ErrNoEntry is returned, and
+// if either entry is immutable, an ErrImmutable is returned. In any such case
+// neither entry is modified. Moving an entry to its own name has no effect.
+func (instance *Instance) Move(src, dst string) error {
+	return instance.wrap("Move", src, instance.withTx(func(tx *Instance) error {
+		value, err := tx.get(src)
+		if err != nil {
+			return err
+		}
+
+		if src == dst {
+			return nil
+		}
+
+		if err := tx.set(dst, value, true); err != nil {
+			return err
+		}
+
+		return tx.remove(src)
+	}))
+}
+
+// MustMove does the same as Move, but panics if an error is returned.
+func (instance *Instance) MustMove(src, dst string) {
+	if err := instance.Move(src, dst); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,129 @@
+package metadb
+
+import "testing"
+
+// TestRenameMany ensures that entries are renamed, including when renames are
+// chained or swapped, and that invalid mappings leave every entry untouched.
+func TestRenameMany(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		InsertFixtures(instance, []EntryFixture{
+			{Name: "a", Value: "1", ValueType: 1},
+			{Name: "b", Value: "2", ValueType: 1},
+			{Name: "c", Value: "3", ValueType: 1},
+			{Name: "x", Value: "hello", ValueType: 3},
+		})
+
+		checkValues := func(expected map[string]interface{}) {
+			fixtures := GetFixtures(instance)
+			if len(fixtures) != len(expected) {
+				t.Errorf("Instance.RenameMany: got %d entries expected %d", len(fixtures), len(expected))
+			}
+
+			for name, value := range expected {
+				if res, err := instance.Get(name); err != nil {
+					t.Errorf("Instance.RenameMany: got error reading '%s':\n%s", name, err)
+				} else if res != value {
+					t.Errorf("Instance.RenameMany: got '%v' expected '%v' for '%s'", res, value, name)
+				}
+			}
+		}
+
+		// chained renames
+		if err := instance.RenameMany(map[string]string{"a": "b", "b": "c", "c": "d"}); err != nil {
+			t.Fatal("Instance.RenameMany: got error:\n", err)
+		}
+		checkValues(map[string]interface{}{"b": 1, "c": 2, "d": 3, "x": "hello"})
+
+		// swapped names
+		if err := instance.RenameMany(map[string]string{"b": "c", "c": "b"}); err != nil {
+			t.Fatal("Instance.RenameMany: got error:\n", err)
+		}
+		checkValues(map[string]interface{}{"b": 2, "c": 1, "d": 3, "x": "hello"})
+
+		if err := instance.RenameMany(map[string]string{"b": "y", "missing": "z"}); err == nil {
+			t.Error("Instance.RenameMany: expected error with non-existent entry")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Error("Instance.RenameMany: expected error of type *ErrNoEntry")
+		}
+
+		if err := instance.RenameMany(map[string]string{"b": "y", "c": "x"}); err == nil {
+			t.Error("Instance.RenameMany: expected error with existing destination")
+		} else if _, ok := err.(*ErrEntryExists); !ok {
+			t.Error("Instance.RenameMany: expected error of type *ErrEntryExists")
+		}
+
+		if err := instance.RenameMany(map[string]string{"b": "y", "c": "y"}); err == nil {
+			t.Error("Instance.RenameMany: expected error with duplicate destination")
+		}
+		checkValues(map[string]interface{}{"b": 2, "c": 1, "d": 3, "x": "hello"})
+
+		if err := instance.Rename("x", "greeting"); err != nil {
+			t.Error("Instance.Rename: got error:\n", err)
+		}
+
+		if err := panicked(func() { instance.MustRename("greeting", "b") }); err == nil {
+			t.Error("Instance.MustRename: expected panic with existing destination")
+		}
+
+		if err := panicked(func() { instance.MustRenameMany(map[string]string{"greeting": "x"}) }); err != nil {
+			t.Error("Instance.MustRenameMany: got panic:\n", err)
+		}
+		checkValues(map[string]interface{}{"b": 2, "c": 1, "d": 3, "x": "hello"})
+	})
+}
+
+// TestRenameWithCodec ensures that renaming an entry re-encodes its value for
+// the codecs registered under the old and new names.
+func TestRenameWithCodec(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.SetValueCodec("secret", reverse, reverse)
+		instance.MustSet("secret", "value")
+
+		instance.MustRename("secret", "plain")
+		if value := GetFixtures(instance)["plain"].Value; value != "value" {
+			t.Errorf("Instance.Rename: got stored value '%v' expected 'value'", value)
+		}
+	})
+}
+
+// TestMove ensures that an entry is moved over an existing one of a different
+// type, and that a missing or immutable source leaves every entry untouched.
+func TestMove(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("staged", "v2")
+		instance.MustSet("live", 1)
+
+		if err := instance.Move("staged", "live"); err != nil {
+			t.Fatal("Instance.Move: got error:\n", err)
+		}
+
+		if instance.Exists("staged") {
+			t.Error("Instance.Move: expected source to be deleted")
+		}
+
+		if res := instance.MustGet("live"); res != "v2" {
+			t.Errorf("Instance.Move: got '%v' expected 'v2'", res)
+		}
+
+		if err := instance.Move("missing", "live"); err == nil {
+			t.Error("Instance.Move: expected error with non-existent entry")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Error("Instance.Move: expected error of type *ErrNoEntry")
+		}
+
+		instance.MustMove("live", "live")
+		if res := instance.MustGet("live"); res != "v2" {
+			t.Errorf("Instance.Move: got '%v' expected 'v2' after moving to own name", res)
+		}
+
+		instance.MustSet("locked", true)
+		instance.MarkImmutable("locked")
+		if err := panicked(func() { instance.MustMove("locked", "live") }); err == nil {
+			t.Error("Instance.MustMove: expected panic with immutable source")
+		}
+
+		if res := instance.MustGet("live"); res != "v2" {
+			t.Errorf("Instance.Move: got '%v' expected 'v2' after failed move", res)
+		}
+	})
+}
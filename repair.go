@@ -0,0 +1,152 @@
+package metadb
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// RepairResult describes an entry whose stored type does not match its stored
+// value, along with the type inferred from the value.
+type RepairResult struct {
+	Name  string
+	From  ValueType   // type with which the entry is tagged
+	To    ValueType   // type inferred from the stored value
+	Value interface{} // value decoded as the inferred type
+}
+
+// inferTypes lists the types which may be inferred from a blob string, in order
+// of preference. Strings come last, as any blob string is a valid string.
+var inferTypes = []ValueType{TypeBool, TypeInt, TypeInt64, TypeFloat, TypeComplex, TypeStringSlice, TypeString}
+
+// inferType returns the most plausible type of the value stored as the given
+// blob string, along with the value decoded as that type.
+func inferType(blob string) (ValueType, interface{}) {
+	for _, valueType := range inferTypes {
+		// only the canonical encodings of bools are accepted, as e.g. "1" is
+		// more plausibly an int
+		if valueType == TypeBool && blob != strconv.FormatBool(true) && blob != strconv.FormatBool(false) {
+			continue
+		}
+
+		if value, err := fromBlobString(blob, valueType); err == nil {
+			return valueType, value
+		}
+	}
+
+	return TypeString, blob
+}
+
+// RepairTypes finds every entry whose stored value cannot be decoded as the
+// type with which it is tagged, such as an int entry holding "2.5", and infers
+// the most plausible type of its value instead. If apply is true, the type of
+// each such entry is corrected within a single transaction. Otherwise nothing
+// is modified, such that the results may be reviewed first. Entries with a
+// codec whose value cannot be decoded by it are skipped.
+func (instance *Instance) RepairTypes(apply bool) ([]RepairResult, error) {
+	results := make([]RepairResult, 0)
+	err := instance.withTx(func(tx *Instance) error {
+		scope, args := tx.scope()
+		rows, err := tx.query("SELECT {name}, {value}, {type} FROM {table} WHERE "+scope+" ORDER BY {name};", args...)
+		if err != nil {
+			return fmt.Errorf("metadb: failed to read entries:\n%s", err)
+		}
+
+		for rows.Next() {
+			var name, blob string
+			var valueType ValueType
+			if err := rows.Scan(&name, &blob, &valueType); err != nil {
+				rows.Close()
+				return fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+			}
+
+			name = tx.unkey(name)
+			if blob, err = tx.decodeBlob(name, blob); err != nil {
+				continue
+			}
+
+			if _, err := fromBlobString(blob, valueType); err == nil {
+				continue
+			}
+
+			inferred, value := inferType(blob)
+			results = append(results, RepairResult{name, valueType, inferred, value})
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("metadb: failed to read entries:\n%s", err)
+		}
+
+		if !apply {
+			return nil
+		}
+
+		for _, result := range results {
+			if _, err := tx.exec("UPDATE {table} SET {type} = ? WHERE {name} = ?;", result.To, tx.key(result.Name)); err != nil {
+				return fmt.Errorf("metadb: failed to repair type of '%s':\n%s", result.Name, err)
+			}
+
+			tx.changed(result.Name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// MustRepairTypes does the same as RepairTypes, but panics if an error is
+// returned.
+func (instance *Instance) MustRepairTypes(apply bool) []RepairResult {
+	if res, err := instance.RepairTypes(apply); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// FindDuplicates returns the names which are shared by more than one row of
+// the metadata table, in order of name, or an empty slice if there are none.
+// This can only occur if the table was created without the UNIQUE constraint
+// on the name column, such as before being adopted by metadb with
+// Options.SkipInitSchema, in which case reading and writing the affected
+// entries is unreliable. Rows of soft deleted entries are included.
+func (instance *Instance) FindDuplicates() ([]string, error) {
+	prefixed, args := instance.prefixed()
+	rows, err := instance.query("SELECT {name} FROM {table} WHERE "+prefixed+
+		" GROUP BY {name} HAVING COUNT(*) > 1 ORDER BY {name};", args...)
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to find duplicate names:\n%s", err)
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan entry name:\n%s", err)
+		}
+
+		names = append(names, instance.unkey(name))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to find duplicate names:\n%s", err)
+	}
+
+	return names, nil
+}
+
+// MustFindDuplicates does the same as FindDuplicates, but panics if an error is
+// returned.
+func (instance *Instance) MustFindDuplicates() []string {
+	if res, err := instance.FindDuplicates(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
@@ -0,0 +1,88 @@
+package metadb
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+// TestRepairTypes ensures that entries tagged with the wrong type are found,
+// and only corrected when requested.
+func TestRepairTypes(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		InsertFixtures(instance, []EntryFixture{
+			{Name: "int", Value: "2.5", ValueType: TypeInt},
+			{Name: "float", Value: "hello", ValueType: TypeFloat},
+			{Name: "bool", Value: "1", ValueType: TypeBool},
+			{Name: "slice", Value: "true", ValueType: TypeStringSlice},
+			{Name: "unknown", Value: "42", ValueType: 100},
+			{Name: "valid", Value: "42", ValueType: TypeInt},
+		})
+
+		expected := []RepairResult{
+			{"float", TypeFloat, TypeString, "hello"},
+			{"int", TypeInt, TypeFloat, 2.5},
+			{"slice", TypeStringSlice, TypeBool, true},
+			{"unknown", 100, TypeInt, 42},
+		}
+
+		results, err := instance.RepairTypes(false)
+		if err != nil {
+			t.Fatal("Instance.RepairTypes: got error:\n", err)
+		}
+
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("Instance.RepairTypes: got '%v' expected '%v'", results, expected)
+		}
+
+		if _, err := instance.Get("int"); err == nil {
+			t.Error("Instance.RepairTypes: expected no entry to be modified without apply")
+		}
+
+		if results := instance.MustRepairTypes(true); !reflect.DeepEqual(results, expected) {
+			t.Errorf("Instance.MustRepairTypes: got '%v' expected '%v'", results, expected)
+		}
+
+		if value := instance.MustGet("int"); value != 2.5 {
+			t.Errorf("Instance.RepairTypes: got '%v' expected '2.5'", value)
+		}
+
+		if results := instance.MustRepairTypes(false); len(results) != 0 {
+			t.Errorf("Instance.MustRepairTypes: got '%v' expected no results once repaired", results)
+		}
+	})
+}
+
+// TestFindDuplicates ensures that names shared by more than one row are found
+// in a table lacking the UNIQUE constraint, and that none are found otherwise.
+func TestFindDuplicates(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("foo", 1)
+		if res := instance.MustFindDuplicates(); res == nil || len(res) != 0 {
+			t.Errorf("Instance.FindDuplicates: got '%v' expected an empty slice", res)
+		}
+	})
+
+	RunWithDB(func(db *sql.DB) {
+		if _, err := db.Exec("CREATE TABLE metadata (Name VARCHAR(255), Value BLOB, ValueType TINYINT);"); err != nil {
+			t.Fatal("tests: failed to create table:\n", err)
+		}
+
+		for _, name := range []string{"b", "a", "b", "c", "a", "a"} {
+			if _, err := db.Exec("INSERT INTO metadata VALUES (?, '1', 1);", name); err != nil {
+				t.Fatal("tests: failed to insert row:\n", err)
+			}
+		}
+
+		instance, err := NewInstanceWithOptions(db, Options{SkipInitSchema: true})
+		if err != nil {
+			t.Fatal("NewInstanceWithOptions: got error:\n", err)
+		}
+
+		if res, err := instance.FindDuplicates(); err != nil {
+			t.Error("Instance.FindDuplicates: got error:\n", err)
+		} else if !reflect.DeepEqual(res, []string{"a", "b"}) {
+			t.Errorf("Instance.FindDuplicates: got '%v' expected '[a b]'", res)
+		}
+	})
+}
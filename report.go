@@ -0,0 +1,148 @@
+package metadb
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ReportRow represents a single metadata entry formatted for display, with
+// both its value and type given as strings.
+type ReportRow struct {
+	Name  string
+	Value string
+	Type  string
+}
+
+// Report returns every metadata entry formatted for display, sorted by name.
+// Values are formatted as they are stored, with floats in their shortest
+// exact representation, and types are named as by ValueType.String.
+func (instance *Instance) Report() ([]ReportRow, error) {
+	return instance.ReportWithPrecision(-1)
+}
+
+// ReportWithPrecision does the same as Report, but formats float values with
+// the given number of digits after the decimal point. A negative precision
+// formats floats in their shortest exact representation.
+func (instance *Instance) ReportWithPrecision(precision int) ([]ReportRow, error) {
+	entries, err := instance.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]ReportRow, 0, len(entries))
+	for _, entry := range entries {
+		var value string
+		if float, ok := entry.Value.(float64); ok && precision >= 0 {
+			value = strconv.FormatFloat(float, 'f', precision, 64)
+		} else if value, err = toBlobString(entry.Value); err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, ReportRow{Name: entry.Name, Value: value, Type: entry.Type.String()})
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+	return rows, nil
+}
+
+// Sizes returns the size in bytes of the stored value of every entry, keyed by
+// name, without decoding the values. Values are cast to BLOB before being
+// measured by the LENGTH function of the database, such that text is measured
+// in bytes rather than characters, and are measured as stored, i.e. after any
+// codec has been applied.
+func (instance *Instance) Sizes() (map[string]int, error) {
+	scope, args := instance.scope()
+	rows, err := instance.query("SELECT {name}, LENGTH(CAST({value} AS BLOB)) FROM {table} WHERE "+scope+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entry sizes:\n%s", err)
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var size int
+		if err := rows.Scan(&name, &size); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan entry size:\n%s", err)
+		}
+
+		sizes[instance.unkey(name)] = size
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entry sizes:\n%s", err)
+	}
+
+	return sizes, nil
+}
+
+// MustSizes does the same as Sizes, but panics if an error is returned.
+func (instance *Instance) MustSizes() map[string]int {
+	if res, err := instance.Sizes(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// TotalSize returns the sum of the sizes in bytes of the stored values of every
+// entry, measured as by Sizes.
+func (instance *Instance) TotalSize() (int64, error) {
+	scope, args := instance.scope()
+	row := instance.queryRow("SELECT SUM(LENGTH(CAST({value} AS BLOB))) FROM {table} WHERE "+scope+";", args...)
+	var total sql.NullInt64
+	if err := row.Scan(&total); err != nil {
+		return 0, fmt.Errorf("metadb: failed to read total size:\n%s", err)
+	}
+
+	return total.Int64, nil
+}
+
+// MustTotalSize does the same as TotalSize, but panics if an error is returned.
+func (instance *Instance) MustTotalSize() int64 {
+	if res, err := instance.TotalSize(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// DistinctTypes returns each ValueType held by at least one entry, in
+// ascending order. Types which are not recognized, such as those of corrupt
+// entries, are included, and may be recognized by their String method.
+func (instance *Instance) DistinctTypes() ([]ValueType, error) {
+	scope, args := instance.scope()
+	rows, err := instance.query("SELECT DISTINCT {type} FROM {table} WHERE "+scope+" ORDER BY {type};", args...)
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read value types:\n%s", err)
+	}
+	defer rows.Close()
+
+	types := make([]ValueType, 0)
+	for rows.Next() {
+		var valueType ValueType
+		if err := rows.Scan(&valueType); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan value type:\n%s", err)
+		}
+
+		types = append(types, valueType)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read value types:\n%s", err)
+	}
+
+	return types, nil
+}
+
+// MustDistinctTypes does the same as DistinctTypes, but panics if an error is
+// returned.
+func (instance *Instance) MustDistinctTypes() []ValueType {
+	if res, err := instance.DistinctTypes(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
@@ -0,0 +1,103 @@
+package metadb
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestReport ensures that Report formats every entry consistently, sorted by
+// name, and that ReportWithPrecision applies a fixed precision to floats.
+func TestReport(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("b", 1.0/3)
+		instance.MustSet("a", true)
+		instance.MustSet("c", int64(10))
+
+		rows, err := instance.Report()
+		if err != nil {
+			t.Fatal("Instance.Report: got error:\n", err)
+		}
+
+		expected := []ReportRow{
+			{Name: "a", Value: "true", Type: "bool"},
+			{Name: "b", Value: "0.3333333333333333", Type: "float64"},
+			{Name: "c", Value: "10", Type: "int64"},
+		}
+
+		if !reflect.DeepEqual(rows, expected) {
+			t.Errorf("Instance.Report: got '%v' expected '%v'", rows, expected)
+		}
+
+		rows, err = instance.ReportWithPrecision(2)
+		if err != nil {
+			t.Fatal("Instance.ReportWithPrecision: got error:\n", err)
+		}
+
+		expected[1].Value = "0.33"
+		if !reflect.DeepEqual(rows, expected) {
+			t.Errorf("Instance.ReportWithPrecision: got '%v' expected '%v'", rows, expected)
+		}
+	})
+}
+
+// TestSizes ensures that Sizes and TotalSize measure the stored values of the
+// entries within the scope of the Instance in bytes.
+func TestSizes(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		if total, err := instance.TotalSize(); err != nil {
+			t.Error("Instance.TotalSize: got error:\n", err)
+		} else if total != 0 {
+			t.Errorf("Instance.TotalSize: got '%d' expected '0'", total)
+		}
+
+		instance.MustSet("a.name", "héllo")
+		instance.MustSet("a.count", 1234)
+		instance.MustSet("b", true)
+
+		scoped := instance.WithPrefix("a.")
+		sizes, err := scoped.Sizes()
+		if err != nil {
+			t.Fatal("Instance.Sizes: got error:\n", err)
+		}
+
+		expected := map[string]int{"name": 6, "count": 4}
+		if !reflect.DeepEqual(sizes, expected) {
+			t.Errorf("Instance.Sizes: got '%v' expected '%v'", sizes, expected)
+		}
+
+		if total, err := scoped.TotalSize(); err != nil {
+			t.Error("Instance.TotalSize: got error:\n", err)
+		} else if total != 10 {
+			t.Errorf("Instance.TotalSize: got '%d' expected '10'", total)
+		}
+
+		if total := instance.MustTotalSize(); total != 14 {
+			t.Errorf("Instance.MustTotalSize: got '%d' expected '14'", total)
+		}
+	})
+}
+
+// TestDistinctTypes ensures that DistinctTypes lists each type in use once,
+// including unrecognized ones.
+func TestDistinctTypes(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		if types := instance.MustDistinctTypes(); len(types) != 0 {
+			t.Errorf("Instance.DistinctTypes: got '%v' expected '[]'", types)
+		}
+
+		instance.MustSet("a", "x")
+		instance.MustSet("b", 1)
+		instance.MustSet("c", "y")
+		InsertFixtures(instance, []EntryFixture{{Name: "corrupt", Value: "?", ValueType: 100}})
+
+		types, err := instance.DistinctTypes()
+		if err != nil {
+			t.Fatal("Instance.DistinctTypes: got error:\n", err)
+		}
+
+		expected := []ValueType{TypeInt, TypeString, ValueType(100)}
+		if !reflect.DeepEqual(types, expected) {
+			t.Errorf("Instance.DistinctTypes: got '%v' expected '%v'", types, expected)
+		}
+	})
+}
@@ -0,0 +1,316 @@
+package metadb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// identifierPattern matches the table and column names which may be configured
+// through Options. Names are restricted to prevent SQL injection, as they are
+// interpolated directly into statements.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// PlaceholderStyle identifies the syntax of the bound parameters of statements
+// as expected by a database driver, see Options.Placeholders.
+type PlaceholderStyle int
+
+// The placeholder styles which may be configured through Options.
+const (
+	PlaceholderQuestion PlaceholderStyle = iota // ?, as used by SQLite and MySQL
+	PlaceholderDollar                           // $1, $2, and so on, as used by PostgreSQL
+)
+
+// schema holds the table and column names used by an Instance.
+type schema struct {
+	table       string
+	name        string
+	value       string
+	valueType   string
+	history     string
+	version     string
+	updated     string
+	read        string
+	deleted     string
+	description string
+	replacer    *strings.Replacer
+	style       PlaceholderStyle
+}
+
+// newSchema returns the schema configured by the provided Options, using the
+// default name for any which is left empty. If any of the names is not a valid
+// identifier or the placeholder style is unknown, an error is returned.
+func newSchema(options Options) (schema, error) {
+	res := schema{table: "metadata", name: "Name", value: "Value", valueType: "ValueType"}
+
+	if options.Placeholders != PlaceholderQuestion && options.Placeholders != PlaceholderDollar {
+		return schema{}, fmt.Errorf("metadb: invalid placeholder style %d", options.Placeholders)
+	}
+	res.style = options.Placeholders
+
+	for _, field := range []struct {
+		dest  *string
+		value string
+	}{
+		{&res.table, options.Table},
+		{&res.name, options.NameColumn},
+		{&res.value, options.ValueColumn},
+		{&res.valueType, options.TypeColumn},
+	} {
+		if field.value == "" {
+			continue
+		} else if !identifierPattern.MatchString(field.value) {
+			return schema{}, fmt.Errorf("metadb: invalid table or column name '%s'", field.value)
+		}
+
+		*field.dest = field.value
+	}
+
+	res.history = res.table + "_history"
+	res.version = res.table + "_schema"
+	res.updated = "UpdatedAt"
+	res.read = "LastReadAt"
+	res.deleted = "Deleted"
+	res.description = "Description"
+
+	// entries flagged as deleted are hidden if soft deletion is enabled
+	visible := "1 = 1"
+	if options.SoftDelete {
+		visible = res.deleted + " = 0"
+	}
+
+	res.replacer = strings.NewReplacer(
+		"{table}", res.table,
+		"{history}", res.history,
+		"{version}", res.version,
+		"{name}", res.name,
+		"{value}", res.value,
+		"{type}", res.valueType,
+		"{updated}", res.updated,
+		"{read}", res.read,
+		"{deleted}", res.deleted,
+		"{description}", res.description,
+		"{visible}", visible,
+	)
+
+	return res, nil
+}
+
+// sql returns the provided query with the {table}, {history}, {version},
+// {name}, {value}, {type}, {updated}, {read}, {deleted}, and {description}
+// placeholders replaced by the configured table and column names, and the
+// {visible} placeholder replaced by a condition matching only entries which
+// have not been soft deleted. Every query is written with ? placeholders,
+// which are then rewritten in the style configured by Options.Placeholders.
+func (instance *Instance) sql(query string) string {
+	query = instance.schema.replacer.Replace(query)
+	if instance.schema.style == PlaceholderDollar {
+		query = numberPlaceholders(query, "$")
+	}
+
+	return query
+}
+
+// numberPlaceholders returns query with each ? placeholder replaced by prefix
+// followed by its position, counting from 1, e.g. $1. Question marks within
+// quoted strings are left as-is.
+func numberPlaceholders(query string, prefix string) string {
+	var res strings.Builder
+	quoted := false
+	n := 0
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			res.WriteString(prefix + strconv.Itoa(n))
+			continue
+		}
+
+		res.WriteRune(r)
+	}
+
+	return res.String()
+}
+
+// createIndexes creates an index on the value type column of the metadata
+// table, speeding up queries filtering entries by type, along with one on the
+// column recording the time at which each entry was last written if
+// Options.Timestamps is set. Existing indexes are left as-is.
+func (instance *Instance) createIndexes() error {
+	columns := []string{instance.schema.valueType}
+	if instance.options.Timestamps {
+		columns = append(columns, instance.schema.updated)
+	}
+
+	for _, column := range columns {
+		index := instance.schema.table + "_" + column + "_index"
+		if _, err := instance.exec("CREATE INDEX IF NOT EXISTS " + index + " ON {table} (" + column + ");"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TableName returns the name of the table in which entries are stored, as
+// configured by Options.Table, for use in custom queries.
+func (instance *Instance) TableName() string {
+	return instance.schema.table
+}
+
+// ColumnNames returns the names of the columns in which the name, value, and
+// value type of each entry are stored, as configured by Options, for use in
+// custom queries. Values are stored as described by ValueType, and may be
+// encoded by a codec registered through SetValueCodec.
+func (instance *Instance) ColumnNames() (name, value, valueType string) {
+	return instance.schema.name, instance.schema.value, instance.schema.valueType
+}
+
+// ErrSchemaMismatch is returned by NewInstance when the metadata table already
+// exists but its columns do not match those expected.
+type ErrSchemaMismatch struct {
+	Table  string
+	Column string
+	Reason string
+}
+
+// Error implements the error interface for ErrSchemaMismatch.
+func (err *ErrSchemaMismatch) Error() string {
+	return fmt.Sprintf("metadb: schema mismatch in table '%s' for column '%s': %s", err.Table, err.Column, err.Reason)
+}
+
+// columnKinds lists, for each of the columns used by an Instance, substrings
+// of which at least one must occur in the database type name of the column.
+// Drivers which do not report type names are not checked.
+var columnKinds = []struct {
+	column func(schema) string
+	kinds  []string
+	reason string
+}{
+	{func(s schema) string { return s.name }, []string{"CHAR", "TEXT", "CLOB", "STRING"}, "expected a text column"},
+	{func(s schema) string { return s.value }, []string{"BLOB", "BINARY", "BYTEA", "CHAR", "TEXT", "CLOB"}, "expected a blob or text column"},
+	{func(s schema) string { return s.valueType }, []string{"INT"}, "expected an integer column"},
+}
+
+// columns returns the database type names of the columns of the metadata
+// table, keyed by the lowercase name of each column. Type names are empty for
+// drivers which do not report them.
+func (instance *Instance) columns() (map[string]string, error) {
+	rows, err := instance.query("SELECT * FROM {table} LIMIT 0;")
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to inspect metadata table:\n%s", err)
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to inspect metadata table:\n%s", err)
+	}
+
+	types := make(map[string]string, len(columnTypes))
+	for _, columnType := range columnTypes {
+		types[strings.ToLower(columnType.Name())] = strings.ToUpper(columnType.DatabaseTypeName())
+	}
+
+	return types, nil
+}
+
+// checkSchema inspects the columns of the metadata table and returns an
+// ErrSchemaMismatch if any of the expected columns is missing or has an
+// incompatible type.
+func (instance *Instance) checkSchema() error {
+	types, err := instance.columns()
+	if err != nil {
+		return err
+	}
+
+	for _, expected := range columnKinds {
+		column := expected.column(instance.schema)
+		typeName, ok := types[strings.ToLower(column)]
+		if !ok {
+			return &ErrSchemaMismatch{instance.schema.table, column, "column is missing"}
+		} else if typeName == "" {
+			continue
+		}
+
+		matches := false
+		for _, kind := range expected.kinds {
+			if strings.Contains(typeName, kind) {
+				matches = true
+				break
+			}
+		}
+
+		if !matches {
+			return &ErrSchemaMismatch{instance.schema.table, column,
+				fmt.Sprintf("%s, got '%s'", expected.reason, typeName)}
+		}
+	}
+
+	return nil
+}
+
+// addColumn adds a column with the given name and definition to the metadata
+// table, if it is missing.
+func (instance *Instance) addColumn(column, definition string) error {
+	columns, err := instance.columns()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := columns[strings.ToLower(column)]; ok {
+		return nil
+	}
+
+	_, err = instance.exec("ALTER TABLE {table} ADD COLUMN " + column + " " + definition + ";")
+	return err
+}
+
+// RefreshSchema discards the prepared statements cached by the Instance and
+// any Instance derived from it, and inspects the metadata table again, such as
+// after it was altered by another process. Statements are prepared again as
+// needed. If any of the expected columns, including those required by the
+// Options of the Instance, is missing or has an incompatible type, an
+// ErrSchemaMismatch is returned. It may be called concurrently with any other
+// operation.
+func (instance *Instance) RefreshSchema() error {
+	if err := instance.closeStatements(); err != nil {
+		return err
+	}
+
+	if err := instance.checkSchema(); err != nil {
+		return err
+	}
+
+	types, err := instance.columns()
+	if err != nil {
+		return err
+	}
+
+	for _, optional := range []struct {
+		column  string
+		enabled bool
+	}{
+		{instance.schema.updated, instance.options.Timestamps},
+		{instance.schema.read, instance.options.TrackReads},
+		{instance.schema.deleted, instance.options.SoftDelete},
+		{instance.schema.description, instance.options.Descriptions},
+	} {
+		if _, ok := types[strings.ToLower(optional.column)]; optional.enabled && !ok {
+			return &ErrSchemaMismatch{instance.schema.table, optional.column, "column is missing"}
+		}
+	}
+
+	return nil
+}
+
+// MustRefreshSchema does the same as RefreshSchema, but panics if an error is
+// returned.
+func (instance *Instance) MustRefreshSchema() {
+	if err := instance.RefreshSchema(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,228 @@
+package metadb
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+// TestCustomSchema ensures that an Instance operates on the configured table
+// and columns, and that invalid names are rejected.
+func TestCustomSchema(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		if _, err := NewInstanceWithOptions(db, Options{Table: "settings; DROP TABLE metadata"}); err == nil {
+			t.Error("NewInstanceWithOptions: expected error with invalid table name")
+		}
+
+		if _, err := NewInstanceWithOptions(db, Options{ValueColumn: "1value"}); err == nil {
+			t.Error("NewInstanceWithOptions: expected error with invalid column name")
+		}
+
+		if _, err := db.Exec(`CREATE TABLE settings (setting TEXT UNIQUE, val BLOB, kind INT);`); err != nil {
+			t.Fatal("tests: failed to create legacy table:\n", err)
+		}
+
+		instance, err := NewInstanceWithOptions(db, Options{
+			Table:       "settings",
+			NameColumn:  "setting",
+			ValueColumn: "val",
+			TypeColumn:  "kind",
+		})
+		if err != nil {
+			t.Fatal("NewInstanceWithOptions: got error:\n", err)
+		}
+
+		if table := instance.TableName(); table != "settings" {
+			t.Errorf("Instance.TableName: got '%s' expected 'settings'", table)
+		}
+
+		if name, value, valueType := instance.ColumnNames(); name != "setting" || value != "val" || valueType != "kind" {
+			t.Errorf("Instance.ColumnNames: got '%s', '%s', '%s' expected 'setting', 'val', 'kind'", name, value, valueType)
+		}
+
+		instance.MustSet("foo", 42)
+		if !instance.Exists("foo") {
+			t.Error("Instance.Exists: got 'false' expected 'true'")
+		}
+
+		if value := instance.MustGet("foo"); value != 42 {
+			t.Errorf("Instance.Get: got '%v' expected '42'", value)
+		}
+
+		var val string
+		var kind int
+		if err := db.QueryRow(`SELECT val, kind FROM settings WHERE setting = 'foo';`).Scan(&val, &kind); err != nil {
+			t.Error("tests: failed to read legacy table:\n", err)
+		} else if val != "42" || kind != 1 {
+			t.Errorf("Instance.Set: got stored '%s', '%d' expected '42', '1'", val, kind)
+		}
+
+		instance.MustDelete("foo")
+		if instance.Exists("foo") {
+			t.Error("Instance.Exists: got 'true' expected 'false'")
+		}
+	})
+}
+
+// TestSchemaMismatch ensures that NewInstance returns an ErrSchemaMismatch when
+// the existing metadata table is missing a column or has an incompatible one.
+func TestSchemaMismatch(t *testing.T) {
+	expectMismatch := func(ddl string, column string) {
+		RunWithDB(func(db *sql.DB) {
+			if _, err := db.Exec(ddl); err != nil {
+				t.Fatal("tests: failed to create table:\n", err)
+			}
+
+			_, err := NewInstance(db)
+			if err == nil {
+				t.Errorf("NewInstance: expected error with table '%s'", ddl)
+			} else if mismatch, ok := err.(*ErrSchemaMismatch); !ok {
+				t.Errorf("NewInstance: expected error of type *ErrSchemaMismatch, got:\n%s", err)
+			} else if mismatch.Column != column {
+				t.Errorf("NewInstance: got mismatch for column '%s' expected '%s'", mismatch.Column, column)
+			}
+		})
+	}
+
+	expectMismatch(`CREATE TABLE metadata (Name TEXT, Value BLOB);`, "ValueType")
+	expectMismatch(`CREATE TABLE metadata (Name TEXT, Value BLOB, ValueType TEXT);`, "ValueType")
+	expectMismatch(`CREATE TABLE metadata (Name INTEGER, Value BLOB, ValueType INT);`, "Name")
+	expectMismatch(`CREATE TABLE metadata (Name TEXT, Value REAL, ValueType INT);`, "Value")
+
+	RunWithDB(func(db *sql.DB) {
+		if _, err := db.Exec(`CREATE TABLE metadata (name TEXT, value TEXT, valuetype INTEGER);`); err != nil {
+			t.Fatal("tests: failed to create table:\n", err)
+		}
+
+		if _, err := NewInstance(db); err != nil {
+			t.Error("NewInstance: got error with compatible table:\n", err)
+		}
+	})
+}
+
+// TestIndexes ensures that indexes are created for existing tables, including
+// on the timestamp column if enabled, unless Options.SkipIndexes is set.
+func TestIndexes(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		indexes := func() []string {
+			rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'index' AND sql IS NOT NULL ORDER BY name;`)
+			if err != nil {
+				t.Fatal("tests: failed to list indexes:\n", err)
+			}
+			defer rows.Close()
+
+			names := make([]string, 0)
+			for rows.Next() {
+				var name string
+				if err := rows.Scan(&name); err != nil {
+					t.Fatal("tests: failed to scan index:\n", err)
+				}
+
+				names = append(names, name)
+			}
+
+			return names
+		}
+
+		if _, err := NewInstanceWithOptions(db, Options{SkipIndexes: true}); err != nil {
+			t.Fatal("NewInstanceWithOptions: got error:\n", err)
+		} else if names := indexes(); len(names) != 0 {
+			t.Errorf("NewInstanceWithOptions: got indexes '%v' expected none", names)
+		}
+
+		if _, err := NewInstance(db); err != nil {
+			t.Fatal("NewInstance: got error:\n", err)
+		} else if names := indexes(); !reflect.DeepEqual(names, []string{"metadata_ValueType_index"}) {
+			t.Errorf("NewInstance: got indexes '%v' expected '[metadata_ValueType_index]'", names)
+		}
+
+		if _, err := NewInstanceWithOptions(db, Options{Timestamps: true}); err != nil {
+			t.Fatal("NewInstanceWithOptions: got error:\n", err)
+		} else if names := indexes(); !reflect.DeepEqual(names,
+			[]string{"metadata_UpdatedAt_index", "metadata_ValueType_index"}) {
+			t.Errorf("NewInstanceWithOptions: got indexes '%v' expected both indexes", names)
+		}
+	})
+}
+
+// TestRefreshSchema ensures that RefreshSchema discards cached statements, and
+// that it reports columns renamed or removed by another process.
+func TestRefreshSchema(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		instance, err := NewInstanceWithOptions(db, Options{Timestamps: true})
+		if err != nil {
+			t.Fatal("NewInstanceWithOptions: got error:\n", err)
+		}
+
+		instance.MustSet("foo", 1)
+		instance.MustGet("foo")
+		if len(instance.statements) == 0 {
+			t.Fatal("Instance.Get: expected a prepared statement to be cached")
+		}
+
+		if err := instance.RefreshSchema(); err != nil {
+			t.Error("Instance.RefreshSchema: got error:\n", err)
+		} else if len(instance.statements) != 0 {
+			t.Errorf("Instance.RefreshSchema: got %d cached statements expected 0", len(instance.statements))
+		}
+
+		if res := instance.MustGet("foo"); res != 1 {
+			t.Errorf("Instance.Get: got '%v' expected '1' after refreshing", res)
+		}
+
+		if _, err := db.Exec("ALTER TABLE metadata RENAME COLUMN UpdatedAt TO ModifiedAt;"); err != nil {
+			t.Fatal("tests: failed to rename column:\n", err)
+		}
+
+		if err := instance.RefreshSchema(); err == nil {
+			t.Error("Instance.RefreshSchema: expected error with renamed timestamp column")
+		} else if _, ok := err.(*ErrSchemaMismatch); !ok {
+			t.Errorf("Instance.RefreshSchema: got '%v' expected ErrSchemaMismatch", err)
+		}
+
+		if _, err := db.Exec("ALTER TABLE metadata RENAME COLUMN Value TO Data;"); err != nil {
+			t.Fatal("tests: failed to rename column:\n", err)
+		}
+
+		if err := panicked(func() { instance.MustRefreshSchema() }); err == nil {
+			t.Error("Instance.MustRefreshSchema: expected panic with renamed value column")
+		}
+	})
+}
+
+// TestPlaceholders ensures that placeholders are rewritten in the configured
+// style, and that every operation works with them.
+func TestPlaceholders(t *testing.T) {
+	query := "SELECT {name} FROM {table} WHERE {name} = ? AND {value} <> '?' AND {type} IN (?, ?);"
+	expected := "SELECT Name FROM metadata WHERE Name = $1 AND Value <> '?' AND ValueType IN ($2, $3);"
+
+	// SQLite accepts $1 as a parameter bound by position
+	RunWithOptions(Options{Placeholders: PlaceholderDollar, SoftDelete: true, Timestamps: true}, func(instance *Instance) {
+		if _, err := NewInstanceWithOptions(instance.DB, Options{Placeholders: PlaceholderStyle(-1)}); err == nil {
+			t.Error("NewInstanceWithOptions: expected error with invalid placeholder style")
+		}
+
+		if res := instance.sql(query); res != expected {
+			t.Errorf("Instance.sql: got '%s' expected '%s'", res, expected)
+		}
+
+		scoped := instance.WithPrefix("app.")
+		scoped.MustSet("name", "metadb")
+		scoped.MustSet("port", 8080)
+		scoped.MustSet("port", 8081)
+		scoped.MustRename("name", "title")
+
+		if value := scoped.MustGet("title"); value != "metadb" {
+			t.Errorf("Instance.Get: got '%v' expected 'metadb'", value)
+		}
+
+		if value := scoped.MustGetInt("port"); value != 8081 {
+			t.Errorf("Instance.GetInt: got '%v' expected '8081'", value)
+		}
+
+		scoped.MustDelete("port")
+		if keys := scoped.MustKeys(); !reflect.DeepEqual(keys, []string{"title"}) {
+			t.Errorf("Instance.Keys: got '%v' expected '[title]'", keys)
+		}
+	})
+}
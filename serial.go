@@ -0,0 +1,183 @@
+package metadb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// worker serializes the operations of a serialized Instance, performing them
+// one at a time on behalf of their callers, see NewSerializedInstance.
+type worker struct {
+	jobs    chan func()
+	done    chan struct{} // closed to stop the worker
+	stopped chan struct{} // closed once the worker has stopped
+	once    sync.Once
+	conn    *sql.Conn
+}
+
+// NewSerializedInstance does the same as NewInstance, but performs every
+// operation on a single dedicated connection taken from db, and funnels every
+// operation through a single worker, such that no two of them are ever
+// performed at once. With SQLite, this avoids SQLITE_BUSY errors entirely at
+// the cost of some latency. Get, Set, SetChanged, ForceSet, and Delete, as
+// well as WithTx and every other operation performed within a transaction,
+// hold the worker until they return, such that no other statement is executed
+// on the connection in the meantime, while any other operation holds it for
+// each of its statements in turn. The worker is stopped and the connection
+// released by Close, after which every operation returns an error. The
+// Middleware of the Instance, if any, runs while the worker is held, and so
+// must not itself call any operation of the Instance.
+func NewSerializedInstance(db *sql.DB) (*Instance, error) {
+	return NewSerializedInstanceWithOptions(db, Options{})
+}
+
+// NewSerializedInstanceWithOptions does the same as NewSerializedInstance, but
+// configures the returned Instance according to the provided Options.
+func NewSerializedInstanceWithOptions(db *sql.DB, options Options) (*Instance, error) {
+	if db == nil {
+		return nil, fmt.Errorf("NewInstance: got nil database handle")
+	}
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("NewInstance: failed to get connection:\n%s", err)
+	}
+
+	instance, err := newInstance(db, conn, options)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	instance.worker = &worker{
+		jobs:    make(chan func()),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		conn:    conn,
+	}
+	go instance.worker.run()
+
+	instance.db = &serialConn{instance.worker}
+	return instance, nil
+}
+
+// run performs each job as it is received until the worker is stopped.
+func (worker *worker) run() {
+	defer close(worker.stopped)
+
+	for {
+		select {
+		case job := <-worker.jobs:
+			job()
+		case <-worker.done:
+			return
+		}
+	}
+}
+
+// hold waits for the worker to be free and keeps it busy until the returned
+// release function is called, such that the caller may perform any number of
+// statements on its connection without any other operation being performed in
+// between. If ctx is done before the worker is free, ctx.Err() is returned,
+// and if the worker has been stopped, an error is returned.
+func (worker *worker) hold(ctx context.Context) (release func(), err error) {
+	released := make(chan struct{})
+	job := func() {
+		<-released
+	}
+
+	select {
+	case worker.jobs <- job:
+	case <-worker.done:
+		return nil, errors.New("metadb: serialized instance is closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return func() { close(released) }, nil
+}
+
+// stop stops the worker once the job it is performing, if any, has returned,
+// and closes its connection.
+func (worker *worker) stop() error {
+	var err error
+	worker.once.Do(func() {
+		close(worker.done)
+		<-worker.stopped
+
+		if err = worker.conn.Close(); err != nil {
+			err = fmt.Errorf("metadb: failed to close connection:\n%s", err)
+		}
+	})
+
+	return err
+}
+
+// serialConn is the executor of a serialized Instance, holding its worker for
+// each statement executed on the dedicated connection.
+type serialConn struct {
+	*worker
+}
+
+// ExecContext does the same as sql.Conn.ExecContext once the worker is free.
+func (serial *serialConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	release, err := serial.hold(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return serial.conn.ExecContext(ctx, query, args...)
+}
+
+// QueryContext does the same as sql.Conn.QueryContext once the worker is free.
+func (serial *serialConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	release, err := serial.hold(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return serial.conn.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext does the same as sql.Conn.QueryRowContext once the worker is
+// free.
+func (serial *serialConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	release, err := serial.hold(ctx)
+	if err != nil {
+		// an *sql.Row cannot hold an error of its own, so the query is made to
+		// fail by cancelling its context instead
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		return serial.conn.QueryRowContext(cancelled, query, args...)
+	}
+	defer release()
+
+	return serial.conn.QueryRowContext(ctx, query, args...)
+}
+
+// exclusive runs fn while holding the worker of a serialized Instance, passing
+// it a copy of the Instance which operates directly on the dedicated
+// connection, such that every statement executed by fn is performed without
+// any other in between. For every other Instance, fn is simply run with the
+// Instance itself.
+func (instance *Instance) exclusive(fn func(held *Instance) error) error {
+	serial, ok := instance.db.(*serialConn)
+	if !ok {
+		return fn(instance)
+	}
+
+	release, err := serial.hold(instance.ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	held := *instance
+	held.db = serial.conn
+	return fn(&held)
+}
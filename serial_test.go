@@ -0,0 +1,142 @@
+package metadb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestNewSerializedInstance ensures that concurrent operations on a serialized
+// Instance all succeed, that panics are raised on the calling goroutine, and
+// that routed operations fail once the Instance is closed.
+func TestNewSerializedInstance(t *testing.T) {
+	if _, err := NewSerializedInstance(nil); err == nil {
+		t.Error("NewSerializedInstance: expected error with nil database handle")
+	}
+
+	RunWithDB(func(db *sql.DB) {
+		var middleware sync.Mutex
+		calls := 0
+		instance, err := NewSerializedInstanceWithOptions(db, Options{
+			Middleware: func(op, name string, next func() error) error {
+				middleware.Lock()
+				calls++
+				middleware.Unlock()
+
+				if name == "panic" {
+					panic("middleware panic")
+				}
+
+				return next()
+			},
+		})
+		if err != nil {
+			t.Fatal("NewSerializedInstance: got error:\n", err)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				name := fmt.Sprintf("key%d", i%4)
+				if err := instance.ForceSet(name, i); err != nil {
+					t.Error("Instance.ForceSet: got error:\n", err)
+				}
+
+				if _, err := instance.Get(name); err != nil {
+					t.Error("Instance.Get: got error:\n", err)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		if calls != 32 {
+			t.Errorf("Options.Middleware: got %d calls expected 32", calls)
+		}
+
+		if err := panicked(func() { instance.Get("panic") }); err == nil {
+			t.Error("Instance.Get: expected panic raised by middleware")
+		}
+
+		instance.MustDelete("key0")
+		if instance.Exists("key0") {
+			t.Error("Instance.Exists: got 'true' expected 'false'")
+		}
+
+		if err := instance.Close(); err != nil {
+			t.Error("Instance.Close: got error:\n", err)
+		}
+
+		if _, err := instance.Get("key1"); err == nil {
+			t.Error("Instance.Get: expected error after Close")
+		}
+
+		if err := instance.Close(); err != nil {
+			t.Error("Instance.Close: got error closing twice:\n", err)
+		}
+	})
+}
+
+// TestSerializedInstanceTx ensures that operations performed concurrently with
+// a transaction on a serialized Instance wait for it to finish, rather than
+// being performed within it and rolled back along with it.
+func TestSerializedInstanceTx(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		instance, err := NewSerializedInstance(db)
+		if err != nil {
+			t.Fatal("NewSerializedInstance: got error:\n", err)
+		}
+		defer instance.Close()
+
+		instance.MustSet("flag", false)
+
+		started := make(chan struct{})
+		finished := make(chan struct{})
+		go func() {
+			defer close(finished)
+
+			rollback := errors.New("rollback")
+			if err := instance.WithTx(func(tx *Instance) error {
+				tx.MustSet("inner", 1)
+				close(started)
+
+				// give the concurrent operations a chance to run
+				time.Sleep(50 * time.Millisecond)
+				return rollback
+			}); err != rollback {
+				t.Errorf("Instance.WithTx: got error '%v' expected '%v'", err, rollback)
+			}
+		}()
+
+		<-started
+		if err := instance.Set("outer", 2); err != nil {
+			t.Error("Instance.Set: got error:\n", err)
+		}
+
+		if _, err := instance.Toggle("flag"); err != nil {
+			t.Error("Instance.Toggle: got error:\n", err)
+		}
+		<-finished
+
+		if res, err := instance.GetInt("outer"); err != nil {
+			t.Error("Instance.GetInt: got error:\n", err)
+		} else if res != 2 {
+			t.Errorf("Instance.GetInt: got '%d' expected '2'", res)
+		}
+
+		if res, err := instance.GetBool("flag"); err != nil {
+			t.Error("Instance.GetBool: got error:\n", err)
+		} else if !res {
+			t.Error("Instance.GetBool: got 'false' expected 'true'")
+		}
+
+		if instance.Exists("inner") {
+			t.Error("Instance.Exists: got 'true' expected 'false'")
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package metadb
+
+import (
+	"errors"
+	"fmt"
+)
+
+// deletion returns a statement deleting the entries matching the given
+// condition which have not already been soft deleted. If Options.SoftDelete
+// is set, the entries are flagged as deleted rather than being removed.
+func (instance *Instance) deletion(condition string) string {
+	if instance.options.SoftDelete {
+		return "UPDATE {table} SET {deleted} = 1 WHERE " + condition + " AND {visible};"
+	}
+
+	return "DELETE FROM {table} WHERE " + condition + ";"
+}
+
+// purge permanently removes the named entry if it has been soft deleted, such
+// that its name may be reused. It does nothing unless Options.SoftDelete is
+// set.
+func (instance *Instance) purge(name string) error {
+	if !instance.options.SoftDelete {
+		return nil
+	}
+
+	_, err := instance.exec("DELETE FROM {table} WHERE {name} = ? AND {deleted} <> 0;", instance.key(name))
+	return err
+}
+
+// Restore restores an entry which has been soft deleted, as if it had never
+// been deleted. If no such entry exists, an ErrNoEntry is returned, and if the
+// Instance was not created with Options.SoftDelete, an error is returned.
+func (instance *Instance) Restore(name string) error {
+	return instance.wrap("Restore", name, instance.restore(name))
+}
+
+// restore implements Restore.
+func (instance *Instance) restore(name string) error {
+	if !instance.options.SoftDelete {
+		return errors.New("metadb: soft deletion is not enabled")
+	}
+
+	res, err := instance.exec("UPDATE {table} SET {deleted} = 0 WHERE {name} = ? AND {deleted} <> 0;", instance.key(name))
+	if err != nil {
+		return fmt.Errorf("metadb: failed to restore entry for '%s':\n%s", name, err)
+	}
+
+	// if RowsAffected is unsupported, check whether the entry now exists
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return &ErrNoEntry{name}
+	} else if err != nil {
+		if exists, err := instance.exists(name); err != nil {
+			return fmt.Errorf("metadb: failed to restore entry for '%s':\n%s", name, err)
+		} else if !exists {
+			return &ErrNoEntry{name}
+		}
+	}
+
+	instance.changed(name)
+	return nil
+}
+
+// MustRestore does the same as Restore, but panics if an error is returned.
+func (instance *Instance) MustRestore(name string) {
+	if err := instance.Restore(name); err != nil {
+		panic(err)
+	}
+}
+
+// PurgeDeleted permanently removes every entry which has been soft deleted,
+// returning the number of entries removed. If the number is not reported by
+// the driver, -1 is returned. If the Instance was not created with
+// Options.SoftDelete, an error is returned.
+func (instance *Instance) PurgeDeleted() (int, error) {
+	if !instance.options.SoftDelete {
+		return 0, errors.New("metadb: soft deletion is not enabled")
+	}
+
+	scope, args := instance.prefixed()
+	res, err := instance.exec("DELETE FROM {table} WHERE "+scope+" AND {deleted} <> 0;", args...)
+	if err != nil {
+		return 0, fmt.Errorf("metadb: failed to purge deleted entries:\n%s", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return -1, nil
+	}
+
+	return int(affected), nil
+}
+
+// MustPurgeDeleted does the same as PurgeDeleted, but panics if an error is
+// returned.
+func (instance *Instance) MustPurgeDeleted() int {
+	if res, err := instance.PurgeDeleted(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
@@ -0,0 +1,76 @@
+package metadb
+
+import "testing"
+
+// TestSoftDelete ensures that soft deleted entries are hidden until restored,
+// may be replaced by new entries, and are removed for good once purged.
+func TestSoftDelete(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		if err := instance.Restore("foo"); err == nil {
+			t.Error("Instance.Restore: expected error with soft deletion disabled")
+		}
+
+		if _, err := instance.PurgeDeleted(); err == nil {
+			t.Error("Instance.PurgeDeleted: expected error with soft deletion disabled")
+		}
+	})
+
+	RunWithOptions(Options{SoftDelete: true}, func(instance *Instance) {
+		instance.MustSet("a", "first")
+		instance.MustSet("b", 2)
+		instance.MustSet("c", true)
+		instance.MustDelete("a")
+
+		if instance.Exists("a") {
+			t.Error("Instance.Exists: got 'true' expected 'false' for soft deleted entry")
+		}
+
+		if _, err := instance.Get("a"); err == nil {
+			t.Error("Instance.Get: expected error with soft deleted entry")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Errorf("Instance.Get: got '%v' expected ErrNoEntry", err)
+		}
+
+		if err := instance.Delete("a"); err == nil {
+			t.Error("Instance.Delete: expected error with soft deleted entry")
+		}
+
+		if keys := instance.MustKeys(); len(keys) != 2 || keys[0] != "b" || keys[1] != "c" {
+			t.Errorf("Instance.Keys: got '%v' expected '[b c]'", keys)
+		}
+
+		instance.MustRestore("a")
+		if value := instance.MustGet("a"); value != "first" {
+			t.Errorf("Instance.Get: got '%v' expected 'first' after restoring", value)
+		}
+
+		if err := instance.Restore("a"); err == nil {
+			t.Error("Instance.Restore: expected error with entry which is not deleted")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Errorf("Instance.Restore: got '%v' expected ErrNoEntry", err)
+		}
+
+		// entries created by the name of a deleted entry replace it
+		instance.MustDelete("b")
+		instance.MustSet("b", "second")
+		if value := instance.MustGet("b"); value != "second" {
+			t.Errorf("Instance.Get: got '%v' expected 'second'", value)
+		}
+
+		instance.MustDelete("c")
+		instance.MustSet("d", 4)
+		instance.MustRename("d", "c")
+
+		if names := instance.MustClear(); len(names) != 3 {
+			t.Errorf("Instance.Clear: got '%v' expected '[a b c]'", names)
+		}
+
+		if count := instance.MustPurgeDeleted(); count != 3 {
+			t.Errorf("Instance.PurgeDeleted: got '%d' expected '3'", count)
+		}
+
+		if err := instance.Restore("a"); err == nil {
+			t.Error("Instance.Restore: expected error with purged entry")
+		}
+	})
+}
@@ -0,0 +1,276 @@
+package metadb
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// maxQueryNames is the greatest number of names looked up by a single query,
+// chosen to remain below the limit on bound parameters of common databases.
+const maxQueryNames = 500
+
+// selectMany selects the given columns of all requested entries which exist,
+// passing the scanner of each row to fn, with as few queries as possible.
+// Names are scanned as stored, and must be unkeyed by fn. If fn returns an
+// error, reading stops and the error is returned as is.
+func (instance *Instance) selectMany(columns string, names []string, fn func(scan func(dest ...interface{}) error) error) error {
+	for start := 0; start < len(names); start += maxQueryNames {
+		end := start + maxQueryNames
+		if end > len(names) {
+			end = len(names)
+		}
+
+		args := make([]interface{}, end-start)
+		for i, name := range names[start:end] {
+			args[i] = instance.key(name)
+		}
+
+		rows, err := instance.query("SELECT "+columns+" FROM {table} WHERE {name} IN (?"+
+			strings.Repeat(", ?", len(args)-1)+") AND {visible};", args...)
+		if err != nil {
+			return fmt.Errorf("metadb: failed to read entries:\n%s", err)
+		}
+
+		for rows.Next() {
+			if err := fn(rows.Scan); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("metadb: failed to read entries:\n%s", err)
+		}
+	}
+
+	return nil
+}
+
+// getMany returns the decoded values of all requested entries which exist,
+// keyed by name, reading them with as few queries as possible.
+func (instance *Instance) getMany(names []string) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(names))
+	err := instance.selectMany("{name}, {value}, {type}", names, func(scan func(dest ...interface{}) error) error {
+		var name, blob string
+		var valueType ValueType
+		if err := scan(&name, &blob, &valueType); err != nil {
+			return fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+		}
+
+		var err error
+		name = instance.unkey(name)
+		values[name], err = instance.decode(name, blob, valueType)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// structField describes a field of a struct which is mapped to an entry.
+type structField struct {
+	index int
+	name  string
+	kind  ValueType
+}
+
+// structFields returns the fields of the struct type t which are mapped to
+// entries, keyed by the name of the entry following the prefix. The name of
+// each entry is given by the `metadb` tag of the field, or by the name of the
+// field if it has none. Unexported fields and fields tagged `metadb:"-"` are
+// skipped. If any other field is of a disallowed type, an error is returned.
+func structFields(prefix string, t reflect.Type) ([]structField, error) {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("metadb")
+		if field.PkgPath != "" || tag == "-" {
+			continue
+		}
+
+		kind, err := toValueType(reflect.Zero(field.Type).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("metadb: cannot map field '%s':\n%s", field.Name, err)
+		}
+
+		name := tag
+		if name == "" {
+			name = field.Name
+		}
+
+		fields = append(fields, structField{index: i, name: prefix + name, kind: kind})
+	}
+
+	return fields, nil
+}
+
+// GetStruct populates the fields of the struct pointed to by v from the entries
+// named by prefix followed by the `metadb` tag of each field, or its name if it
+// has no tag. For example, with a prefix of "server.", a field tagged
+// `metadb:"port"` is read from the entry named "server.port". Unexported fields
+// and fields tagged `metadb:"-"` are skipped. Every entry is read by a single
+// query.
+//
+// The names of the entries which do not exist are returned in order, their
+// fields being left as-is. If v is not a non-nil pointer to a struct, if any
+// field is of a disallowed type, or if any entry holds data of a different
+// type than its field, an error is returned and no field is modified.
+func (instance *Instance) GetStruct(prefix string, v interface{}) ([]string, error) {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("metadb: GetStruct requires a non-nil pointer to a struct")
+	}
+
+	target = target.Elem()
+	fields, err := structFields(prefix, target.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.name
+	}
+
+	values, err := instance.getMany(names)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make([]string, 0)
+	for _, field := range fields {
+		value, ok := values[field.name]
+		if !ok {
+			missing = append(missing, field.name)
+		} else if got, _ := toValueType(value); got != field.kind {
+			return nil, &ErrWrongType{field.name, field.kind, got}
+		}
+	}
+
+	for _, field := range fields {
+		if value, ok := values[field.name]; ok {
+			target.Field(field.index).Set(reflect.ValueOf(value))
+		}
+	}
+
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// ErrLoad is returned by Load when any of the fields of the struct could not be
+// populated, listing an error for each such field in the order of the fields.
+// Each error is an ErrNoEntry if the entry of the field does not exist, or an
+// ErrWrongType if it holds data of a different type than the field.
+type ErrLoad struct {
+	Errors []error
+}
+
+// Error implements the error interface for ErrLoad.
+func (err *ErrLoad) Error() string {
+	return fmt.Sprintf("metadb: failed to load %d field(s):\n%s", len(err.Errors), joinErrors(err.Errors))
+}
+
+// joinErrors returns the messages of errs, one per line.
+func joinErrors(errs []error) string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// Load populates the fields of the struct pointed to by dest from the entries
+// named by the `metadb` tag of each field, or its name if it has no tag, in the
+// same way as GetStruct with an empty prefix. Every entry is read by a single
+// query.
+//
+// Rather than stopping at the first field which cannot be populated, Load
+// populates every other field and returns an ErrLoad listing an error for
+// each field whose entry does not exist or holds data of a different type,
+// such that a whole configuration may be validated at once. If dest is not a
+// non-nil pointer to a struct or any field is of a disallowed type, an error
+// is returned and no field is modified.
+func (instance *Instance) Load(dest interface{}) error {
+	target := reflect.ValueOf(dest)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("metadb: Load requires a non-nil pointer to a struct")
+	}
+
+	target = target.Elem()
+	fields, err := structFields("", target.Type())
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.name
+	}
+
+	values, err := instance.getMany(names)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, field := range fields {
+		value, ok := values[field.name]
+		if !ok {
+			errs = append(errs, &ErrNoEntry{field.name})
+		} else if got, _ := toValueType(value); got != field.kind {
+			errs = append(errs, &ErrWrongType{field.name, field.kind, got})
+		} else {
+			target.Field(field.index).Set(reflect.ValueOf(value))
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ErrLoad{errs}
+	}
+
+	return nil
+}
+
+// MustLoad does the same as Load, but panics if an error is returned.
+func (instance *Instance) MustLoad(dest interface{}) {
+	if err := instance.Load(dest); err != nil {
+		panic(err)
+	}
+}
+
+// GetFirst returns the data within the first of the named entries which
+// exists, along with its name, reading every entry with as few queries as
+// possible. This suits layered configuration, where e.g. "env.prod.timeout"
+// overrides "env.default.timeout". If none of the entries exist, an ErrNoEntry
+// naming all of them is returned.
+func (instance *Instance) GetFirst(names ...string) (value interface{}, foundKey string, err error) {
+	values, err := instance.getMany(names)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, name := range names {
+		if value, ok := values[name]; ok {
+			return value, name, nil
+		}
+	}
+
+	return nil, "", &ErrNoEntry{strings.Join(names, ", ")}
+}
+
+// MustGetFirst does the same as GetFirst, but panics if an error is returned.
+func (instance *Instance) MustGetFirst(names ...string) (value interface{}, foundKey string) {
+	value, foundKey, err := instance.GetFirst(names...)
+	if err != nil {
+		panic(err)
+	}
+
+	return value, foundKey
+}
@@ -0,0 +1,167 @@
+package metadb
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// serverConfig is a struct used to test mapping entries onto struct fields.
+type serverConfig struct {
+	Host    string
+	Port    int    `metadb:"port"`
+	Debug   bool   `metadb:"debug"`
+	Ignored string `metadb:"-"`
+	hidden  string
+}
+
+// TestGetStruct ensures that struct fields are populated from their entries,
+// that missing entries are reported, and that invalid targets are rejected.
+func TestGetStruct(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("server.Host", "localhost")
+		instance.MustSet("server.port", 8080)
+		instance.MustSet("server.Ignored", "value")
+		instance.MustSet("other.port", "not an int")
+
+		config := serverConfig{Debug: true, Ignored: "kept", hidden: "kept"}
+		missing, err := instance.GetStruct("server.", &config)
+		if err != nil {
+			t.Fatal("Instance.GetStruct: got error:\n", err)
+		}
+
+		expected := serverConfig{Host: "localhost", Port: 8080, Debug: true, Ignored: "kept", hidden: "kept"}
+		if config != expected {
+			t.Errorf("Instance.GetStruct: got '%+v' expected '%+v'", config, expected)
+		}
+
+		if !reflect.DeepEqual(missing, []string{"server.debug"}) {
+			t.Errorf("Instance.GetStruct: got missing '%v' expected '[server.debug]'", missing)
+		}
+
+		config = serverConfig{}
+		if _, err := instance.GetStruct("other.", &config); err == nil {
+			t.Error("Instance.GetStruct: expected error with entry of different type than field")
+		} else if _, ok := err.(*ErrWrongType); !ok {
+			t.Error("Instance.GetStruct: expected error of type *ErrWrongType")
+		}
+
+		if config != (serverConfig{}) {
+			t.Errorf("Instance.GetStruct: got '%+v' expected no field to be modified", config)
+		}
+
+		if _, err := instance.GetStruct("server.", config); err == nil {
+			t.Error("Instance.GetStruct: expected error with non-pointer")
+		}
+
+		var invalid struct{ Port int32 }
+		if _, err := instance.GetStruct("server.", &invalid); err == nil {
+			t.Error("Instance.GetStruct: expected error with field of disallowed type")
+		}
+	})
+}
+
+// TestGetMany ensures that getMany reads every existing entry, including when
+// more names are requested than fit in a single query.
+func TestGetMany(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		names := make([]string, 0, maxQueryNames*2+1)
+		for i := 0; i < cap(names); i++ {
+			name := fmt.Sprintf("key%d", i)
+			names = append(names, name)
+			if i%2 == 0 {
+				instance.MustSet(name, i)
+			}
+		}
+
+		values, err := instance.getMany(names)
+		if err != nil {
+			t.Fatal("Instance.getMany: got error:\n", err)
+		}
+
+		for i, name := range names {
+			if value, ok := values[name]; ok != (i%2 == 0) {
+				t.Errorf("Instance.getMany: got presence '%t' for '%s'", ok, name)
+			} else if ok && value != i {
+				t.Errorf("Instance.getMany: got '%v' expected '%d' for '%s'", value, i, name)
+			}
+		}
+	})
+}
+
+// TestLoad ensures that every field which can be populated is, and that an
+// error is reported for each field which cannot.
+func TestLoad(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("Host", "localhost")
+		instance.MustSet("port", "8080")
+
+		var config serverConfig
+		err := instance.Load(&config)
+		if err == nil {
+			t.Fatal("Instance.Load: expected error with missing and mismatched entries")
+		}
+
+		loadErr, ok := err.(*ErrLoad)
+		if !ok {
+			t.Fatalf("Instance.Load: got '%v' expected ErrLoad", err)
+		} else if len(loadErr.Errors) != 2 {
+			t.Fatalf("Instance.Load: got %d errors expected 2:\n%v", len(loadErr.Errors), err)
+		}
+
+		if wrongType, ok := loadErr.Errors[0].(*ErrWrongType); !ok || wrongType.Name != "port" {
+			t.Errorf("Instance.Load: got '%v' expected ErrWrongType for 'port'", loadErr.Errors[0])
+		}
+
+		if noEntry, ok := loadErr.Errors[1].(*ErrNoEntry); !ok || noEntry.Name != "debug" {
+			t.Errorf("Instance.Load: got '%v' expected ErrNoEntry for 'debug'", loadErr.Errors[1])
+		}
+
+		if config != (serverConfig{Host: "localhost"}) {
+			t.Errorf("Instance.Load: got '%+v' expected valid field to be populated", config)
+		}
+
+		instance.MustForceSet("port", 8080)
+		instance.MustSet("debug", true)
+		instance.MustLoad(&config)
+
+		expected := serverConfig{Host: "localhost", Port: 8080, Debug: true}
+		if config != expected {
+			t.Errorf("Instance.Load: got '%+v' expected '%+v'", config, expected)
+		}
+
+		if err := instance.Load(config); err == nil {
+			t.Error("Instance.Load: expected error with non-pointer")
+		}
+	})
+}
+
+// TestGetFirst ensures that the first existing entry is returned in the order
+// in which the names were given.
+func TestGetFirst(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("env.default.timeout", 30)
+		instance.MustSet("env.prod.timeout", 10)
+
+		if value, name, err := instance.GetFirst("env.prod.timeout", "env.default.timeout"); err != nil {
+			t.Error("Instance.GetFirst: got error:\n", err)
+		} else if value != 10 || name != "env.prod.timeout" {
+			t.Errorf("Instance.GetFirst: got '%v', '%s' expected '10', 'env.prod.timeout'", value, name)
+		}
+
+		if value, name := instance.MustGetFirst("env.dev.timeout", "env.default.timeout"); value != 30 ||
+			name != "env.default.timeout" {
+			t.Errorf("Instance.MustGetFirst: got '%v', '%s' expected '30', 'env.default.timeout'", value, name)
+		}
+
+		if _, _, err := instance.GetFirst("env.dev.timeout", "timeout"); err == nil {
+			t.Error("Instance.GetFirst: expected error with non-existent entries")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Error("Instance.GetFirst: expected error of type *ErrNoEntry")
+		}
+
+		if err := panicked(func() { instance.MustGetFirst() }); err == nil {
+			t.Error("Instance.MustGetFirst: expected panic with no names")
+		}
+	})
+}
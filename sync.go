@@ -0,0 +1,34 @@
+package metadb
+
+import "fmt"
+
+// Sync flushes any writes buffered by the database to durable storage, such as
+// before shutting down when SQLite is used with PRAGMA synchronous=OFF. With
+// SQLite, the write-ahead log is checkpointed into the database file, if any.
+// With other databases, for which every committed write is already durable,
+// nothing is done and nil is returned.
+func (instance *Instance) Sync() error {
+	// sqlite_version() is only recognized by SQLite
+	row := instance.queryRow("SELECT sqlite_version();")
+	var version string
+	if err := row.Scan(&version); err != nil {
+		if err := instance.ctx.Err(); err != nil {
+			return fmt.Errorf("metadb: failed to sync:\n%s", err)
+		}
+
+		return nil
+	}
+
+	if _, err := instance.exec("PRAGMA wal_checkpoint(FULL);"); err != nil {
+		return fmt.Errorf("metadb: failed to sync:\n%s", err)
+	}
+
+	return nil
+}
+
+// MustSync does the same as Sync, but panics if an error is returned.
+func (instance *Instance) MustSync() {
+	if err := instance.Sync(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,38 @@
+package metadb
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+// TestSync ensures that Sync succeeds both with and without a write-ahead log,
+// and fails once the context of the Instance is cancelled.
+func TestSync(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("foo", "bar")
+		if err := instance.Sync(); err != nil {
+			t.Error("Instance.Sync: got error:\n", err)
+		}
+	})
+
+	RunWithDB(func(db *sql.DB) {
+		if _, err := db.Exec("PRAGMA journal_mode=WAL;"); err != nil {
+			t.Fatal("tests: failed to enable write-ahead log:\n", err)
+		}
+
+		instance, err := NewInstance(db)
+		if err != nil {
+			t.Fatal("NewInstance: got error:\n", err)
+		}
+
+		instance.MustSet("foo", "bar")
+		instance.MustSync()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := instance.WithContext(ctx).Sync(); err == nil {
+			t.Error("Instance.Sync: expected error with cancelled context")
+		}
+	})
+}
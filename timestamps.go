@@ -0,0 +1,160 @@
+package metadb
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// byUpdatedAt returns at most limit entries ordered by the time at which they
+// were last written, in the given SQL direction, with their UpdatedAt field
+// set. If Options.Timestamps is not set, an error is returned.
+func (instance *Instance) byUpdatedAt(direction string, limit int) ([]Entry, error) {
+	if !instance.options.Timestamps {
+		return nil, fmt.Errorf("metadb: timestamps are not enabled")
+	}
+
+	return instance.byTime(instance.schema.updated, direction, limit)
+}
+
+// byTime returns at most limit entries ordered by the time recorded in the
+// given column, in the given SQL direction, with the corresponding field of
+// each Entry set.
+func (instance *Instance) byTime(column, direction string, limit int) ([]Entry, error) {
+	scope, args := instance.scope()
+	rows, err := instance.query("SELECT {name}, {value}, {type}, "+column+" FROM {table} WHERE "+scope+
+		" ORDER BY "+column+" "+direction+", {name} LIMIT ?;", append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+	defer rows.Close()
+
+	entries := make([]Entry, 0)
+	for rows.Next() {
+		var name, blob string
+		var valueType ValueType
+		var nanoseconds int64
+		if err := rows.Scan(&name, &blob, &valueType, &nanoseconds); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+		}
+
+		name = instance.unkey(name)
+		value, err := instance.decode(name, blob, valueType)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := Entry{Name: name, Value: value, Type: valueType}
+		if nanoseconds != 0 && column == instance.schema.read {
+			entry.LastReadAt = time.Unix(0, nanoseconds)
+		} else if nanoseconds != 0 {
+			entry.UpdatedAt = time.Unix(0, nanoseconds)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+
+	return entries, nil
+}
+
+// RecentlyChanged returns at most limit entries, most recently written first,
+// with their UpdatedAt field set. Entries written before timestamps were
+// enabled have a zero UpdatedAt and are listed last. If the Instance was not
+// created with Options.Timestamps, an error is returned.
+func (instance *Instance) RecentlyChanged(limit int) ([]Entry, error) {
+	return instance.byUpdatedAt("DESC", limit)
+}
+
+// MustRecentlyChanged does the same as RecentlyChanged, but panics if an error
+// is returned.
+func (instance *Instance) MustRecentlyChanged(limit int) []Entry {
+	if res, err := instance.RecentlyChanged(limit); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// OldestUnchanged returns at most limit entries, least recently written first,
+// with their UpdatedAt field set. Entries written before timestamps were
+// enabled have a zero UpdatedAt and are listed first. If the Instance was not
+// created with Options.Timestamps, an error is returned.
+func (instance *Instance) OldestUnchanged(limit int) ([]Entry, error) {
+	return instance.byUpdatedAt("ASC", limit)
+}
+
+// MustOldestUnchanged does the same as OldestUnchanged, but panics if an error
+// is returned.
+func (instance *Instance) MustOldestUnchanged(limit int) []Entry {
+	if res, err := instance.OldestUnchanged(limit); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetWithAge does the same as Get, but additionally returns the time elapsed
+// since the entry was last written, read along with its value by a single
+// query, such that callers may decide whether to refresh it. Entries written
+// before timestamps were enabled, whose write time is unknown, are given the
+// greatest possible age. As with Get, an entry which does not exist is read
+// from the fallback of the Instance, if any, with its age within the fallback.
+// If the Instance was not created with Options.Timestamps, an error is
+// returned.
+func (instance *Instance) GetWithAge(name string) (value interface{}, age time.Duration, err error) {
+	value, age, err = instance.getWithAge(name)
+	if _, ok := err.(*ErrNoEntry); ok && instance.fallback != nil {
+		return instance.fallback.GetWithAge(name)
+	}
+
+	return value, age, instance.wrap("GetWithAge", name, err)
+}
+
+// getWithAge implements GetWithAge.
+func (instance *Instance) getWithAge(name string) (value interface{}, age time.Duration, err error) {
+	if !instance.options.Timestamps {
+		return nil, 0, fmt.Errorf("metadb: timestamps are not enabled")
+	}
+
+	row := instance.queryRow("SELECT {value}, {type}, {updated} FROM {table} WHERE {name} = ? AND {visible};", instance.key(name))
+	var blob string
+	var valueType ValueType
+	var nanoseconds int64
+	if err := row.Scan(&blob, &valueType, &nanoseconds); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, 0, &ErrNoEntry{name}
+		}
+
+		return nil, 0, fmt.Errorf("metadb: failed to read entry for '%s':\n%s", name, err)
+	}
+
+	if value, err = instance.decode(name, blob, valueType); err != nil {
+		return nil, 0, err
+	}
+
+	if instance.options.TrackReads {
+		instance.recordRead(name)
+	}
+
+	if nanoseconds == 0 {
+		return value, time.Duration(math.MaxInt64), nil
+	}
+
+	return value, time.Since(time.Unix(0, nanoseconds)), nil
+}
+
+// MustGetWithAge does the same as GetWithAge, but panics if an error is
+// returned.
+func (instance *Instance) MustGetWithAge(name string) (value interface{}, age time.Duration) {
+	value, age, err := instance.GetWithAge(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return value, age
+}
@@ -0,0 +1,139 @@
+package metadb
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+)
+
+// entryNames returns the names of a list of Entries.
+func entryNames(entries []Entry) []string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name
+	}
+
+	return names
+}
+
+// TestTimestamps ensures that an existing table is migrated, and that entries
+// are listed by the time at which they were last written.
+func TestTimestamps(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		legacy, err := NewInstance(db)
+		if err != nil {
+			t.Fatal("NewInstance: got error:\n", err)
+		}
+
+		legacy.MustSet("legacy", true)
+		if _, err := legacy.RecentlyChanged(1); err == nil {
+			t.Error("Instance.RecentlyChanged: expected error with timestamps disabled")
+		}
+
+		instance, err := NewInstanceWithOptions(db, Options{Timestamps: true})
+		if err != nil {
+			t.Fatal("NewInstanceWithOptions: got error while adding timestamps:\n", err)
+		}
+
+		if _, err := NewInstanceWithOptions(db, Options{Timestamps: true}); err != nil {
+			t.Fatal("NewInstanceWithOptions: got error with existing timestamps:\n", err)
+		}
+
+		before := time.Now()
+		for _, name := range []string{"a", "b", "c"} {
+			instance.MustSet(name, 1)
+			time.Sleep(time.Millisecond)
+		}
+
+		instance.MustSet("a", 2)
+		instance.MustSet("b", 1) // unchanged, so not written
+		if value := instance.MustToggle("legacy"); value {
+			t.Error("Instance.Toggle: got 'true' expected 'false'")
+		}
+
+		recent, err := instance.RecentlyChanged(3)
+		if err != nil {
+			t.Fatal("Instance.RecentlyChanged: got error:\n", err)
+		}
+
+		if names := entryNames(recent); len(names) != 3 || names[0] != "legacy" || names[1] != "a" || names[2] != "c" {
+			t.Errorf("Instance.RecentlyChanged: got '%v' expected '[legacy a c]'", names)
+		} else if recent[1].Value != 2 || recent[1].UpdatedAt.Before(before) {
+			t.Errorf("Instance.RecentlyChanged: got '%+v' expected value '2' written after '%v'", recent[1], before)
+		}
+
+		legacy.MustSet("untracked", 1)
+		oldest := instance.MustOldestUnchanged(2)
+		if names := entryNames(oldest); len(names) != 2 || names[0] != "untracked" || names[1] != "b" {
+			t.Errorf("Instance.OldestUnchanged: got '%v' expected '[untracked b]'", names)
+		} else if !oldest[0].UpdatedAt.IsZero() {
+			t.Errorf("Instance.OldestUnchanged: got '%v' expected zero time for untracked entry", oldest[0].UpdatedAt)
+		}
+
+		if entries := instance.WithPrefix("c").MustRecentlyChanged(10); len(entries) != 1 || entries[0].Name != "" {
+			t.Errorf("Instance.RecentlyChanged: got '%v' expected a single entry within scope", entries)
+		}
+	})
+}
+
+// TestGetWithAge ensures that the age of an entry is measured from when it was
+// last written, that entries of unknown age are given the greatest one, and
+// that missing entries are read from the fallback.
+func TestGetWithAge(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		legacy, err := NewInstance(db)
+		if err != nil {
+			t.Fatal("NewInstance: got error:\n", err)
+		}
+
+		legacy.MustSet("legacy", 1)
+		if _, _, err := legacy.GetWithAge("legacy"); err == nil {
+			t.Error("Instance.GetWithAge: expected error with timestamps disabled")
+		}
+
+		instance, err := NewInstanceWithOptions(db, Options{Timestamps: true})
+		if err != nil {
+			t.Fatal("NewInstanceWithOptions: got error:\n", err)
+		}
+
+		if _, age := instance.MustGetWithAge("legacy"); age != time.Duration(math.MaxInt64) {
+			t.Errorf("Instance.GetWithAge: got '%v' expected greatest age for untracked entry", age)
+		}
+
+		before := time.Now()
+		instance.MustSet("token", "abc")
+		time.Sleep(10 * time.Millisecond)
+
+		value, age := instance.MustGetWithAge("token")
+		if value != "abc" {
+			t.Errorf("Instance.GetWithAge: got '%v' expected 'abc'", value)
+		} else if age < 10*time.Millisecond || age > time.Since(before) {
+			t.Errorf("Instance.GetWithAge: got age '%v' expected between 10ms and '%v'", age, time.Since(before))
+		}
+
+		if _, _, err := instance.GetWithAge("missing"); err == nil {
+			t.Error("Instance.GetWithAge: expected error with missing entry")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Errorf("Instance.GetWithAge: got '%v' expected ErrNoEntry", err)
+		}
+
+		overrides, err := NewInstanceWithOptions(db, Options{Table: "overrides", Timestamps: true})
+		if err != nil {
+			t.Fatal("NewInstanceWithOptions: got error:\n", err)
+		}
+
+		chained := overrides.WithFallback(instance)
+		if value, age := chained.MustGetWithAge("token"); value != "abc" {
+			t.Errorf("Instance.GetWithAge: got '%v' expected default 'abc'", value)
+		} else if age < 10*time.Millisecond {
+			t.Errorf("Instance.GetWithAge: got age '%v' expected that of the default", age)
+		}
+
+		if _, _, err := chained.GetWithAge("missing"); err == nil {
+			t.Error("Instance.GetWithAge: expected error with entry missing from both")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Errorf("Instance.GetWithAge: got '%v' expected ErrNoEntry", err)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package metadb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// separator returns the separator of hierarchical entry names configured by
+// Options.Separator, or the default if none was.
+func (instance *Instance) separator() string {
+	if instance.options.Separator == "" {
+		return "."
+	}
+
+	return instance.options.Separator
+}
+
+// Tree returns the entries whose names begin with prefix as a nested map, with
+// the prefix removed and the remainder of each name split on Options.Separator
+// (by default "."). For example, with the prefix "server." the entry
+// "server.http.port" is stored at tree["http"]["port"], where tree["http"] is
+// itself a map[string]interface{}. If the name of an entry is also the parent
+// of other entries (e.g. both "server.http" and "server.http.port" exist), an
+// error is returned.
+func (instance *Instance) Tree(prefix string) (map[string]interface{}, error) {
+	separator := instance.separator()
+	tree := make(map[string]interface{})
+
+	err := instance.WithPrefix(prefix).each(func(name string, value interface{}) error {
+		components := strings.Split(name, separator)
+		branch := tree
+		for i, component := range components[:len(components)-1] {
+			switch child := branch[component].(type) {
+			case nil:
+				next := make(map[string]interface{})
+				branch[component] = next
+				branch = next
+			case map[string]interface{}:
+				branch = child
+			default:
+				return fmt.Errorf("metadb: entry '%s' is both a value and the parent of '%s'",
+					prefix+strings.Join(components[:i+1], separator), prefix+name)
+			}
+		}
+
+		leaf := components[len(components)-1]
+		if _, ok := branch[leaf]; ok {
+			return fmt.Errorf("metadb: entry '%s' is both a value and the parent of other entries", prefix+name)
+		}
+
+		branch[leaf] = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+// MustTree does the same as Tree, but panics if an error is returned.
+func (instance *Instance) MustTree(prefix string) map[string]interface{} {
+	if res, err := instance.Tree(prefix); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// DeleteSubtree deletes the entry named prefix along with every entry nested
+// beneath it according to Options.Separator, within a single transaction, and
+// returns the number of entries deleted. For example, deleting "server" also
+// deletes "server.http.port", but not "servers.x". If no such entries exist,
+// nothing is done and 0 is returned.
+func (instance *Instance) DeleteSubtree(prefix string) (int, error) {
+	count := 0
+	err := instance.withTx(func(tx *Instance) error {
+		names, err := tx.WithPrefix(prefix + tx.separator()).Clear()
+		if err != nil {
+			return err
+		}
+
+		count = len(names)
+		if exists, err := tx.exists(prefix); err != nil {
+			return fmt.Errorf("metadb: failed to delete entry for '%s':\n%s", prefix, err)
+		} else if exists {
+			if err := tx.remove(prefix); err != nil {
+				return err
+			}
+
+			count++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// MustDeleteSubtree does the same as DeleteSubtree, but panics if an error is
+// returned.
+func (instance *Instance) MustDeleteSubtree(prefix string) int {
+	if res, err := instance.DeleteSubtree(prefix); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// Namespaces returns the distinct first components of the names of all entries
+// split on the given separator, in order (e.g. "auth" and "server" for the
+// entries "server.port", "server.host", and "auth.token"). Entries whose names
+// do not contain the separator belong to no namespace and are skipped. If the
+// separator is empty, Options.Separator is used, or "." if it is unset.
+func (instance *Instance) Namespaces(separator string) ([]string, error) {
+	if separator == "" {
+		separator = instance.separator()
+	}
+
+	names, err := instance.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]string, 0)
+	for _, name := range names {
+		i := strings.Index(name, separator)
+		if i < 0 {
+			continue
+		}
+
+		// names are sorted, so equal namespaces are adjacent
+		namespace := name[:i]
+		if len(namespaces) == 0 || namespaces[len(namespaces)-1] != namespace {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	return namespaces, nil
+}
+
+// MustNamespaces does the same as Namespaces, but panics if an error is
+// returned.
+func (instance *Instance) MustNamespaces(separator string) []string {
+	if res, err := instance.Namespaces(separator); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
@@ -0,0 +1,108 @@
+package metadb
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestTree ensures that entries are nested according to their names, and that
+// conflicting entries are rejected.
+func TestTree(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("server.host", "localhost")
+		instance.MustSet("server.http.port", 8080)
+		instance.MustSet("server.http.tls", true)
+		instance.MustSet("client.host", "remote")
+
+		tree, err := instance.Tree("server.")
+		if err != nil {
+			t.Fatal("Instance.Tree: got error:\n", err)
+		}
+
+		expected := map[string]interface{}{
+			"host": "localhost",
+			"http": map[string]interface{}{"port": 8080, "tls": true},
+		}
+		if !reflect.DeepEqual(tree, expected) {
+			t.Errorf("Instance.Tree: got '%v' expected '%v'", tree, expected)
+		}
+
+		if tree := instance.MustTree(""); len(tree) != 2 || tree["client"] == nil || tree["server"] == nil {
+			t.Errorf("Instance.MustTree: got '%v' expected 'client' and 'server' branches", tree)
+		}
+
+		instance.MustSet("server.http", "conflict")
+		if _, err := instance.Tree("server."); err == nil {
+			t.Error("Instance.Tree: expected error with entry which is both a value and a parent")
+		}
+
+		if err := panicked(func() { instance.MustTree("") }); err == nil {
+			t.Error("Instance.MustTree: expected panic with entry which is both a value and a parent")
+		}
+	})
+
+	RunWithOptions(Options{Separator: "/"}, func(instance *Instance) {
+		instance.MustSet("a/b.c", 1)
+
+		expected := map[string]interface{}{"a": map[string]interface{}{"b.c": 1}}
+		if tree := instance.MustTree(""); !reflect.DeepEqual(tree, expected) {
+			t.Errorf("Instance.Tree: got '%v' expected '%v'", tree, expected)
+		}
+	})
+}
+
+// TestDeleteSubtree ensures that an entry is deleted along with its nested
+// entries, but not along with entries which merely share its prefix.
+func TestDeleteSubtree(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		for _, name := range []string{"server", "server.host", "server.http.port", "servers.x", "serverless"} {
+			instance.MustSet(name, true)
+		}
+
+		if count, err := instance.DeleteSubtree("server"); err != nil {
+			t.Error("Instance.DeleteSubtree: got error:\n", err)
+		} else if count != 3 {
+			t.Errorf("Instance.DeleteSubtree: got '%d' expected '3'", count)
+		}
+
+		if keys := instance.MustKeys(); !reflect.DeepEqual(keys, []string{"serverless", "servers.x"}) {
+			t.Errorf("Instance.DeleteSubtree: got remaining '%v' expected '[serverless servers.x]'", keys)
+		}
+
+		if count := instance.MustDeleteSubtree("missing"); count != 0 {
+			t.Errorf("Instance.MustDeleteSubtree: got '%d' expected '0'", count)
+		}
+
+		instance.MustSet("a.b.c", 1)
+		instance.MustSet("a.d", 2)
+		if count := instance.WithPrefix("a.").MustDeleteSubtree("b"); count != 1 || !instance.Exists("a.d") {
+			t.Errorf("Instance.MustDeleteSubtree: got '%d' expected '1' within scope", count)
+		}
+	})
+}
+
+// TestNamespaces ensures that the distinct first components of entry names are
+// returned for the given or default separator.
+func TestNamespaces(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("server.port", 8080)
+		instance.MustSet("server.host", "localhost")
+		instance.MustSet("auth.token", "secret")
+		instance.MustSet("auth/legacy", true)
+		instance.MustSet("debug", false)
+
+		if res, err := instance.Namespaces(""); err != nil {
+			t.Error("Instance.Namespaces: got error:\n", err)
+		} else if !reflect.DeepEqual(res, []string{"auth", "server"}) {
+			t.Errorf("Instance.Namespaces: got '%v' expected '[auth server]'", res)
+		}
+
+		if res := instance.MustNamespaces("/"); !reflect.DeepEqual(res, []string{"auth"}) {
+			t.Errorf("Instance.Namespaces: got '%v' expected '[auth]' with separator '/'", res)
+		}
+
+		if res := instance.WithPrefix("server.").MustNamespaces(""); len(res) != 0 {
+			t.Errorf("Instance.Namespaces: got '%v' expected none within prefix", res)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package metadb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+)
+
+// executor is implemented by *sql.DB, *sql.Conn, and *sql.Tx, allowing the
+// same operations to be performed either directly or within a transaction.
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// beginner is implemented by the executors which can begin a transaction,
+// namely *sql.DB and *sql.Conn.
+type beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// savepoints counts the savepoints created by every Instance, such that each
+// is given a unique name.
+var savepoints uint64
+
+// WithTx runs fn with an Instance bound to a new transaction, committing the
+// transaction if fn returns nil and rolling it back if fn returns an error or
+// panics, in which case the error or panic is passed on. Every operation of
+// the Instance passed to fn is performed within the transaction, and changes
+// are only reported to bound variables once it has been committed.
+//
+// If the Instance is itself bound to a transaction, such as when WithTx is
+// called within fn, a savepoint is created within that transaction instead,
+// such that the nested operations are rolled back on their own if fn fails,
+// leaving the outer transaction intact. Savepoints are named metadb_savepoint_
+// followed by a number unique within the process, so they never clash with
+// one another, even if nested concurrently. The database must support the
+// SAVEPOINT, ROLLBACK TO SAVEPOINT, and RELEASE SAVEPOINT statements, as
+// SQLite, MySQL, and PostgreSQL do.
+func (instance *Instance) WithTx(fn func(tx *Instance) error) error {
+	return instance.withTx(fn)
+}
+
+// withTx implements WithTx, and is used by operations which must be performed
+// atomically. For an Instance which can neither begin a transaction nor create
+// a savepoint, fn is simply run with the Instance itself. For a serialized
+// Instance, the worker is held until the transaction has been committed or
+// rolled back.
+func (instance *Instance) withTx(fn func(tx *Instance) error) (err error) {
+	if _, ok := instance.db.(*serialConn); ok {
+		return instance.exclusive(func(held *Instance) error {
+			return held.withTx(fn)
+		})
+	}
+
+	if _, ok := instance.db.(*sql.Tx); ok {
+		return instance.withSavepoint(fn)
+	}
+
+	db, ok := instance.db.(beginner)
+	if !ok {
+		return fn(instance)
+	}
+
+	// the transaction as a whole is bounded by the context of the Instance
+	ctx, cancel := instance.context()
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("metadb: failed to begin transaction:\n%s", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	bound := *instance
+	bound.db = tx
+	bound.pending = new([]string)
+	if err := fn(&bound); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("metadb: failed to commit transaction:\n%s", err)
+	}
+
+	// changes are only reported once they have been committed
+	instance.notify(*bound.pending...)
+	return nil
+}
+
+// withSavepoint does the same as withTx, but for an Instance bound to a
+// transaction, within which a savepoint is created rather than a transaction.
+func (instance *Instance) withSavepoint(fn func(tx *Instance) error) error {
+	name := fmt.Sprintf("metadb_savepoint_%d", atomic.AddUint64(&savepoints, 1))
+	if _, err := instance.exec("SAVEPOINT " + name + ";"); err != nil {
+		return fmt.Errorf("metadb: failed to create savepoint:\n%s", err)
+	}
+
+	// the savepoint is released after being rolled back to, as rolling back
+	// leaves it in place
+	rollback := func() {
+		instance.exec("ROLLBACK TO SAVEPOINT " + name + ";")
+		instance.exec("RELEASE SAVEPOINT " + name + ";")
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			rollback()
+			panic(r)
+		}
+	}()
+
+	bound := *instance
+	bound.pending = new([]string)
+	if err := fn(&bound); err != nil {
+		rollback()
+		return err
+	}
+
+	if _, err := instance.exec("RELEASE SAVEPOINT " + name + ";"); err != nil {
+		return fmt.Errorf("metadb: failed to release savepoint:\n%s", err)
+	}
+
+	// changes are reported once the outer transaction has been committed
+	*instance.pending = append(*instance.pending, *bound.pending...)
+	return nil
+}
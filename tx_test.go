@@ -0,0 +1,75 @@
+package metadb
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestWithTx ensures that a transaction is committed or rolled back as a whole,
+// and that nested calls roll back to a savepoint without affecting the outer
+// transaction.
+func TestWithTx(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		failure := errors.New("tests: failure")
+
+		err := instance.WithTx(func(tx *Instance) error {
+			tx.MustSet("outer", 1)
+
+			if err := tx.WithTx(func(nested *Instance) error {
+				nested.MustSet("nested", 1)
+				nested.MustSet("outer", 2)
+				return failure
+			}); err != failure {
+				t.Errorf("Instance.WithTx: got '%v' expected '%v' from nested call", err, failure)
+			}
+
+			if tx.Exists("nested") || tx.MustGet("outer") != 1 {
+				t.Error("Instance.WithTx: expected nested changes to be rolled back")
+			}
+
+			if err := panicked(func() {
+				tx.WithTx(func(nested *Instance) error {
+					nested.MustSet("panicked", true)
+					panic("tests: nested panic")
+				})
+			}); err == nil {
+				t.Error("Instance.WithTx: expected nested panic to be passed on")
+			}
+
+			return tx.WithTx(func(nested *Instance) error {
+				return nested.Set("kept", true)
+			})
+		})
+		if err != nil {
+			t.Fatal("Instance.WithTx: got error:\n", err)
+		}
+
+		if !instance.Exists("outer") || !instance.Exists("kept") || instance.Exists("nested") || instance.Exists("panicked") {
+			t.Errorf("Instance.WithTx: got entries '%v' expected 'outer' and 'kept'", GetFixtures(instance))
+		}
+
+		err = instance.WithTx(func(tx *Instance) error {
+			tx.MustSet("discarded", 1)
+			return failure
+		})
+		if err != failure {
+			t.Errorf("Instance.WithTx: got '%v' expected '%v'", err, failure)
+		} else if instance.Exists("discarded") {
+			t.Error("Instance.WithTx: expected changes to be rolled back")
+		}
+
+		// operations performed within a transaction roll back on their own
+		err = instance.WithTx(func(tx *Instance) error {
+			if created := tx.MustSetAllIfNotExists(map[string]interface{}{"a": 1, "outer": 2}); created {
+				t.Error("Instance.SetAllIfNotExists: expected no entries to be created")
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Error("Instance.WithTx: got error:\n", err)
+		} else if instance.Exists("a") {
+			t.Error("Instance.SetAllIfNotExists: expected 'a' not to be created within a transaction")
+		}
+	})
+}
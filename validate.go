@@ -0,0 +1,94 @@
+package metadb
+
+import "fmt"
+
+// SetValidator registers a function which validates every value written to
+// the entry with the given name by Set, ForceSet, and the methods built upon
+// them. The validator is only called once the type of the value has been
+// checked, and if it returns an error, the value is not written and the error
+// is returned as-is. Passing nil removes any registered validator.
+func (instance *Instance) SetValidator(name string, fn func(value interface{}) error) {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	if fn == nil {
+		delete(instance.validators, instance.key(name))
+		return
+	}
+
+	instance.validators[instance.key(name)] = fn
+}
+
+// validate checks value against the enum registered for the named entry and
+// then calls its registered validator, if either exists, returning the first
+// error.
+func (instance *Instance) validate(name string, value interface{}) error {
+	if err := instance.checkEnum(name, value); err != nil {
+		return err
+	}
+
+	instance.mu.RLock()
+	fn, ok := instance.validators[instance.key(name)]
+	instance.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	return fn(value)
+}
+
+// Validate checks whether Set would accept the given value for the named
+// entry, without writing anything. It performs the same checks as Set,
+// returning the same error Set would if the value is of a disallowed type, of
+// a different type than that of the existing entry, too large, or rejected by
+// a validator or because the entry is immutable, and nil otherwise.
+func (instance *Instance) Validate(name string, value interface{}) error {
+	return instance.wrap("Validate", name, instance.check(name, value))
+}
+
+// check implements Validate.
+func (instance *Instance) check(name string, value interface{}) error {
+	if instance.options.CoerceNumbers {
+		var err error
+		if value, err = coerceNumber(value); err != nil {
+			return err
+		}
+	}
+
+	valueType, err := toValueType(value)
+	if err != nil {
+		return err
+	}
+
+	// the entry would be deleted rather than written
+	if value == "" && instance.options.DeleteEmptyStrings {
+		return nil
+	}
+
+	blob, err := toBlobString(value)
+	if err != nil {
+		return err
+	}
+
+	if blob, err = instance.encodeBlob(name, blob); err != nil {
+		return err
+	}
+
+	if max := instance.options.MaxValueBytes; max > 0 && len(blob) > max {
+		return &ErrValueTooLarge{name, len(blob), max}
+	}
+
+	currentType, err := instance.getValueType(name)
+	if err != nil {
+		if _, ok := err.(*ErrNoEntry); !ok {
+			return err
+		}
+	} else if instance.isImmutable(name) {
+		return &ErrImmutable{name}
+	} else if valueType != currentType {
+		return fmt.Errorf("metadb: cannot change value for '%s' to one of a different type", name)
+	}
+
+	return instance.validate(name, value)
+}
@@ -0,0 +1,96 @@
+package metadb
+
+import (
+	"errors"
+	"testing"
+)
+
+// errInvalidPort is returned by the validator used in tests.
+var errInvalidPort = errors.New("port out of range")
+
+// validatePort accepts ints between 1 and 65535.
+func validatePort(value interface{}) error {
+	if port, ok := value.(int); !ok || port < 1 || port > 65535 {
+		return errInvalidPort
+	}
+
+	return nil
+}
+
+// TestSetValidator ensures that values rejected by a validator are not written,
+// and that the type of a value is checked before it is validated.
+func TestSetValidator(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.SetValidator("port", validatePort)
+
+		if err := instance.Set("port", 0); err != errInvalidPort {
+			t.Errorf("Instance.Set: got error '%v' expected '%v' for new entry", err, errInvalidPort)
+		}
+
+		if instance.Exists("port") {
+			t.Error("Instance.Set: expected invalid value not to be written")
+		}
+
+		instance.MustSet("port", 8080)
+		if err := instance.Set("port", 70000); err != errInvalidPort {
+			t.Errorf("Instance.Set: got error '%v' expected '%v' for existing entry", err, errInvalidPort)
+		}
+
+		if err := instance.Set("port", "8080"); err == nil || err == errInvalidPort {
+			t.Errorf("Instance.Set: got error '%v' expected type to be checked first", err)
+		}
+
+		if err := instance.ForceSet("port", "8080"); err != errInvalidPort {
+			t.Errorf("Instance.ForceSet: got error '%v' expected '%v'", err, errInvalidPort)
+		}
+
+		if value := instance.MustGet("port"); value != 8080 {
+			t.Errorf("Instance.Get: got '%v' expected '8080' after rejected writes", value)
+		}
+
+		instance.SetValidator("port", nil)
+		instance.MustSet("port", 70000)
+	})
+}
+
+// TestValidate ensures that Validate reports the same errors as Set without
+// writing anything.
+func TestValidate(t *testing.T) {
+	RunWithOptions(Options{MaxValueBytes: 8}, func(instance *Instance) {
+		instance.SetValidator("port", validatePort)
+
+		if err := instance.Validate("port", 8080); err != nil {
+			t.Error("Instance.Validate: got error:\n", err)
+		}
+
+		if instance.Exists("port") {
+			t.Error("Instance.Validate: expected nothing to be written")
+		}
+
+		if err := instance.Validate("port", 0); err != errInvalidPort {
+			t.Errorf("Instance.Validate: got error '%v' expected '%v'", err, errInvalidPort)
+		}
+
+		if err := instance.Validate("name", "too long for the limit"); err == nil {
+			t.Error("Instance.Validate: expected error with value exceeding Options.MaxValueBytes")
+		} else if _, ok := err.(*ErrValueTooLarge); !ok {
+			t.Errorf("Instance.Validate: got '%v' expected ErrValueTooLarge", err)
+		}
+
+		if err := instance.Validate("name", struct{}{}); err == nil {
+			t.Error("Instance.Validate: expected error with disallowed type")
+		}
+
+		instance.MustSet("port", 8080)
+		if err := instance.Validate("port", "8080"); err == nil || err == errInvalidPort {
+			t.Errorf("Instance.Validate: got error '%v' expected type to be checked first", err)
+		}
+
+		instance.MarkImmutable("port")
+		if err := instance.Validate("port", 443); err == nil {
+			t.Error("Instance.Validate: expected error with immutable entry")
+		} else if _, ok := err.(*ErrImmutable); !ok {
+			t.Errorf("Instance.Validate: got '%v' expected ErrImmutable", err)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package metadb
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// LatestSchemaVersion is the version of the schema created and migrated by
+// InitSchema, which is recorded in a companion table named after the metadata
+// table with the suffix _schema. It is incremented whenever InitSchema gains
+// a migration which existing databases must undergo.
+const LatestSchemaVersion = 1
+
+// ErrSchemaVersion is returned by RequireSchema when the schema version
+// recorded in the database is older than required.
+type ErrSchemaVersion struct {
+	Table    string
+	Version  int
+	Required int
+}
+
+// Error implements the error interface for ErrSchemaVersion.
+func (err *ErrSchemaVersion) Error() string {
+	return fmt.Sprintf("metadb: schema of table '%s' is at version %d, but version %d is required; "+
+		"call InitSchema to migrate it", err.Table, err.Version, err.Required)
+}
+
+// recordVersion creates the schema version table if it is missing, and records
+// LatestSchemaVersion unless a newer version is already recorded.
+func (instance *Instance) recordVersion() error {
+	if _, err := instance.exec("CREATE TABLE IF NOT EXISTS {version}(Version INT NOT NULL);"); err != nil {
+		return err
+	}
+
+	return instance.withTx(func(tx *Instance) error {
+		var version sql.NullInt64
+		if err := tx.queryRow("SELECT MAX(Version) FROM {version};").Scan(&version); err != nil {
+			return err
+		} else if version.Valid && version.Int64 >= LatestSchemaVersion {
+			return nil
+		}
+
+		if _, err := tx.exec("DELETE FROM {version};"); err != nil {
+			return err
+		}
+
+		_, err := tx.exec("INSERT INTO {version} (Version) VALUES (?);", LatestSchemaVersion)
+		return err
+	})
+}
+
+// SchemaVersion returns the schema version recorded in the database by the
+// last call to InitSchema, which is at most LatestSchemaVersion unless the
+// database was migrated by a newer release of the package. For a database
+// created or last migrated before schema versions were recorded, 0 is
+// returned.
+func (instance *Instance) SchemaVersion() (int, error) {
+	var version sql.NullInt64
+	if err := instance.queryRow("SELECT MAX(Version) FROM {version};").Scan(&version); err != nil {
+		// the version table is only missing if the metadata table can be read
+		if _, tableErr := instance.columns(); tableErr != nil {
+			return 0, fmt.Errorf("metadb: failed to read schema version:\n%s", err)
+		}
+
+		return 0, nil
+	}
+
+	return int(version.Int64), nil
+}
+
+// MustSchemaVersion does the same as SchemaVersion, but panics if an error is
+// returned.
+func (instance *Instance) MustSchemaVersion() int {
+	if res, err := instance.SchemaVersion(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// RequireSchema returns an ErrSchemaVersion if the schema version recorded in
+// the database is older than min, allowing applications to fail on startup
+// when run against a database which has not been migrated, rather than once a
+// missing column is queried. Passing LatestSchemaVersion requires every
+// migration known to the package.
+func (instance *Instance) RequireSchema(min int) error {
+	version, err := instance.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if version < min {
+		return &ErrSchemaVersion{instance.schema.table, version, min}
+	}
+
+	return nil
+}
+
+// MustRequireSchema does the same as RequireSchema, but panics if an error is
+// returned.
+func (instance *Instance) MustRequireSchema(min int) {
+	if err := instance.RequireSchema(min); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,61 @@
+package metadb
+
+import "testing"
+
+// TestSchemaVersion ensures that the schema version is recorded by InitSchema,
+// that databases predating it report version 0, and that RequireSchema rejects
+// older versions.
+func TestSchemaVersion(t *testing.T) {
+	RunWithOptions(Options{SkipInitSchema: true}, func(instance *Instance) {
+		if _, err := instance.SchemaVersion(); err == nil {
+			t.Error("Instance.SchemaVersion: expected error without metadata table")
+		}
+
+		if _, err := instance.exec(`CREATE TABLE {table}(ID INT AUTO_INCREMENT PRIMARY KEY,
+			{name} VARCHAR(255) NOT NULL UNIQUE, {value} BLOB NOT NULL, {type} TINYINT NOT NULL);`); err != nil {
+			t.Fatal("exec: got error creating legacy table:\n", err)
+		}
+
+		if version := instance.MustSchemaVersion(); version != 0 {
+			t.Errorf("Instance.SchemaVersion: got '%d' expected '0' for legacy table", version)
+		}
+
+		if err := instance.RequireSchema(1); err == nil {
+			t.Error("Instance.RequireSchema: expected error for legacy table")
+		} else if versionErr, ok := err.(*ErrSchemaVersion); !ok {
+			t.Errorf("Instance.RequireSchema: got '%v' expected ErrSchemaVersion", err)
+		} else if versionErr.Version != 0 || versionErr.Required != 1 {
+			t.Errorf("Instance.RequireSchema: got '%+v' expected version 0 and required 1", versionErr)
+		}
+
+		instance.MustRequireSchema(0)
+
+		for i := 0; i < 2; i++ {
+			if err := instance.InitSchema(); err != nil {
+				t.Fatal("Instance.InitSchema: got error:\n", err)
+			}
+		}
+
+		if version := instance.MustSchemaVersion(); version != LatestSchemaVersion {
+			t.Errorf("Instance.SchemaVersion: got '%d' expected '%d'", version, LatestSchemaVersion)
+		}
+
+		instance.MustRequireSchema(LatestSchemaVersion)
+		if err := instance.RequireSchema(LatestSchemaVersion + 1); err == nil {
+			t.Error("Instance.RequireSchema: expected error for future version")
+		}
+
+		// a version recorded by a newer release is left in place
+		if _, err := instance.exec("UPDATE {version} SET Version = ?;", LatestSchemaVersion+1); err != nil {
+			t.Fatal("exec: got error:\n", err)
+		}
+
+		if err := instance.InitSchema(); err != nil {
+			t.Fatal("Instance.InitSchema: got error:\n", err)
+		}
+
+		if version := instance.MustSchemaVersion(); version != LatestSchemaVersion+1 {
+			t.Errorf("Instance.SchemaVersion: got '%d' expected '%d' after newer release", version, LatestSchemaVersion+1)
+		}
+	})
+}
@@ -0,0 +1,304 @@
+package metadb
+
+import (
+	"math/big"
+	"sort"
+	"time"
+)
+
+// View is a read-only snapshot of the entries of an Instance, as returned by
+// ViewSnapshot. It is held entirely in memory and never refreshed, such that
+// reading from it never touches the database, and may be shared freely across
+// goroutines. To observe later changes, a new snapshot must be taken.
+type View struct {
+	entries map[string]interface{}
+}
+
+// ViewSnapshot returns a View holding every entry within the scope of the
+// Instance, as read by a single query.
+func (instance *Instance) ViewSnapshot() (View, error) {
+	entries := make(map[string]interface{})
+	err := instance.each(func(name string, value interface{}) error {
+		entries[name] = value
+		return nil
+	})
+	if err != nil {
+		return View{}, err
+	}
+
+	return View{entries}, nil
+}
+
+// MustViewSnapshot does the same as ViewSnapshot, but panics if an error is
+// returned.
+func (instance *Instance) MustViewSnapshot() View {
+	if res, err := instance.ViewSnapshot(); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// Len returns the number of entries within the View.
+func (view View) Len() int {
+	return len(view.entries)
+}
+
+// Exists returns true if the requested entry exists within the View.
+func (view View) Exists(name string) bool {
+	_, ok := view.entries[name]
+	return ok
+}
+
+// Keys returns the names of all entries within the View in order of name.
+func (view View) Keys() []string {
+	names := make([]string, 0, len(view.entries))
+	for name := range view.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Get returns the data within the requested entry as it was when the snapshot
+// was taken. If the entry did not exist, an ErrNoEntry is returned.
+func (view View) Get(name string) (interface{}, error) {
+	value, ok := view.entries[name]
+	if !ok {
+		return nil, &ErrNoEntry{name}
+	}
+
+	// slices and pointers are copied so that the View cannot be modified
+	// through them
+	switch value := value.(type) {
+	case []string:
+		return append([]string{}, value...), nil
+	case *big.Int:
+		return new(big.Int).Set(value), nil
+	case *big.Float:
+		return new(big.Float).Copy(value), nil
+	default:
+		return value, nil
+	}
+}
+
+// MustGet does the same as Get, but panics if an error is returned.
+func (view View) MustGet(name string) interface{} {
+	if res, err := view.Get(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// getTyped does the same as Instance.getTyped, but reads from the View.
+func (view View) getTyped(name string, valueType ValueType) (interface{}, error) {
+	value, err := view.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if got, _ := toValueType(value); got != valueType {
+		return nil, &ErrWrongType{name, valueType, got}
+	}
+
+	return value, nil
+}
+
+// GetBool does the same as Instance.GetBool, but reads from the View.
+func (view View) GetBool(name string) (bool, error) {
+	value, err := view.getTyped(name, TypeBool)
+	if err != nil {
+		return false, err
+	}
+
+	return value.(bool), nil
+}
+
+// MustGetBool does the same as GetBool, but panics if an error is returned.
+func (view View) MustGetBool(name string) bool {
+	if res, err := view.GetBool(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetInt does the same as Instance.GetInt, but reads from the View.
+func (view View) GetInt(name string) (int, error) {
+	value, err := view.getTyped(name, TypeInt)
+	if err != nil {
+		return 0, err
+	}
+
+	return value.(int), nil
+}
+
+// MustGetInt does the same as GetInt, but panics if an error is returned.
+func (view View) MustGetInt(name string) int {
+	if res, err := view.GetInt(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetInt64 does the same as Instance.GetInt64, but reads from the View.
+func (view View) GetInt64(name string) (int64, error) {
+	value, err := view.getTyped(name, TypeInt64)
+	if err != nil {
+		return 0, err
+	}
+
+	return value.(int64), nil
+}
+
+// MustGetInt64 does the same as GetInt64, but panics if an error is returned.
+func (view View) MustGetInt64(name string) int64 {
+	if res, err := view.GetInt64(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetFloat does the same as Instance.GetFloat, but reads from the View.
+func (view View) GetFloat(name string) (float64, error) {
+	value, err := view.Get(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return toFloat(name, value)
+}
+
+// MustGetFloat does the same as GetFloat, but panics if an error is returned.
+func (view View) MustGetFloat(name string) float64 {
+	if res, err := view.GetFloat(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetString does the same as Instance.GetString, but reads from the View.
+func (view View) GetString(name string) (string, error) {
+	value, err := view.getTyped(name, TypeString)
+	if err != nil {
+		return "", err
+	}
+
+	return value.(string), nil
+}
+
+// MustGetString does the same as GetString, but panics if an error is
+// returned.
+func (view View) MustGetString(name string) string {
+	if res, err := view.GetString(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetStringSlice does the same as Instance.GetStringSlice, but reads from the
+// View.
+func (view View) GetStringSlice(name string) ([]string, error) {
+	value, err := view.getTyped(name, TypeStringSlice)
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]string), nil
+}
+
+// MustGetStringSlice does the same as GetStringSlice, but panics if an error
+// is returned.
+func (view View) MustGetStringSlice(name string) []string {
+	if res, err := view.GetStringSlice(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetComplex does the same as Instance.GetComplex, but reads from the View.
+func (view View) GetComplex(name string) (complex128, error) {
+	value, err := view.getTyped(name, TypeComplex)
+	if err != nil {
+		return 0, err
+	}
+
+	return value.(complex128), nil
+}
+
+// MustGetComplex does the same as GetComplex, but panics if an error is
+// returned.
+func (view View) MustGetComplex(name string) complex128 {
+	if res, err := view.GetComplex(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetDuration does the same as Instance.GetDuration, but reads from the View.
+func (view View) GetDuration(name string) (time.Duration, error) {
+	value, err := view.getTyped(name, TypeDuration)
+	if err != nil {
+		return 0, err
+	}
+
+	return value.(time.Duration), nil
+}
+
+// MustGetDuration does the same as GetDuration, but panics if an error is
+// returned.
+func (view View) MustGetDuration(name string) time.Duration {
+	if res, err := view.GetDuration(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetBigInt does the same as Instance.GetBigInt, but reads from the View.
+func (view View) GetBigInt(name string) (*big.Int, error) {
+	value, err := view.getTyped(name, TypeBigInt)
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*big.Int), nil
+}
+
+// MustGetBigInt does the same as GetBigInt, but panics if an error is
+// returned.
+func (view View) MustGetBigInt(name string) *big.Int {
+	if res, err := view.GetBigInt(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// GetBigFloat does the same as Instance.GetBigFloat, but reads from the View.
+func (view View) GetBigFloat(name string) (*big.Float, error) {
+	value, err := view.getTyped(name, TypeBigFloat)
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*big.Float), nil
+}
+
+// MustGetBigFloat does the same as GetBigFloat, but panics if an error is
+// returned.
+func (view View) MustGetBigFloat(name string) *big.Float {
+	if res, err := view.GetBigFloat(name); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
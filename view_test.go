@@ -0,0 +1,58 @@
+package metadb
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestViewSnapshot ensures that a View holds the entries as they were when the
+// snapshot was taken, and that it cannot be modified through returned slices.
+func TestViewSnapshot(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("app.name", "metadb")
+		instance.MustSet("app.port", 8080)
+		instance.MustSet("app.hosts", []string{"a", "b"})
+		instance.MustSet("other", true)
+
+		view, err := instance.WithPrefix("app.").ViewSnapshot()
+		if err != nil {
+			t.Fatal("Instance.ViewSnapshot: got error:\n", err)
+		}
+
+		instance.MustSet("app.port", 9090)
+		instance.MustDelete("app.name")
+
+		if keys := view.Keys(); !reflect.DeepEqual(keys, []string{"hosts", "name", "port"}) {
+			t.Errorf("View.Keys: got '%v' expected '[hosts name port]'", keys)
+		}
+
+		if res := view.MustGetInt("port"); res != 8080 {
+			t.Errorf("View.GetInt: got '%d' expected '8080'", res)
+		}
+
+		if res := view.MustGetString("name"); res != "metadb" {
+			t.Errorf("View.GetString: got '%s' expected 'metadb'", res)
+		}
+
+		view.MustGetStringSlice("hosts")[0] = "changed"
+		if res := view.MustGetStringSlice("hosts"); !reflect.DeepEqual(res, []string{"a", "b"}) {
+			t.Errorf("View.GetStringSlice: got '%v' expected '[a b]'", res)
+		}
+
+		if _, err := view.GetBool("port"); err == nil {
+			t.Error("View.GetBool: expected error with entry of another type")
+		} else if _, ok := err.(*ErrWrongType); !ok {
+			t.Errorf("View.GetBool: got '%v' expected ErrWrongType", err)
+		}
+
+		if _, err := view.Get("other"); err == nil {
+			t.Error("View.Get: expected error with entry outside of scope")
+		} else if _, ok := err.(*ErrNoEntry); !ok {
+			t.Errorf("View.Get: got '%v' expected ErrNoEntry", err)
+		}
+
+		if view.Exists("other") || !view.Exists("port") || view.Len() != 3 {
+			t.Errorf("View.Exists: got unexpected entries '%v'", view.Keys())
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package metadb
+
+import (
+	"context"
+	"time"
+)
+
+// WaitFor returns the data within the requested entry once it exists, waiting
+// until it is written if it does not yet, such as to coordinate the startup of
+// components which depend on a value written by another. The entry is read
+// again whenever it is written through this Instance (or any Instance derived
+// from it), and additionally every poll to observe writes made by other
+// Instances or processes. If poll is not positive, only writes through this
+// Instance are observed. Writes within a transaction are observed once it has
+// been committed.
+//
+// If ctx is done before the entry exists, ctx.Err() is returned. Any error
+// other than an ErrNoEntry while reading the entry is returned as by Get.
+func (instance *Instance) WaitFor(ctx context.Context, name string, poll time.Duration) (interface{}, error) {
+	var ticks <-chan time.Time
+	if poll > 0 {
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		// the waiter is registered first, such that a write between reading
+		// the entry and waiting is not missed
+		waiter := make(chan struct{})
+		instance.mu.Lock()
+		instance.waiters[instance.key(name)] = append(instance.waiters[instance.key(name)], waiter)
+		instance.mu.Unlock()
+
+		value, found, err := instance.Lookup(name)
+		if err != nil || found {
+			instance.forgetWaiter(name, waiter)
+			return value, err
+		}
+
+		select {
+		case <-waiter:
+		case <-ticks:
+			instance.forgetWaiter(name, waiter)
+		case <-ctx.Done():
+			instance.forgetWaiter(name, waiter)
+			return nil, instance.wrap("WaitFor", name, ctx.Err())
+		}
+	}
+}
+
+// MustWaitFor does the same as WaitFor, but panics if an error is returned.
+func (instance *Instance) MustWaitFor(ctx context.Context, name string, poll time.Duration) interface{} {
+	if res, err := instance.WaitFor(ctx, name, poll); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// forgetWaiter removes waiter from those waiting on the named entry, if it has
+// not already been woken.
+func (instance *Instance) forgetWaiter(name string, waiter chan struct{}) {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	key := instance.key(name)
+	waiters := instance.waiters[key]
+	for i, candidate := range waiters {
+		if candidate == waiter {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+
+	if len(waiters) == 0 {
+		delete(instance.waiters, key)
+	} else {
+		instance.waiters[key] = waiters
+	}
+}
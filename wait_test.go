@@ -0,0 +1,61 @@
+package metadb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestWaitFor ensures that WaitFor returns existing entries immediately, wakes
+// once an entry is written through the Instance or observed by polling, and
+// gives up once its context is done.
+func TestWaitFor(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		instance.MustSet("existing", 1)
+		if value := instance.MustWaitFor(ctx, "existing", 0); value != 1 {
+			t.Errorf("Instance.WaitFor: got '%v' expected '1'", value)
+		}
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			instance.WithPrefix("app.").MustSet("ready", true)
+		}()
+
+		if value, err := instance.WaitFor(ctx, "app.ready", 0); err != nil {
+			t.Error("Instance.WaitFor: got error:\n", err)
+		} else if value != true {
+			t.Errorf("Instance.WaitFor: got '%v' expected 'true'", value)
+		}
+
+		// writes through another Instance are only observed by polling
+		other, err := NewInstance(instance.DB)
+		if err != nil {
+			t.Fatal("NewInstance: got error:\n", err)
+		}
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			other.MustSet("external", "value")
+		}()
+
+		if value := instance.MustWaitFor(ctx, "external", 5*time.Millisecond); value != "value" {
+			t.Errorf("Instance.WaitFor: got '%v' expected 'value'", value)
+		}
+
+		short, cancelShort := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancelShort()
+		if _, err := instance.WaitFor(short, "missing", 5*time.Millisecond); err != context.DeadlineExceeded {
+			t.Errorf("Instance.WaitFor: got error '%v' expected '%v'", err, context.DeadlineExceeded)
+		}
+
+		instance.mu.RLock()
+		waiting := len(instance.waiters)
+		instance.mu.RUnlock()
+		if waiting != 0 {
+			t.Errorf("Instance.WaitFor: got %d entries with waiters expected 0", waiting)
+		}
+	})
+}
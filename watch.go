@@ -0,0 +1,453 @@
+package metadb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChangeOp identifies the kind of modification described by a ChangeEvent.
+type ChangeOp int
+
+// ChangeInsert, ChangeUpdate, and ChangeDelete describe the creation,
+// modification, and deletion of an entry respectively.
+const (
+	ChangeInsert ChangeOp = iota
+	ChangeUpdate
+	ChangeDelete
+)
+
+// String returns the name of the ChangeOp, e.g. "insert".
+func (op ChangeOp) String() string {
+	switch op {
+	case ChangeInsert:
+		return "insert"
+	case ChangeUpdate:
+		return "update"
+	case ChangeDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("ChangeOp(%d)", int(op))
+	}
+}
+
+// ChangeEvent describes a committed modification of a single entry, as sent by
+// WatchHook. Name is relative to the scope of the watching Instance.
+type ChangeEvent struct {
+	Op   ChangeOp
+	Name string
+}
+
+// watchInterval is the interval at which WatchHook polls the metadata table
+// when update hooks are unavailable.
+const watchInterval = time.Second
+
+// The operation codes passed to the update hook by SQLite.
+const (
+	sqliteDelete = 9
+	sqliteInsert = 18
+	sqliteUpdate = 23
+)
+
+// hookConn is implemented by driver connections which report modifications
+// through hooks, namely those of github.com/mattn/go-sqlite3.
+type hookConn interface {
+	RegisterUpdateHook(callback func(int, string, string, int64))
+	RegisterCommitHook(callback func() int)
+	RegisterRollbackHook(callback func())
+}
+
+// WatchHook returns a channel on which a ChangeEvent is sent for every entry
+// within the scope of the Instance which is created, modified, or deleted,
+// until ctx is done, at which point the channel is closed. Soft deletions are
+// reported as updates, and renaming an entry is reported as the deletion of
+// its old name followed by the creation of its new one. Modifications which
+// change neither the value, type, nor visibility of an entry, such as those
+// recording reads for Options.TrackReads, are not reported.
+//
+// For an Instance operating on a single SQLite connection, such as one created
+// by NewSerializedInstance or from an *sql.Conn, the update, commit, and
+// rollback hooks of github.com/mattn/go-sqlite3 are used, such that events are
+// sent as soon as each modification is committed, and never for modifications
+// which are rolled back. Only modifications made through that connection are
+// reported, and the hooks replace any registered on it by other means, so at
+// most one WatchHook may be active per connection. For every other Instance,
+// the metadata table is polled once per second instead, and events are sent
+// for the differences between each poll, including those made by other
+// processes.
+//
+// Events are sent in order, and the sender waits for each to be received, so
+// the channel should be drained promptly.
+func (instance *Instance) WatchHook(ctx context.Context) (<-chan ChangeEvent, error) {
+	db := instance.db
+	if serial, ok := db.(*serialConn); ok {
+		db = serial.conn
+	}
+
+	if conn, ok := db.(*sql.Conn); ok {
+		var hooks hookConn
+		if err := conn.Raw(func(driverConn interface{}) error {
+			hooks, _ = driverConn.(hookConn)
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("metadb: failed to access connection:\n%s", err)
+		}
+
+		if hooks != nil {
+			return instance.watchHooks(ctx, conn, hooks)
+		}
+	}
+
+	return instance.watchPolling(ctx)
+}
+
+// MustWatchHook does the same as WatchHook, but panics if an error is
+// returned.
+func (instance *Instance) MustWatchHook(ctx context.Context) <-chan ChangeEvent {
+	if res, err := instance.WatchHook(ctx); err != nil {
+		panic(err)
+	} else {
+		return res
+	}
+}
+
+// rowChange is a modification of a row of the metadata table reported by the
+// update hook.
+type rowChange struct {
+	op    int
+	rowid int64
+}
+
+// rowState is the name and stored form of a row of the metadata table, as
+// last looked up by WatchHook. Modifications which leave the stored form as-is,
+// such as those recording reads for Options.TrackReads, are not reported.
+type rowState struct {
+	name   string
+	stored string
+}
+
+// watchHooks implements WatchHook by registering hooks on conn, whose driver
+// connection is hooks. Modifications are buffered until their transaction is
+// committed, and the names of the rows they affect are looked up afterward on
+// a separate goroutine, as the connection may not be used within a hook.
+func (instance *Instance) watchHooks(ctx context.Context, conn *sql.Conn, hooks hookConn) (<-chan ChangeEvent, error) {
+	var mu sync.Mutex
+	var pending []rowChange
+	var committed [][]rowChange // the modifications of each transaction
+	wake := make(chan struct{}, 1)
+
+	if err := conn.Raw(func(interface{}) error {
+		hooks.RegisterUpdateHook(func(op int, db string, table string, rowid int64) {
+			if !strings.EqualFold(table, instance.schema.table) {
+				return
+			}
+
+			mu.Lock()
+			pending = append(pending, rowChange{op, rowid})
+			mu.Unlock()
+		})
+
+		hooks.RegisterCommitHook(func() int {
+			mu.Lock()
+			if len(pending) > 0 {
+				committed = append(committed, pending)
+				pending = nil
+			}
+			mu.Unlock()
+
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+
+			return 0
+		})
+
+		hooks.RegisterRollbackHook(func() {
+			mu.Lock()
+			pending = nil
+			mu.Unlock()
+		})
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("metadb: failed to register hooks:\n%s", err)
+	}
+
+	unregister := func() {
+		conn.Raw(func(interface{}) error {
+			hooks.RegisterUpdateHook(nil)
+			hooks.RegisterCommitHook(nil)
+			hooks.RegisterRollbackHook(nil)
+			return nil
+		})
+	}
+
+	// rows are remembered by rowid, as deleted rows can no longer be read
+	states, err := instance.rowStates()
+	if err != nil {
+		unregister()
+		return nil, err
+	}
+
+	events := make(chan ChangeEvent)
+	go func() {
+		defer close(events)
+		defer unregister()
+
+		for {
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return
+			}
+
+			mu.Lock()
+			transactions := committed
+			committed = nil
+			mu.Unlock()
+
+			for _, changes := range transactions {
+				for _, change := range coalesce(changes) {
+					for _, event := range instance.resolve(change, states) {
+						if !instance.send(ctx, events, event) {
+							return
+						}
+					}
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// rowStateQuery selects the rowid, full name, value, type, and visibility of
+// rows of the metadata table, for use by WatchHook.
+const rowStateQuery = "SELECT rowid, {name}, {value}, {type}, {visible} FROM {table}"
+
+// rowStates returns the state of every row of the metadata table, keyed by its
+// SQLite rowid.
+func (instance *Instance) rowStates() (map[int64]rowState, error) {
+	rows, err := instance.query(rowStateQuery + ";")
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+	defer rows.Close()
+
+	states := make(map[int64]rowState)
+	for rows.Next() {
+		rowid, state, err := scanRowState(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+		}
+
+		states[rowid] = state
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+
+	return states, nil
+}
+
+// scanRowState scans a row selected by rowStateQuery using scan.
+func scanRowState(scan func(dest ...interface{}) error) (int64, rowState, error) {
+	var rowid int64
+	var name, blob string
+	var valueType ValueType
+	var visible bool
+	if err := scan(&rowid, &name, &blob, &valueType, &visible); err != nil {
+		return 0, rowState{}, err
+	}
+
+	return rowid, rowState{name, fmt.Sprintf("%d:%t:%s", valueType, visible, blob)}, nil
+}
+
+// coalesce returns the net modification of each row modified within a single
+// transaction, in order of the first modification of each row, such that e.g.
+// an entry renamed through a temporary name is reported once. Rows which were
+// both inserted and deleted are given an op of 0.
+func coalesce(changes []rowChange) []rowChange {
+	index := make(map[int64]int, len(changes))
+	res := make([]rowChange, 0, len(changes))
+	for _, change := range changes {
+		i, ok := index[change.rowid]
+		if !ok {
+			index[change.rowid] = len(res)
+			res = append(res, change)
+			continue
+		}
+
+		switch previous := res[i].op; {
+		case previous == sqliteInsert && change.op == sqliteDelete:
+			// the row never existed outside of the transaction
+			res[i].op = 0
+		case previous == sqliteInsert || previous == 0:
+			res[i].op = sqliteInsert
+		case previous == sqliteDelete:
+			// the rowid was reused, possibly by another name
+			res[i].op = sqliteUpdate
+		default:
+			res[i].op = change.op
+		}
+	}
+
+	return res
+}
+
+// resolve returns the events describing change, updating states to reflect
+// it. Inserted rows which no longer exist by the time they are looked up are
+// skipped along with their later deletion, while updated ones are reported by
+// the name last known. Updates which change neither the name nor the stored
+// form of a row are skipped.
+func (instance *Instance) resolve(change rowChange, states map[int64]rowState) []ChangeEvent {
+	if change.op == 0 {
+		return nil
+	}
+
+	old, known := states[change.rowid]
+	if change.op == sqliteDelete {
+		delete(states, change.rowid)
+		if !known {
+			return nil
+		}
+
+		return []ChangeEvent{{ChangeDelete, old.name}}
+	}
+
+	_, state, err := scanRowState(instance.queryRow(rowStateQuery+" WHERE rowid = ?;", change.rowid).Scan)
+	if err != nil {
+		if change.op == sqliteInsert || !known {
+			return nil
+		}
+
+		return []ChangeEvent{{ChangeUpdate, old.name}}
+	}
+
+	states[change.rowid] = state
+	if change.op == sqliteInsert || !known {
+		return []ChangeEvent{{ChangeInsert, state.name}}
+	} else if old.name != state.name {
+		return []ChangeEvent{{ChangeDelete, old.name}, {ChangeInsert, state.name}}
+	} else if old.stored == state.stored {
+		return nil
+	}
+
+	return []ChangeEvent{{ChangeUpdate, state.name}}
+}
+
+// send sends event on events if the named entry is within the scope of the
+// Instance, returning false if ctx is done beforehand.
+func (instance *Instance) send(ctx context.Context, events chan<- ChangeEvent, event ChangeEvent) bool {
+	if !strings.HasPrefix(event.Name, instance.prefix) {
+		return true
+	}
+
+	event.Name = instance.unkey(event.Name)
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// watchPolling implements WatchHook by comparing the stored form of every
+// entry within the scope of the Instance every watchInterval.
+func (instance *Instance) watchPolling(ctx context.Context) (<-chan ChangeEvent, error) {
+	previous, err := instance.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan ChangeEvent)
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			// transient errors are retried at the next interval
+			current, err := instance.snapshot()
+			if err != nil {
+				continue
+			}
+
+			for _, event := range diffSnapshots(previous, current) {
+				if !instance.send(ctx, events, event) {
+					return
+				}
+			}
+
+			previous = current
+		}
+	}()
+
+	return events, nil
+}
+
+// snapshot returns the stored value type and blob of every entry within the
+// scope of the Instance, keyed by full name.
+func (instance *Instance) snapshot() (map[string]string, error) {
+	scope, args := instance.scope()
+	rows, err := instance.query("SELECT {name}, {value}, {type} FROM {table} WHERE "+scope+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+	defer rows.Close()
+
+	entries := make(map[string]string)
+	for rows.Next() {
+		var name, blob string
+		var valueType ValueType
+		if err := rows.Scan(&name, &blob, &valueType); err != nil {
+			return nil, fmt.Errorf("metadb: failed to scan entry:\n%s", err)
+		}
+
+		entries[name] = fmt.Sprintf("%d:%s", valueType, blob)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("metadb: failed to read entries:\n%s", err)
+	}
+
+	return entries, nil
+}
+
+// diffSnapshots returns the events describing the differences between two
+// snapshots, in order of name.
+func diffSnapshots(previous, current map[string]string) []ChangeEvent {
+	var events []ChangeEvent
+	for name, stored := range current {
+		if old, ok := previous[name]; !ok {
+			events = append(events, ChangeEvent{ChangeInsert, name})
+		} else if old != stored {
+			events = append(events, ChangeEvent{ChangeUpdate, name})
+		}
+	}
+
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			events = append(events, ChangeEvent{ChangeDelete, name})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Name < events[j].Name
+	})
+
+	return events
+}
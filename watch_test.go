@@ -0,0 +1,132 @@
+package metadb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// receive returns the next n events sent on events, failing the test if they
+// are not sent in time.
+func receive(t *testing.T, events <-chan ChangeEvent, n int) []ChangeEvent {
+	t.Helper()
+
+	var received []ChangeEvent
+	timeout := time.After(3 * watchInterval)
+	for len(received) < n {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("Instance.WatchHook: channel closed early")
+			}
+
+			received = append(received, event)
+		case <-timeout:
+			t.Fatalf("Instance.WatchHook: got events '%v' expected %d", received, n)
+		}
+	}
+
+	return received
+}
+
+// TestWatchHook ensures that committed modifications made through a single
+// SQLite connection are reported through its hooks, and that rolled back ones
+// are not.
+func TestWatchHook(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		instance, err := NewSerializedInstance(db)
+		if err != nil {
+			t.Fatal("NewSerializedInstance: got error:\n", err)
+		}
+		defer instance.Close()
+
+		instance.MustSet("existing", 1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		events := instance.MustWatchHook(ctx)
+
+		// each step is awaited, as rows are looked up once their events are sent
+		steps := []struct {
+			fn       func()
+			expected []ChangeEvent
+		}{
+			{func() { instance.MustSet("foo", "bar") }, []ChangeEvent{{ChangeInsert, "foo"}}},
+			{func() { instance.MustSet("foo", "baz") }, []ChangeEvent{{ChangeUpdate, "foo"}}},
+			{func() {
+				instance.WithTx(func(tx *Instance) error {
+					tx.MustSet("discarded", true)
+					return errors.New("rolled back")
+				})
+				instance.MustRename("existing", "renamed")
+			}, []ChangeEvent{{ChangeDelete, "existing"}, {ChangeInsert, "renamed"}}},
+			{func() { instance.MustDelete("foo") }, []ChangeEvent{{ChangeDelete, "foo"}}},
+		}
+
+		for _, step := range steps {
+			step.fn()
+			if got := receive(t, events, len(step.expected)); !reflect.DeepEqual(got, step.expected) {
+				t.Errorf("Instance.WatchHook: got '%v' expected '%v'", got, step.expected)
+			}
+		}
+
+		cancel()
+		if _, ok := <-events; ok {
+			t.Error("Instance.WatchHook: expected channel to be closed once context is done")
+		}
+
+		instance.MustSet("after", 1)
+	})
+}
+
+// TestWatchHookPolling ensures that the metadata table is polled for Instances
+// for which hooks are unavailable.
+func TestWatchHookPolling(t *testing.T) {
+	RunWithInstance(func(instance *Instance) {
+		instance.MustSet("app.removed", 1)
+		instance.MustSet("other", 1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events := instance.WithPrefix("app.").MustWatchHook(ctx)
+
+		instance.MustSet("app.added", "value")
+		instance.MustDelete("app.removed")
+		instance.MustSet("other", 2)
+
+		expected := []ChangeEvent{{ChangeInsert, "added"}, {ChangeDelete, "removed"}}
+		if got := receive(t, events, len(expected)); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Instance.WatchHook: got '%v' expected '%v'", got, expected)
+		}
+	})
+}
+
+// TestWatchHookTrackReads ensures that recording reads for Options.TrackReads
+// is not reported as a modification of the entry read.
+func TestWatchHookTrackReads(t *testing.T) {
+	RunWithDB(func(db *sql.DB) {
+		instance, err := NewSerializedInstanceWithOptions(db, Options{TrackReads: true})
+		if err != nil {
+			t.Fatal("NewSerializedInstance: got error:\n", err)
+		}
+		defer instance.Close()
+
+		instance.MustSet("a", 1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events := instance.MustWatchHook(ctx)
+
+		instance.MustGet("a")
+		instance.MustSet("b", 1)
+
+		expected := []ChangeEvent{{ChangeInsert, "b"}}
+		if got := receive(t, events, len(expected)); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Instance.WatchHook: got '%v' expected '%v'", got, expected)
+		}
+	})
+}